@@ -4,9 +4,14 @@ package app
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"sync"
 	"syscall"
@@ -14,10 +19,13 @@ import (
 
 	"github.com/lxn/walk"
 	"wintray/internal/config"
+	"wintray/internal/diagnostics"
+	"wintray/internal/history"
 	"wintray/internal/i18n"
 	"wintray/internal/ipc"
 	"wintray/internal/lifecycle"
 	"wintray/internal/logging"
+	"wintray/internal/notify"
 	"wintray/internal/orchestrator"
 	"wintray/internal/startup"
 	"wintray/internal/tray"
@@ -28,6 +36,7 @@ const (
 	appName            = "WinTray"
 	singleInstanceName = "WinTray_SingleInstance"
 	activationEvent    = "WinTray_ShowMainWindow"
+	stopManagedEvent   = "WinTray_StopManagedApps"
 )
 
 func Run(args []string) {
@@ -35,6 +44,22 @@ func Run(args []string) {
 		_ = runCleanupRestoreHeadless()
 		return
 	}
+	if target, ok := openOutputTarget(args); ok {
+		runOpenOutput(target)
+		return
+	}
+	if n, ok := historyCount(args); ok {
+		runPrintHistory(n)
+		return
+	}
+	if path, ok := diagnosticsTarget(args); ok {
+		runCollectDiagnostics(path)
+		return
+	}
+	if path, ok := validateTarget(args); ok {
+		runValidate(path)
+		return
+	}
 
 	instance, alreadyRunning, err := ipc.Acquire(singleInstanceName)
 	if err != nil {
@@ -43,6 +68,14 @@ func Run(args []string) {
 	}
 	defer instance.Close()
 
+	if isStopManagedLaunch(args) {
+		// Only the running instance knows which processes it launched.
+		if alreadyRunning {
+			ipc.TrySignalActivation(stopManagedEvent)
+		}
+		return
+	}
+
 	if alreadyRunning {
 		if shouldSignalRunningInstance(args) {
 			if ipc.TrySignalActivation(activationEvent) {
@@ -61,7 +94,7 @@ func Run(args []string) {
 	}
 	migrationErr := config.TryMigrateFromWinTray(settingsPath)
 	store := config.NewStore(settingsPath)
-	settings := store.Load()
+	settings, loadReport := store.LoadWithReport()
 
 	appDir, appDirErr := config.AppDirWithError()
 	if appDirErr != nil {
@@ -69,35 +102,80 @@ func Run(args []string) {
 		return
 	}
 
-	logger, err := logging.New(appDir)
+	logger, err := logging.New(appDir, logOptions(settings))
 	if err != nil {
 		emitFatalBeforeUI("failed to initialize logger", err)
 		return
 	}
 	defer logger.Close()
 	if migrationErr != nil {
-		logger.Warn(fmt.Sprintf("settings migration failed: %v", migrationErr))
+		logger.Warn("settings migration failed", "err", migrationErr)
 	}
+	if loadReport.Err != nil {
+		logger.Warn("settings file unusable", "err", loadReport.Err, "backup", loadReport.Backup)
+	}
+	if loadReport.UpgradedFrom != 0 {
+		logger.Info("settings schema upgraded", "from", loadReport.UpgradedFrom, "to", config.CurrentSchemaVersion, "backup", loadReport.UpgradeBackup)
+	}
+	logIssues(logger, loadReport.Issues)
 
 	enumerator := orchestrator.NewWin32WindowEnumerator()
 	manager := orchestrator.NewWin32WindowManager()
 	orch := orchestrator.NewService(enumerator, manager, logger)
-	registrar := startup.NewRegistrar()
+	outputRoot := filepath.Join(appDir, "output")
+	orch.SetOutputRoot(outputRoot)
+	logon := newLogonRegistration()
+	importer := startup.NewSystemImporter()
+	journalPath := filepath.Join(appDir, "startup-import-undo.json")
+	historyStore := history.NewStore(filepath.Join(appDir, "history"), settings.RunHistoryLimit)
 
 	var (
-		mu     sync.Mutex
-		latest = settings
+		mu           sync.Mutex
+		latest       = settings
+		latestIssues = loadReport.Issues
 	)
 
 	var trayController *tray.Controller
 	var mainWindow *ui.MainWindow
 	activation, activationErr := ipc.NewActivationListener(activationEvent)
 	if activationErr != nil {
-		logger.Warn(fmt.Sprintf("activation listener unavailable: %v", activationErr))
+		logger.Warn("activation listener unavailable", "err", activationErr)
 	}
 	if activation != nil {
 		defer activation.Close()
 	}
+	stopRequests, stopRequestsErr := ipc.NewActivationListener(stopManagedEvent)
+	if stopRequestsErr != nil {
+		logger.Warn("stop request listener unavailable", "err", stopRequestsErr)
+	}
+	if stopRequests != nil {
+		defer stopRequests.Close()
+	}
+
+	stopManagedApps := func(ctx context.Context) []orchestrator.StopResult {
+		mu.Lock()
+		snapshot := latest
+		mu.Unlock()
+		grace := time.Duration(snapshot.StopGraceSeconds) * time.Second
+		results := orch.StopManagedApps(ctx, snapshot.ManagedApps, grace)
+		for _, r := range results {
+			logger.Info("stop summary", "app", r.AppName, "pid", r.PID, "skipped", r.Skipped, "closed", r.Closed, "killed", r.Killed, "err", r.Err)
+		}
+		return results
+	}
+	stopManagedAppsAndReport := func() {
+		results := stopManagedApps(context.Background())
+		failed := make([]string, 0)
+		for _, r := range results {
+			if r.Err != nil {
+				failed = append(failed, r.AppName)
+			}
+		}
+		if len(failed) > 0 && trayController != nil {
+			m := i18n.For(safeLanguage(mainWindow))
+			trayController.ShowWarning(m.StopManagedFailedTitle, fmt.Sprintf(m.StopManagedFailedBody, strings.Join(failed, ", ")))
+		}
+	}
 
 	cleanupAndRestore := func() {
 		if mainWindow == nil || mainWindow.Native() == nil {
@@ -115,12 +193,12 @@ func Run(args []string) {
 		latest = defaults
 		mu.Unlock()
 
-		if saveErr := store.Save(defaults); saveErr != nil {
+		if saveErr := store.Overwrite(defaults); saveErr != nil {
 			mainWindow.ShowError(m.CleanupFailedTitle, fmt.Sprintf(m.CleanupFailedBody, saveErr))
 			return
 		}
 
-		ensureRunAtLogon(registrar, defaults, logger)
+		ensureRunAtLogon(logon, defaults, logger)
 		if scheduleErr := scheduleAppDataCleanupOnExit(); scheduleErr != nil {
 			mainWindow.ShowError(m.CleanupFailedTitle, fmt.Sprintf(m.CleanupFailedBody, scheduleErr))
 			return
@@ -130,18 +208,39 @@ func Run(args []string) {
 		mainWindow.RequestExplicitClose()
 	}
 
+	// applySettings makes s the running settings, whether they were edited
+	// in the window or in the file.
+	applySettings := func(s config.Settings, issues config.Issues) {
+		mu.Lock()
+		latest = s
+		latestIssues = issues
+		mu.Unlock()
+		mainWindow.SetValidationIssues(issues)
+		logger.SetLevel(logging.ParseLevel(string(s.LogLevel)))
+		ensureRunAtLogon(logon, s, logger)
+		mainWindow.SetLogonStatus(logon.statusText(s.Language))
+		if trayController != nil {
+			trayController.SetLanguage(s.Language)
+			trayController.SetOutputEntries(outputItems(s))
+		}
+	}
+
 	mainWindow, err = ui.NewMainWindow(settings, ui.Callbacks{
 		OnSave: func(s config.Settings) {
-			mu.Lock()
-			latest = s
-			mu.Unlock()
-			if saveErr := store.Save(s); saveErr != nil {
-				logger.Warn(fmt.Sprintf("save settings failed: %v", saveErr))
+			saveErr := store.Save(s)
+			if errors.Is(saveErr, config.ErrSettingsChanged) {
+				if !mainWindow.ConfirmOverwrite() {
+					// The watcher loads the file's version into the window.
+					logger.Info("settings edit discarded for an external change")
+					store.Recheck()
+					return
+				}
+				saveErr = store.Overwrite(s)
 			}
-			ensureRunAtLogon(registrar, s, logger)
-			if trayController != nil {
-				trayController.SetLanguage(s.Language)
+			if saveErr != nil {
+				logger.Warn("save settings failed", "err", saveErr)
 			}
+			applySettings(s, config.Validate(s, config.LaunchTargetExists))
 		},
 		OnOpenLogs: func() {
 			if openErr := openLogLocation(); openErr != nil {
@@ -154,9 +253,46 @@ func Run(args []string) {
 		OnExit: func() {
 			mainWindow.RequestExplicitClose()
 		},
+		OnScanStartup: func() ([]startup.Item, error) {
+			items, scanErr := importer.Scan()
+			return withoutSelf(items), scanErr
+		},
+		OnDisableStartup: func(items []startup.Item) error {
+			journal, loadErr := startup.LoadJournal(journalPath)
+			if loadErr != nil && !errors.Is(loadErr, startup.ErrNoJournal) {
+				return loadErr
+			}
+			disableErr := importer.Disable(items, &journal, time.Now())
+			if saveErr := startup.SaveJournal(journalPath, journal); saveErr != nil {
+				logger.Warn("save startup import journal failed", "err", saveErr)
+			}
+			logger.Info("disabled imported startup items", "count", len(items))
+			return disableErr
+		},
+		OnUndoStartupImport: func() (int, error) {
+			journal, loadErr := startup.LoadJournal(journalPath)
+			if loadErr != nil {
+				return 0, loadErr
+			}
+			total := len(journal.Changes)
+			undoErr := importer.Undo(&journal)
+			if saveErr := startup.SaveJournal(journalPath, journal); saveErr != nil {
+				logger.Warn("save startup import journal failed", "err", saveErr)
+			}
+			restored := total - len(journal.Changes)
+			logger.Info("restored startup items disabled by import", "count", restored)
+			return restored, undoErr
+		},
+		OnLoadHistory: func() ([]history.Run, error) {
+			runs, listErr := historyStore.List(0)
+			if listErr != nil {
+				logger.Warn("load run history failed", "err", listErr)
+			}
+			return runs, listErr
+		},
 	})
 	if err != nil {
-		logger.Error(fmt.Sprintf("create main window failed: %v", err))
+		logger.Error("create main window failed", "err", err)
 		emitFatalWithLog(settings.Language, "failed to create main window", err)
 		return
 	}
@@ -166,20 +302,155 @@ func Run(args []string) {
 		})
 	}
 
-	ensureRunAtLogon(registrar, settings, logger)
+	ensureRunAtLogon(logon, settings, logger)
+	mainWindow.SetLogonStatus(logon.statusText(settings.Language))
 
 	trayController, err = tray.New(
 		mainWindow.Native(),
-		mainWindow.ShowMainWindow,
-		func() { mainWindow.RequestExplicitClose() },
+		tray.Callbacks{
+			OnOpen: mainWindow.ShowMainWindow,
+			OnRestoreWindow: func(handle uintptr) {
+				go func() {
+					if restoreErr := orch.Restore(handle); restoreErr != nil {
+						logger.Warn("restore window failed", "err", restoreErr)
+					}
+				}()
+			},
+			OnRestoreAll: func() {
+				go func() {
+					if restoreErr := orch.RestoreAll(); restoreErr != nil {
+						logger.Warn("restore all windows failed", "err", restoreErr)
+					}
+				}()
+			},
+			OnRestorePreviousSession: func() {
+				go func() {
+					if restoreErr := orch.RestorePreviousSession(); restoreErr != nil {
+						logger.Warn("restore previous session windows failed", "err", restoreErr)
+					}
+				}()
+			},
+			OnOpenOutput: func(entryID string) {
+				mu.Lock()
+				entry, ok := findManagedEntry(latest, entryID)
+				mu.Unlock()
+				if !ok {
+					return
+				}
+				m := i18n.For(safeLanguage(mainWindow))
+				openErr := openLatestOutput(outputRoot, entry)
+				switch {
+				case errors.Is(openErr, orchestrator.ErrNoOutput):
+					trayController.ShowInfo(m.OpenOutputNoneTitle, fmt.Sprintf(m.OpenOutputNoneBody, entry.Name))
+				case openErr != nil:
+					logger.Warn("open output failed", "entry", entry.ID, "app", entry.Name, "err", openErr)
+					trayController.ShowWarning(m.TrayOpenOutput, fmt.Sprintf(m.OpenOutputFailedBody, openErr))
+				}
+			},
+			OnShowHistory: mainWindow.ShowHistory,
+			OnCollectDiagnostics: func() {
+				mu.Lock()
+				snapshot := latest
+				mu.Unlock()
+				go func() {
+					path, collectErr := collectDiagnostics(orch, snapshot, appDir, "")
+					mainWindow.Native().Synchronize(func() {
+						m := i18n.For(snapshot.Language)
+						if collectErr != nil {
+							logger.Warn("collect diagnostics failed", "err", collectErr)
+							trayController.ShowWarning(m.DiagnosticsTitle, fmt.Sprintf("%s: %v", m.DiagnosticsFailed, collectErr))
+							return
+						}
+						logger.Info("diagnostics collected", "path", path)
+						trayController.ShowNotification(m.DiagnosticsTitle, fmt.Sprintf(m.DiagnosticsSaved, path), false, func() {
+							_ = exec.Command("explorer", "/select,", path).Start()
+						})
+					})
+				}()
+			},
+			OnStopManaged: func() { go stopManagedAppsAndReport() },
+			OnExit:        func() { mainWindow.RequestExplicitClose() },
+			Proxy: tray.ProxyActions{
+				OnToggle: func(target tray.ProxyTarget) {
+					go func() {
+						if _, visible, ok := enumerator.InspectWindow(target.Handle); ok && visible {
+							hideProxyTarget(orch, target, logger)
+							return
+						}
+						showProxyTarget(orch, target, logger)
+					}()
+				},
+				OnShow: func(target tray.ProxyTarget) { go showProxyTarget(orch, target, logger) },
+				OnHide: func(target tray.ProxyTarget) { go hideProxyTarget(orch, target, logger) },
+				OnQuit: func(target tray.ProxyTarget) {
+					go func() {
+						if quitErr := orch.Quit(target.Handle); quitErr != nil {
+							logger.Warn("proxy quit failed", "entry", target.EntryID, "app", target.AppName, "err", quitErr)
+						}
+					}()
+				},
+			},
+		},
 		settings.Language,
 	)
 	if err != nil {
-		logger.Error(fmt.Sprintf("create tray failed: %v", err))
+		logger.Error("create tray failed", "err", err)
 		emitFatalWithLog(settings.Language, "failed to create system tray", err)
 		return
 	}
 	defer trayController.Dispose()
+	trayController.SetOutputEntries(outputItems(settings))
+	if lastRun, historyErr := historyStore.Latest(); historyErr == nil {
+		trayController.SetLastRun(lastRun)
+	}
+	if stopRequests != nil {
+		stopRequests.Start(stopManagedAppsAndReport)
+	}
+
+	hiddenStore := orchestrator.NewHiddenWindowStore(filepath.Join(appDir, "hidden-windows.json"))
+	orch.OnHiddenWindowsChanged(func() {
+		hidden := orch.HiddenWindows()
+		if saveErr := hiddenStore.Save(hidden); saveErr != nil {
+			logger.Warn("save hidden window registry failed", "err", saveErr)
+		}
+		items := hiddenWindowItems(hidden)
+		mu.Lock()
+		proxies := proxyTargets(latest, hidden)
+		mu.Unlock()
+		mainWindow.Native().Synchronize(func() {
+			trayController.SetHiddenWindows(items)
+			for _, target := range proxies {
+				if proxyErr := trayController.EnsureProxyIcon(target); proxyErr != nil {
+					logger.Warn("create proxy tray icon failed", "entry", target.EntryID, "app", target.AppName, "err", proxyErr)
+				}
+			}
+		})
+	})
+	mainWindow.SetValidationIssues(loadReport.Issues)
+	adoptPreviousHiddenWindows(orch, hiddenStore, trayController, settings.Language, logger)
+	if loadReport.Err != nil {
+		m := i18n.For(settings.Language)
+		body := m.SettingsResetToDefaults
+		if loadReport.Backup != "" {
+			body = fmt.Sprintf(m.SettingsRestoredFromBackup, filepath.Base(loadReport.Backup))
+		}
+		if errors.Is(loadReport.Err, config.ErrSchemaTooNew) {
+			body = fmt.Sprintf(m.SettingsSchemaTooNew, loadReport.Err)
+		}
+		trayController.ShowWarning(m.SettingsRecoveredTitle, body)
+	}
+	orch.OnSupervisorEvent(func(state orchestrator.SupervisorState) {
+		if !state.CrashLoop {
+			return
+		}
+		if err := historyStore.Save(history.NewCrashLoopRun(state)); err != nil {
+			logger.Warn("save crash loop to run history failed", "entry", state.EntryID, "err", err)
+		}
+		mainWindow.Native().Synchronize(func() {
+			m := i18n.For(safeLanguage(mainWindow))
+			trayController.ShowWarning(m.SupervisorCrashLoopTitle, fmt.Sprintf(m.SupervisorCrashLoopBody, state.AppName, state.Restarts, state.LastExitCode))
+		})
+	})
 
 	if shouldShowMainWindow(args) {
 		mainWindow.ShowMainWindow()
@@ -190,11 +461,107 @@ func Run(args []string) {
 	managedCtx, managedCancel := context.WithCancel(context.Background())
 	defer managedCancel()
 
-	if isAutorunLaunch(args) {
+	go pruneProxyIcons(managedCtx, orch, trayController, mainWindow)
+	go store.Watch(managedCtx, config.DefaultWatchInterval, func(s config.Settings, report config.LoadReport) bool {
+		// The change is applied on the UI thread, like a save from the window.
+		adopted := make(chan bool, 1)
+		mainWindow.Native().Synchronize(func() {
+			m := i18n.For(safeLanguage(mainWindow))
+			if report.Err != nil {
+				logger.Warn("changed settings file cannot be read", "err", report.Err)
+				trayController.ShowWarning(m.SettingsExternalTitle, fmt.Sprintf(m.SettingsExternalInvalid, report.Err))
+				adopted <- false
+				return
+			}
+			if !mainWindow.ConfirmExternalChange() {
+				logger.Info("external settings change declined")
+				adopted <- false
+				return
+			}
+			logger.Info("settings reloaded after an external change")
+			logIssues(logger, report.Issues)
+			applySettings(s, report.Issues)
+			mainWindow.ReloadSettings(s)
+			trayController.ShowInfo(m.SettingsExternalTitle, m.SettingsExternalReloaded)
+			adopted <- true
+		})
+		select {
+		case ok := <-adopted:
+			return ok
+		case <-managedCtx.Done():
+			return false
+		}
+	})
+
+	mu.Lock()
+	strictSkip := isAutorunLaunch(args) && latest.StrictValidation && latestIssues.HasErrors()
+	mu.Unlock()
+
+	// Exits of background launches are added to the run that started them,
+	// and wait in pendingExits until the autorun run is saved.
+	var (
+		exitMu       sync.Mutex
+		runSaved     = !isAutorunLaunch(args) || strictSkip
+		pendingExits []orchestrator.ExitRecord
+	)
+	orch.OnProcessExit(func(record orchestrator.ExitRecord) {
+		exitMu.Lock()
+		defer exitMu.Unlock()
+		if !runSaved {
+			pendingExits = append(pendingExits, record)
+			return
+		}
+		if _, err := historyStore.RecordExit(record); err != nil {
+			logger.Warn("record exit in run history failed", "entry", record.EntryID, "err", err)
+		}
+	})
+
+	if strictSkip {
+		logger.Error("autorun skipped: settings have errors and strict validation is on")
+		m := i18n.For(settings.Language)
+		trayController.ShowNotification(m.ValidationTitle, m.ValidationStrictSkipped, true, mainWindow.ShowValidationIssues)
+	} else if isAutorunLaunch(args) {
 		mu.Lock()
 		snapshot := latest
 		mu.Unlock()
-		go runManagedApps(managedCtx, orch, mainWindow, snapshot, snapshot.ExitAfterManagedAppsCompleted, logger)
+		recordRun := func(run history.Run) {
+			mu.Lock()
+			current := latest
+			mu.Unlock()
+			// The balloon would go away with the tray icon when WinTray exits
+			// right after the run.
+			var entries []history.Entry
+			if !snapshot.ExitAfterManagedAppsCompleted {
+				entries = notify.Entries(current.AutorunNotify, run, notify.MutedEntries(current))
+			}
+			if len(entries) > 0 {
+				previous, _ := historyStore.List(0)
+				if notify.DefaultLimiter.Allow(previous, run.StartedAt) {
+					run.Notified = true
+				} else {
+					logger.Info("autorun notification skipped: rate limited", "run", run.ID)
+				}
+			}
+			exitMu.Lock()
+			for _, record := range pendingExits {
+				run.RecordExit(record)
+			}
+			pendingExits = nil
+			saveErr := historyStore.Save(run)
+			runSaved = true
+			exitMu.Unlock()
+			if saveErr != nil {
+				logger.Warn("save run history failed", "run", run.ID, "err", saveErr)
+			}
+			mainWindow.Native().Synchronize(func() {
+				trayController.SetLastRun(run)
+				if run.Notified {
+					title, body := i18n.FormatRunNotification(current.Language, entries, current.AutorunNotify == config.NotifyFailures)
+					trayController.ShowNotification(title, body, run.Managed() < len(run.Entries), mainWindow.ShowHistory)
+				}
+			})
+		}
+		go runManagedApps(managedCtx, orch, mainWindow, snapshot, snapshot.ExitAfterManagedAppsCompleted, logger, recordRun)
 	}
 
 	exitCode := mainWindow.Run()
@@ -203,14 +570,23 @@ func Run(args []string) {
 	mu.Lock()
 	finalSettings := latest
 	mu.Unlock()
-	if saveErr := store.Save(finalSettings); saveErr != nil {
-		logger.Warn(fmt.Sprintf("save settings on exit failed: %v", saveErr))
+	if finalSettings.StopManagedAppsOnExit {
+		stopManagedApps(context.Background())
+	}
+	// An external change that was not adopted is kept rather than replaced.
+	if saveErr := store.Save(finalSettings); errors.Is(saveErr, config.ErrSettingsChanged) {
+		logger.Info("settings not saved on exit: the file was changed outside WinTray")
+	} else if saveErr != nil {
+		logger.Warn("save settings on exit failed", "err", saveErr)
 	}
 	os.Exit(exitCode)
 }
 
-func runManagedApps(ctx context.Context, orch *orchestrator.Service, mainWindow *ui.MainWindow, settings config.Settings, autoExit bool, logger *logging.Logger) {
-	msg := i18n.For(settings.Language)
+// runManagedApps processes the managed entries and passes the run record to
+// record before deciding whether to exit.
+func runManagedApps(ctx context.Context, orch *orchestrator.Service, mainWindow *ui.MainWindow, settings config.Settings, autoExit bool, logger *logging.Logger, record func(history.Run)) {
+	run := history.NewRun(history.TriggerAutorun, time.Now())
+	ctx = orchestrator.WithLogFields(ctx, "run", run.ID)
 	managedEntries := make([]config.ManagedAppEntry, 0, len(settings.ManagedApps))
 	for _, entry := range settings.ManagedApps {
 		if config.ShouldLaunchViaWinTray(entry) {
@@ -218,7 +594,7 @@ func runManagedApps(ctx context.Context, orch *orchestrator.Service, mainWindow
 		}
 	}
 
-	summaries := make([]string, len(managedEntries))
+	run.Entries = make([]history.Entry, len(managedEntries))
 	var wg sync.WaitGroup
 	for i, entry := range managedEntries {
 		i := i
@@ -226,22 +602,20 @@ func runManagedApps(ctx context.Context, orch *orchestrator.Service, mainWindow
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
+			started := time.Now()
 			result := processManagedEntry(ctx, orch, settings, entry, logger)
-
-			detail := i18n.TranslateResultMessage(settings.Language, result.Message)
-			if !result.Managed && i18n.IsLikelyPermissionIssue(result.Message) {
-				detail += " " + msg.StatusPermissionHint
-			}
-			summaries[i] = fmt.Sprintf(msg.RunSummaryLine, result.AppName, detail)
+			run.Entries[i] = history.NewEntry(entry, result, started, time.Now())
+			logger.Info("managed summary", "run", run.ID, "entry", entry.ID, "app", result.AppName,
+				"code", result.Code, "managed", result.Managed, "permission", i18n.IsLikelyPermissionIssue(result),
+				"elapsed", run.Entries[i].EndedAt.Sub(started))
 		}()
 	}
 	wg.Wait()
+	run.EndedAt = time.Now()
+	record(run)
 
 	if len(managedEntries) == 0 {
-		summaries = append(summaries, msg.RunSummaryNone)
-	}
-	for _, line := range summaries {
-		logger.Info(fmt.Sprintf("managed summary: %s", line))
+		logger.Info("managed summary: no managed tasks to run", "run", run.ID)
 	}
 
 	hadTasks := len(managedEntries) > 0
@@ -260,12 +634,373 @@ func processManagedEntry(ctx context.Context, orch *orchestrator.Service, settin
 
 	result := orch.StartAndManage(ctx, entry, settings.CloseWindowRetrySeconds)
 	if !result.Managed {
-		logger.Warn(fmt.Sprintf("managed startup app failed: %s %s", result.AppName, result.Message))
+		logger.Warn("managed startup app failed", "entry", entry.ID, "app", result.AppName, "code", result.Code, "err", result.Err)
 	}
 	return result
 }
 
-func ensureRunAtLogon(registrar *startup.Registrar, settings config.Settings, logger *logging.Logger) {
+func hiddenWindowItems(hidden []orchestrator.HiddenWindow) []tray.HiddenWindowItem {
+	items := make([]tray.HiddenWindowItem, 0, len(hidden))
+	for _, h := range hidden {
+		items = append(items, tray.HiddenWindowItem{
+			Handle:              h.Handle,
+			AppName:             h.AppName,
+			Title:               h.Title,
+			FromPreviousSession: h.FromPreviousSession,
+		})
+	}
+	return items
+}
+
+// proxyTargets selects the hidden windows whose entry opted into a proxy tray icon.
+func proxyTargets(settings config.Settings, hidden []orchestrator.HiddenWindow) []tray.ProxyTarget {
+	enabled := map[string]bool{}
+	for _, entry := range settings.ManagedApps {
+		if entry.TrayBehavior.ProxyTrayIcon {
+			enabled[entry.ID] = true
+		}
+	}
+	targets := make([]tray.ProxyTarget, 0)
+	for _, h := range hidden {
+		if h.EntryID == "" || !enabled[h.EntryID] {
+			continue
+		}
+		targets = append(targets, tray.ProxyTarget{
+			Handle:    h.Handle,
+			ProcessID: h.ProcessID,
+			EntryID:   h.EntryID,
+			AppName:   h.AppName,
+			Title:     h.Title,
+			ExePath:   h.ProcessPath,
+		})
+	}
+	return targets
+}
+
+func showProxyTarget(orch *orchestrator.Service, target tray.ProxyTarget, logger *logging.Logger) {
+	if err := orch.Restore(target.Handle); err != nil {
+		logger.Info("proxy show skipped", "entry", target.EntryID, "app", target.AppName, "err", err)
+	}
+}
+
+func hideProxyTarget(orch *orchestrator.Service, target tray.ProxyTarget, logger *logging.Logger) {
+	err := orch.Rehide(orchestrator.HiddenWindow{
+		Handle:      target.Handle,
+		ProcessID:   target.ProcessID,
+		ProcessPath: target.ExePath,
+		EntryID:     target.EntryID,
+		AppName:     target.AppName,
+		Title:       target.Title,
+	})
+	if err != nil {
+		logger.Warn("proxy hide failed", "entry", target.EntryID, "app", target.AppName, "err", err)
+	}
+}
+
+// pruneProxyIcons removes proxy icons of apps that have exited, together with
+// their stale hidden-window registry entries.
+func pruneProxyIcons(ctx context.Context, orch *orchestrator.Service, trayController *tray.Controller, mainWindow *ui.MainWindow) {
+	processes := orchestrator.NewWin32ProcessController()
+	ticker := time.NewTicker(3 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		orch.PruneHidden()
+		mainWindow.Native().Synchronize(func() {
+			trayController.PruneProxyIcons(func(target tray.ProxyTarget) bool {
+				return processes.IsProcessRunning(target.ProcessID)
+			})
+		})
+	}
+}
+
+// outputItems lists the entries whose background output is captured.
+func outputItems(settings config.Settings) []tray.OutputItem {
+	items := make([]tray.OutputItem, 0)
+	for _, entry := range settings.ManagedApps {
+		if entry.CaptureOutput && entry.LaunchHiddenInBackground {
+			items = append(items, tray.OutputItem{EntryID: entry.ID, AppName: entry.Name})
+		}
+	}
+	return items
+}
+
+// findManagedEntry looks an entry up by ID, falling back to a case-insensitive
+// name match for command-line use.
+func findManagedEntry(settings config.Settings, idOrName string) (config.ManagedAppEntry, bool) {
+	for _, entry := range settings.ManagedApps {
+		if entry.ID != "" && entry.ID == idOrName {
+			return entry, true
+		}
+	}
+	for _, entry := range settings.ManagedApps {
+		if strings.EqualFold(entry.Name, idOrName) {
+			return entry, true
+		}
+	}
+	return config.ManagedAppEntry{}, false
+}
+
+func openLatestOutput(outputRoot string, entry config.ManagedAppEntry) error {
+	path, err := orchestrator.LatestOutput(outputRoot, entry)
+	if err != nil {
+		return err
+	}
+	return exec.Command("explorer", path).Start()
+}
+
+// runOpenOutput handles --open-output without starting the tray.
+func runOpenOutput(target string) {
+	settings := config.NewStore(config.SettingsPath()).Load()
+	m := i18n.For(settings.Language)
+	entry, ok := findManagedEntry(settings, target)
+	if !ok {
+		showMessage(m.OpenOutputNoneTitle, fmt.Sprintf(m.OpenOutputNoneBody, target), walk.MsgBoxIconInformation)
+		return
+	}
+	appDir, err := config.AppDirWithError()
+	if err != nil {
+		showMessage(m.WindowTitle, fmt.Sprintf(m.OpenOutputFailedBody, err), walk.MsgBoxIconError)
+		return
+	}
+	openErr := openLatestOutput(filepath.Join(appDir, "output"), entry)
+	switch {
+	case errors.Is(openErr, orchestrator.ErrNoOutput):
+		showMessage(m.OpenOutputNoneTitle, fmt.Sprintf(m.OpenOutputNoneBody, entry.Name), walk.MsgBoxIconInformation)
+	case openErr != nil:
+		showMessage(m.WindowTitle, fmt.Sprintf(m.OpenOutputFailedBody, openErr), walk.MsgBoxIconError)
+	}
+}
+
+// runPrintHistory handles --history without starting the tray. The runs are
+// written to standard output as JSON when it is redirected; otherwise their
+// summaries are shown in a message box.
+func runPrintHistory(n int) {
+	settings := config.NewStore(config.SettingsPath()).Load()
+	m := i18n.For(settings.Language)
+	appDir, err := config.AppDirWithError()
+	if err != nil {
+		showMessage(m.RunHistory, fmt.Sprintf("%s: %v", m.RunHistoryLoadFailed, err), walk.MsgBoxIconError)
+		return
+	}
+	runs, listErr := history.NewStore(filepath.Join(appDir, "history"), settings.RunHistoryLimit).List(n)
+
+	if _, statErr := os.Stdout.Stat(); statErr == nil {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(runs)
+		if listErr != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", m.RunHistoryLoadFailed, listErr)
+		}
+		return
+	}
+	if listErr != nil && len(runs) == 0 {
+		showMessage(m.RunHistory, fmt.Sprintf("%s: %v", m.RunHistoryLoadFailed, listErr), walk.MsgBoxIconError)
+		return
+	}
+	if len(runs) == 0 {
+		showMessage(m.RunHistory, m.RunHistoryNone, walk.MsgBoxIconInformation)
+		return
+	}
+	lines := make([]string, len(runs))
+	for i, run := range runs {
+		lines[i] = i18n.FormatRunSummary(settings.Language, run)
+	}
+	showMessage(m.RunHistory, strings.Join(lines, "\r\n"), walk.MsgBoxIconInformation)
+}
+
+// collectDiagnostics writes a diagnostics bundle to path, or to the
+// diagnostics folder of appDir when path is empty, and returns where it went.
+func collectDiagnostics(orch *orchestrator.Service, settings config.Settings, appDir, path string) (string, error) {
+	now := time.Now()
+	if path == "" {
+		path = diagnostics.DefaultPath(filepath.Join(appDir, "diagnostics"), now)
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	src := diagnostics.Sources{
+		LogDir:  appDir,
+		History: history.NewStore(filepath.Join(appDir, "history"), settings.RunHistoryLimit),
+	}
+	return path, diagnostics.Write(path, diagnostics.Capture(orch, settings, now), src, logRedactor(settings))
+}
+
+// runCollectDiagnostics handles --collect-diagnostics without starting the
+// UI. The bundle path is printed when standard output is redirected and
+// shown in a dialog otherwise.
+func runCollectDiagnostics(path string) {
+	settings := config.NewStore(config.SettingsPath()).Load()
+	m := i18n.For(settings.Language)
+	appDir, err := config.AppDirWithError()
+	if err == nil {
+		orch := orchestrator.NewService(orchestrator.NewWin32WindowEnumerator(), orchestrator.NewWin32WindowManager(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+		path, err = collectDiagnostics(orch, settings, appDir, path)
+	}
+
+	if _, statErr := os.Stdout.Stat(); statErr == nil {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", m.DiagnosticsFailed, err)
+			os.Exit(1)
+		}
+		fmt.Println(path)
+		return
+	}
+	if err != nil {
+		showMessage(m.DiagnosticsTitle, fmt.Sprintf("%s: %v", m.DiagnosticsFailed, err), walk.MsgBoxIconError)
+		return
+	}
+	showMessage(m.DiagnosticsTitle, fmt.Sprintf(m.DiagnosticsSaved, path), walk.MsgBoxIconInformation)
+}
+
+// logIssues writes one record per settings issue.
+func logIssues(logger *logging.Logger, issues config.Issues) {
+	for _, issue := range issues {
+		level := slog.LevelWarn
+		if issue.Severity == config.SeverityError {
+			level = slog.LevelError
+		}
+		logger.Log(context.Background(), level, "settings issue", "code", issue.Code, "path", issue.Path, "value", issue.Value)
+	}
+}
+
+// runValidate handles --validate without starting the UI. It checks path, or
+// the current settings file when path is empty. The issues are written to
+// standard output as JSON when it is redirected, exiting with status 1 if
+// any is an error; otherwise they are shown in a dialog.
+func runValidate(path string) {
+	settingsPath := config.SettingsPath()
+	language := config.NewStore(settingsPath).Load().Language
+	m := i18n.For(language)
+	if path == "" {
+		path = settingsPath
+	}
+	issues, err := config.ValidateFile(path)
+	if errors.Is(err, os.ErrNotExist) && path == settingsPath {
+		err = nil
+	}
+
+	if _, statErr := os.Stdout.Stat(); statErr == nil {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			os.Exit(1)
+		}
+		if issues == nil {
+			issues = config.Issues{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(issues)
+		if issues.HasErrors() {
+			os.Exit(1)
+		}
+		return
+	}
+	switch {
+	case err != nil:
+		showMessage(m.ValidationTitle, fmt.Sprintf("%s: %v", path, err), walk.MsgBoxIconError)
+	case issues.HasErrors():
+		showMessage(m.ValidationTitle, i18n.FormatIssues(language, issues), walk.MsgBoxIconError)
+	case len(issues) > 0:
+		showMessage(m.ValidationTitle, i18n.FormatIssues(language, issues), walk.MsgBoxIconWarning)
+	default:
+		showMessage(m.ValidationTitle, m.ValidationNone, walk.MsgBoxIconInformation)
+	}
+}
+
+func adoptPreviousHiddenWindows(orch *orchestrator.Service, store *orchestrator.HiddenWindowStore, trayController *tray.Controller, language string, logger *logging.Logger) {
+	persisted, err := store.Load()
+	if err != nil {
+		logger.Warn("load hidden window registry failed", "err", err)
+	}
+	adopted := orch.AdoptHiddenWindows(persisted)
+	// Rewrite the file so entries pruned during reconciliation do not linger.
+	if err = store.Save(orch.HiddenWindows()); err != nil {
+		logger.Warn("save hidden window registry failed", "err", err)
+	}
+	if len(adopted) == 0 {
+		return
+	}
+	logger.Info("adopted windows hidden in previous session", "count", len(adopted))
+	m := i18n.For(language)
+	trayController.ShowInfo(m.PreviousSessionHiddenTitle, fmt.Sprintf(m.PreviousSessionHiddenBody, len(adopted)))
+}
+
+// withoutSelf drops WinTray's own run-at-logon entry from importable items.
+func withoutSelf(items []startup.Item) []startup.Item {
+	exePath, _ := os.Executable()
+	out := items[:0]
+	for _, item := range items {
+		if item.Origin == startup.OriginRunKey && item.Scope == startup.ScopeUser && strings.EqualFold(item.Name, appName) {
+			continue
+		}
+		if exePath != "" && strings.EqualFold(item.Target, exePath) {
+			continue
+		}
+		out = append(out, item)
+	}
+	return out
+}
+
+// logonRegistration applies the run-at-logon settings to the selected
+// backend. Unchanged settings are not reapplied, so saving settings does not
+// run schtasks every time.
+type logonRegistration struct {
+	task     *startup.TaskRegistrar
+	backends startup.Backends
+	applied  string
+
+	selected config.StartupBackend
+	result   startup.Applied
+	err      error
+}
+
+func newLogonRegistration() *logonRegistration {
+	task := startup.NewTaskRegistrar()
+	return &logonRegistration{
+		task: task,
+		backends: startup.Backends{
+			config.StartupRunKey:        startup.NewRunKeyRegistrar(),
+			config.StartupFolder:        startup.NewStartupFolderRegistrar(),
+			config.StartupTaskScheduler: task,
+		},
+	}
+}
+
+// logOptions maps the log settings to logging options. They only take
+// effect when the log file is opened at startup, except for LogLevel.
+func logOptions(settings config.Settings) logging.Options {
+	return logging.Options{
+		Level: logging.ParseLevel(string(settings.LogLevel)),
+		JSON:  settings.LogFormat == config.LogJSON,
+		Retention: logging.Retention{
+			MaxSize:     int64(settings.LogRetention.MaxSizeMB) << 20,
+			MaxAge:      time.Duration(settings.LogRetention.MaxAgeDays) * 24 * time.Hour,
+			Generations: settings.LogRetention.Generations,
+			TotalSize:   int64(settings.LogRetention.TotalSizeMB) << 20,
+		},
+		Privacy:    logPrivacy[settings.LogPrivacy],
+		ProfileDir: os.Getenv("USERPROFILE"),
+	}
+}
+
+// logRedactor redacts files outside the log the way the logger does.
+func logRedactor(settings config.Settings) logging.Redactor {
+	return logging.NewRedactor(logPrivacy[settings.LogPrivacy], os.Getenv("USERPROFILE"))
+}
+
+var logPrivacy = map[config.LogPrivacy]logging.Privacy{
+	config.LogPrivacyOff:      logging.PrivacyOff,
+	config.LogPrivacyTruncate: logging.PrivacyTruncate,
+	config.LogPrivacyHash:     logging.PrivacyHash,
+}
+
+func ensureRunAtLogon(logon *logonRegistration, settings config.Settings, logger *logging.Logger) {
 	exePath, err := os.Executable()
 	if err != nil || exePath == "" {
 		logger.Warn("unable to resolve executable path for run-at-logon")
@@ -275,8 +1010,67 @@ func ensureRunAtLogon(registrar *startup.Registrar, settings config.Settings, lo
 	if settings.StartMinimizedToTray {
 		command = fmt.Sprintf("\"%s\" --background --autorun", exePath)
 	}
-	if err = registrar.SetEnabled(appName, command, settings.RunAtLogon); err != nil {
-		logger.Warn(fmt.Sprintf("set run-at-logon failed: %v", err))
+	state := fmt.Sprintf("%s|%s|%t|%+v", settings.StartupBackend, command, settings.RunAtLogon, settings.LogonTask)
+	if state == logon.applied {
+		return
+	}
+	logon.task.Options = startup.TaskOptions{
+		Delay:             time.Duration(settings.LogonTask.DelaySeconds) * time.Second,
+		HighestPrivileges: settings.LogonTask.HighestPrivileges,
+		DisallowOnBattery: settings.LogonTask.DisallowOnBattery,
+	}
+	logon.selected = settings.StartupBackend
+	logon.result, logon.err = logon.backends.Apply(settings.StartupBackend, appName, command, exePath, settings.RunAtLogon)
+	if logon.err != nil {
+		logger.Warn("set run-at-logon failed", "backend", settings.StartupBackend, "err", logon.err)
+		return
+	}
+	result := logon.result
+	if result.Backend != settings.StartupBackend {
+		logger.Warn("run-at-logon fell back", "selected", settings.StartupBackend, "used", result.Backend)
+	}
+	if result.CleanupErr != nil {
+		logger.Warn("remove other run-at-logon registrations failed", "err", result.CleanupErr)
+	}
+	if result.Repaired {
+		logger.Info("run-at-logon repaired", "backend", result.Backend, "command", result.Status.Command)
+	}
+	if result.Status.DisabledByUser {
+		logger.Info("run-at-logon disabled by user", "backend", result.Backend)
+	}
+	logon.applied = state
+}
+
+// statusText describes the last applied registration for the settings window.
+func (l *logonRegistration) statusText(language string) string {
+	m := i18n.For(language)
+	st := l.result.Status
+	switch {
+	case l.err != nil:
+		return fmt.Sprintf(m.LogonStatusUnavailable, l.err)
+	case !st.Present:
+		return m.LogonStatusNotRegistered
+	case st.DisabledByUser:
+		return m.LogonStatusDisabledByUser
+	case !st.MatchesExecutable:
+		return fmt.Sprintf(m.LogonStatusOtherExe, st.Command)
+	case l.result.Backend != l.selected:
+		return fmt.Sprintf(m.LogonStatusFallback, startupBackendName(m, l.result.Backend))
+	case l.result.Repaired:
+		return m.LogonStatusRepaired
+	default:
+		return m.LogonStatusRegistered
+	}
+}
+
+func startupBackendName(m i18n.Messages, backend config.StartupBackend) string {
+	switch backend {
+	case config.StartupTaskScheduler:
+		return m.StartupBackendTask
+	case config.StartupFolder:
+		return m.StartupBackendFolder
+	default:
+		return m.StartupBackendRunKey
 	}
 }
 
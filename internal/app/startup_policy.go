@@ -1,6 +1,9 @@
 package app
 
-import "strings"
+import (
+	"strconv"
+	"strings"
+)
 
 func isBackgroundLaunch(args []string) bool {
 	for _, arg := range args {
@@ -29,6 +32,83 @@ func isCleanupRestoreLaunch(args []string) bool {
 	return false
 }
 
+func isStopManagedLaunch(args []string) bool {
+	for _, arg := range args {
+		if strings.EqualFold(arg, "--stop-managed") {
+			return true
+		}
+	}
+	return false
+}
+
+// openOutputTarget returns the entry named by --open-output, accepting both
+// "--open-output=<id|name>" and "--open-output <id|name>".
+func openOutputTarget(args []string) (string, bool) {
+	const flag = "--open-output"
+	for i, arg := range args {
+		if strings.EqualFold(arg, flag) {
+			if i+1 < len(args) {
+				return args[i+1], true
+			}
+			return "", false
+		}
+		if len(arg) > len(flag) && strings.EqualFold(arg[:len(flag)+1], flag+"=") {
+			return arg[len(flag)+1:], true
+		}
+	}
+	return "", false
+}
+
+// historyCount returns how many runs --history asks for, accepting
+// "--history" for every retained run and "--history=<n>" for the newest n.
+func historyCount(args []string) (int, bool) {
+	const flag = "--history"
+	for _, arg := range args {
+		if strings.EqualFold(arg, flag) {
+			return 0, true
+		}
+		if len(arg) > len(flag) && strings.EqualFold(arg[:len(flag)+1], flag+"=") {
+			n, err := strconv.Atoi(arg[len(flag)+1:])
+			if err != nil || n < 0 {
+				n = 0
+			}
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// diagnosticsTarget returns the bundle path --collect-diagnostics asks for,
+// accepting "--collect-diagnostics" for the default location and
+// "--collect-diagnostics=<file>".
+func diagnosticsTarget(args []string) (string, bool) {
+	const flag = "--collect-diagnostics"
+	for _, arg := range args {
+		if strings.EqualFold(arg, flag) {
+			return "", true
+		}
+		if len(arg) > len(flag) && strings.EqualFold(arg[:len(flag)+1], flag+"=") {
+			return arg[len(flag)+1:], true
+		}
+	}
+	return "", false
+}
+
+// validateTarget returns the settings file --validate asks to check,
+// accepting "--validate" for the current settings and "--validate=<file>".
+func validateTarget(args []string) (string, bool) {
+	const flag = "--validate"
+	for _, arg := range args {
+		if strings.EqualFold(arg, flag) {
+			return "", true
+		}
+		if len(arg) > len(flag) && strings.EqualFold(arg[:len(flag)+1], flag+"=") {
+			return arg[len(flag)+1:], true
+		}
+	}
+	return "", false
+}
+
 func shouldShowMainWindow(args []string) bool {
 	return !isBackgroundLaunch(args)
 }
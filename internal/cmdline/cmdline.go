@@ -0,0 +1,150 @@
+// Package cmdline converts between argument lists and Windows command lines.
+//
+// Windows passes a process its command line as a single string, and each
+// program splits it itself. Split and Quote follow the rules of
+// CommandLineToArgvW, which the Microsoft C runtime and Go also use. Command
+// lines run through cmd.exe are parsed by cmd.exe first; JoinCmd additionally
+// escapes its metacharacters so that the target still receives the intended
+// arguments.
+package cmdline
+
+import "strings"
+
+// Split parses commandLine into arguments the way CommandLineToArgvW parses
+// everything after the program name:
+//
+//   - spaces and tabs separate arguments unless inside double quotes;
+//   - 2n backslashes followed by '"' produce n backslashes and toggle quoting;
+//   - 2n+1 backslashes followed by '"' produce n backslashes and a literal '"';
+//   - backslashes not followed by '"' are literal;
+//   - inside quotes, '""' produces a literal '"' and ends the quoted section.
+func Split(commandLine string) []string {
+	var args []string
+	for {
+		commandLine = strings.TrimLeft(commandLine, " \t")
+		if commandLine == "" {
+			return args
+		}
+		var arg string
+		arg, commandLine = nextArg(commandLine)
+		args = append(args, arg)
+	}
+}
+
+func nextArg(s string) (string, string) {
+	var b strings.Builder
+	inQuote := false
+	backslashes := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\':
+			backslashes++
+			continue
+		case c == '"':
+			b.WriteString(strings.Repeat(`\`, backslashes/2))
+			if backslashes%2 == 1 {
+				b.WriteByte('"')
+			} else if inQuote && i+1 < len(s) && s[i+1] == '"' {
+				b.WriteByte('"')
+				i++
+				inQuote = false
+			} else {
+				inQuote = !inQuote
+			}
+			backslashes = 0
+			continue
+		case (c == ' ' || c == '\t') && !inQuote:
+			b.WriteString(strings.Repeat(`\`, backslashes))
+			return b.String(), s[i+1:]
+		}
+		b.WriteString(strings.Repeat(`\`, backslashes))
+		backslashes = 0
+		b.WriteByte(c)
+	}
+	b.WriteString(strings.Repeat(`\`, backslashes))
+	return b.String(), ""
+}
+
+// Quote returns arg in a form that Split parses back to arg.
+func Quote(arg string) string {
+	if arg == "" {
+		return `""`
+	}
+	if !strings.ContainsAny(arg, " \t\"") {
+		return arg
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	backslashes := 0
+	for i := 0; i < len(arg); i++ {
+		c := arg[i]
+		switch c {
+		case '\\':
+			backslashes++
+			continue
+		case '"':
+			// Escape the preceding backslashes and the quote itself.
+			b.WriteString(strings.Repeat(`\`, 2*backslashes+1))
+		default:
+			b.WriteString(strings.Repeat(`\`, backslashes))
+		}
+		backslashes = 0
+		b.WriteByte(c)
+	}
+	// Backslashes before the closing quote must be doubled.
+	b.WriteString(strings.Repeat(`\`, 2*backslashes))
+	b.WriteByte('"')
+	return b.String()
+}
+
+// Join quotes each argument and joins them with spaces.
+func Join(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = Quote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// cmdMetachars are the characters cmd.exe interprets outside of quotes.
+const cmdMetachars = `()%!^<>&|`
+
+// cmdPercent stands for a literal '%' inside quotes, where '^' is not an
+// escape: "%" is followed by an empty substring of the %cd% variable, so
+// no %VAR% reference can form around it.
+const cmdPercent = `%%cd:~,%`
+
+// QuoteCmd is JoinCmd for a single argument.
+func QuoteCmd(arg string) string {
+	return escapeCmd(Quote(arg))
+}
+
+// JoinCmd is Join for command lines that cmd.exe parses before the target
+// does. The quotes of the joined line are left as they are, so cmd.exe sees
+// the same quoted sections as the target; metacharacters outside them are
+// escaped with '^' and '%' inside them is written as cmdPercent.
+func JoinCmd(args []string) string {
+	return escapeCmd(Join(args))
+}
+
+// escapeCmd follows the quoting of s the way cmd.exe does: every '"' toggles
+// it, including the ones the target reads as escaped.
+func escapeCmd(s string) string {
+	var b strings.Builder
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == '%' && quoted:
+			b.WriteString(cmdPercent)
+			continue
+		case !quoted && strings.IndexByte(cmdMetachars, c) >= 0:
+			b.WriteByte('^')
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
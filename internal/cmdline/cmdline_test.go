@@ -0,0 +1,183 @@
+package cmdline
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"", nil},
+		{"   ", nil},
+		{"--verbose", []string{"--verbose"}},
+		{`--config "C:\My Path\cfg.json" --verbose`, []string{"--config", `C:\My Path\cfg.json`, "--verbose"}},
+		{`  --flag   value  `, []string{"--flag", "value"}},
+		{"a\tb", []string{"a", "b"}},
+		{`"quoted arg"`, []string{"quoted arg"}},
+		{`one two three`, []string{"one", "two", "three"}},
+		{`""`, []string{""}},
+		{`a "" b`, []string{"a", "", "b"}},
+		{`ab"c d"ef`, []string{"abc def"}},
+		{`"abc" d e`, []string{"abc", "d", "e"}},
+		{`a\\b d"e f"g h`, []string{`a\\b`, "de fg", "h"}},
+		{`a\\\"b c d`, []string{`a\"b`, "c", "d"}},
+		{`a\\\\"b c" d e`, []string{`a\\b c`, "d", "e"}},
+		{`C:\dir\ x`, []string{`C:\dir\`, "x"}},
+		{`"C:\dir\\" x`, []string{`C:\dir\`, "x"}},
+		{`"a""b"`, []string{`a"b`}},
+		{`"a"" b"`, []string{`a"`, `b`}},
+		{`"unterminated arg`, []string{"unterminated arg"}},
+		{`trailing\\`, []string{`trailing\\`}},
+		{`a&b ^c %PATH%`, []string{"a&b", "^c", "%PATH%"}},
+	}
+	for _, tc := range tests {
+		got := Split(tc.input)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Split(%q) = %q, want %q", tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", `""`},
+		{"plain", "plain"},
+		{`C:\dir\file.txt`, `C:\dir\file.txt`},
+		{"with space", `"with space"`},
+		{"tab\there", "\"tab\there\""},
+		{`say "hi"`, `"say \"hi\""`},
+		{`a"b`, `"a\"b"`},
+		{`C:\My Dir\`, `"C:\My Dir\\"`},
+		{`a\"b`, `"a\\\"b"`},
+		{"a&b", "a&b"},
+	}
+	for _, tc := range tests {
+		if got := Quote(tc.input); got != tc.want {
+			t.Errorf("Quote(%q) = %q, want %q", tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestJoinSplitRoundTrip(t *testing.T) {
+	cases := [][]string{
+		{},
+		{""},
+		{"a", "", "b"},
+		{`C:\Program Files\App\app.exe`, "--config", `C:\My Path\`},
+		{`he said "hi"`, `\\server\share\`, `trailing\\`},
+		{`\"`, `"`, `""`, `\`, `\\"\\`},
+		{"a&b", "c|d", "100%", "%PATH%", "(x)", "^", "!bang!"},
+		{"tab\tsep", "  spaced  "},
+	}
+	for _, args := range cases {
+		got := Split(Join(args))
+		if len(args) == 0 && got == nil {
+			continue
+		}
+		if !reflect.DeepEqual(got, args) {
+			t.Errorf("Split(Join(%q)) = %q via %q", args, got, Join(args))
+		}
+	}
+}
+
+func TestQuoteCmd(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"plain", "plain"},
+		{"a&b", "a^&b"},
+		{"x|y>z<w", "x^|y^>z^<w"},
+		{"%PATH%", "^%PATH^%"},
+		{"caret^", "caret^^"},
+		{"(group)!", "^(group^)^!"},
+		{"with space", `"with space"`},
+		{`C:\A & B\run.cmd`, `"C:\A & B\run.cmd"`},
+		{"50 %", `"50 %%cd:~,%"`},
+		{`say "hi & bye"`, `"say \"hi ^& bye\""`},
+	}
+	for _, tc := range tests {
+		if got := QuoteCmd(tc.input); got != tc.want {
+			t.Errorf("QuoteCmd(%q) = %q, want %q", tc.input, got, tc.want)
+		}
+	}
+
+	if got, want := JoinCmd([]string{`C:\a b\x.bat`, "50%", "&"}), `"C:\a b\x.bat" 50^% ^&`; got != want {
+		t.Errorf("JoinCmd = %q, want %q", got, want)
+	}
+}
+
+// parseCmd models how cmd.exe reads a command line before running it. It
+// first expands %VAR% references, which it does inside quotes too; every
+// such reference is reported since any variable may be defined. It then
+// tracks quoting, where every '"' toggles it: outside quotes '^' escapes
+// the next character, &, |, < and > end the command and the first space ends
+// the program name; inside quotes all of them are literal. It returns the
+// line cmd.exe runs and the program name.
+func parseCmd(s string) (string, string, error) {
+	var expanded strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			expanded.WriteByte(s[i])
+			continue
+		}
+		if strings.HasPrefix(s[i:], cmdPercent) {
+			expanded.WriteByte('%')
+			i += len(cmdPercent) - 1
+			continue
+		}
+		if end := strings.IndexByte(s[i+1:], '%'); end > 0 {
+			if name := s[i+1 : i+1+end]; !strings.ContainsAny(name, "^\" \t") {
+				return "", "", fmt.Errorf("%%%s%% is expanded", name)
+			}
+		}
+		expanded.WriteByte('%')
+	}
+
+	line := expanded.String()
+	var out strings.Builder
+	program := ""
+	quoted := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case !quoted && c == '^' && i+1 < len(line):
+			i++
+			c = line[i]
+		case !quoted && strings.IndexByte("&|<>", c) >= 0:
+			return "", "", fmt.Errorf("unescaped %q outside quotes at %d", c, i)
+		case !quoted && c == ' ' && program == "":
+			program = out.String()
+		}
+		out.WriteByte(c)
+	}
+	return out.String(), program, nil
+}
+
+func TestJoinCmdSurvivesCmdParsing(t *testing.T) {
+	args := []string{
+		`C:\Program Files\Tools & More\a.cmd`,
+		`he said "hi"`, `"odd`, "x|y", "100%", "%USERPROFILE%", "with %PATH% inside",
+		`%"`, `\%x %`, "a^b", "quoted ^ caret", "a & b", "(group)", "trailing\\",
+	}
+	line, program, err := parseCmd(JoinCmd(args))
+	if err != nil {
+		t.Fatalf("cmd.exe would misread %s: %v", JoinCmd(args), err)
+	}
+	if want := `"C:\Program Files\Tools & More\a.cmd"`; program != want {
+		t.Fatalf("program = %s, want %s", program, want)
+	}
+	if got := Split(line); !reflect.DeepEqual(got, args) {
+		t.Fatalf("expected %q, got %q", args, got)
+	}
+}
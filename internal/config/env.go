@@ -0,0 +1,99 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// EnvOp is the kind of change an EnvOverride makes to the launch environment.
+type EnvOp string
+
+const (
+	EnvSet         EnvOp = "set"
+	EnvUnset       EnvOp = "unset"
+	EnvPrependPath EnvOp = "prependPath"
+)
+
+// EnvOverride changes one variable of the environment a managed app is
+// launched with. Values may reference other variables as %VAR%; overrides are
+// applied in order, so later ones see the result of earlier ones.
+type EnvOverride struct {
+	Op    EnvOp  `json:"op"`
+	Name  string `json:"name"`
+	Value string `json:"value,omitempty"`
+}
+
+var errEmptyEnvName = errors.New("environment variable name is empty")
+
+// ParseEnvOverride parses the one-line form used by the settings window:
+// "NAME=value" sets, "-NAME" unsets and "+NAME=value" prepends value to the
+// path list in NAME.
+func ParseEnvOverride(line string) (EnvOverride, error) {
+	line = strings.TrimSpace(line)
+	switch {
+	case strings.HasPrefix(line, "-"):
+		name := strings.TrimSpace(line[1:])
+		if name == "" || strings.Contains(name, "=") {
+			return EnvOverride{}, fmt.Errorf("invalid unset %q", line)
+		}
+		return EnvOverride{Op: EnvUnset, Name: name}, nil
+	case strings.HasPrefix(line, "+"):
+		o, err := parseEnvAssignment(line[1:])
+		if err != nil {
+			return EnvOverride{}, err
+		}
+		o.Op = EnvPrependPath
+		return o, nil
+	default:
+		o, err := parseEnvAssignment(line)
+		if err != nil {
+			return EnvOverride{}, err
+		}
+		o.Op = EnvSet
+		return o, nil
+	}
+}
+
+func parseEnvAssignment(s string) (EnvOverride, error) {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return EnvOverride{}, fmt.Errorf("missing '=' in %q", s)
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return EnvOverride{}, errEmptyEnvName
+	}
+	return EnvOverride{Name: name, Value: value}, nil
+}
+
+// FormatEnvOverride is the inverse of ParseEnvOverride.
+func FormatEnvOverride(o EnvOverride) string {
+	switch o.Op {
+	case EnvUnset:
+		return "-" + o.Name
+	case EnvPrependPath:
+		return "+" + o.Name + "=" + o.Value
+	default:
+		return o.Name + "=" + o.Value
+	}
+}
+
+func normalizeEnvOverrides(overrides []EnvOverride) []EnvOverride {
+	out := make([]EnvOverride, 0, len(overrides))
+	for _, o := range overrides {
+		o.Name = strings.TrimSpace(o.Name)
+		if o.Name == "" {
+			continue
+		}
+		switch o.Op {
+		case EnvSet, EnvPrependPath:
+		case EnvUnset:
+			o.Value = ""
+		default:
+			o.Op = EnvSet
+		}
+		out = append(out, o)
+	}
+	return out
+}
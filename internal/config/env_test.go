@@ -0,0 +1,34 @@
+package config
+
+import "testing"
+
+func TestParseEnvOverride(t *testing.T) {
+	tests := []struct {
+		line string
+		want EnvOverride
+	}{
+		{"JAVA_HOME=C:\\jdk", EnvOverride{Op: EnvSet, Name: "JAVA_HOME", Value: "C:\\jdk"}},
+		{"EMPTY=", EnvOverride{Op: EnvSet, Name: "EMPTY"}},
+		{"OPTS=a=b", EnvOverride{Op: EnvSet, Name: "OPTS", Value: "a=b"}},
+		{"-PROXY", EnvOverride{Op: EnvUnset, Name: "PROXY"}},
+		{"+PATH=%JAVA_HOME%\\bin", EnvOverride{Op: EnvPrependPath, Name: "PATH", Value: "%JAVA_HOME%\\bin"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseEnvOverride(tt.line)
+		if err != nil {
+			t.Fatalf("ParseEnvOverride(%q): %v", tt.line, err)
+		}
+		if got != tt.want {
+			t.Fatalf("ParseEnvOverride(%q) = %+v, want %+v", tt.line, got, tt.want)
+		}
+		if formatted := FormatEnvOverride(got); formatted != tt.line {
+			t.Fatalf("FormatEnvOverride(%+v) = %q, want %q", got, formatted, tt.line)
+		}
+	}
+
+	for _, bad := range []string{"NOVALUE", "=x", "-", "-A=B", "+PATH"} {
+		if _, err := ParseEnvOverride(bad); err == nil {
+			t.Fatalf("ParseEnvOverride(%q) expected error", bad)
+		}
+	}
+}
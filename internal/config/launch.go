@@ -0,0 +1,70 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// LaunchKind selects how ManagedAppEntry.ExePath is started.
+type LaunchKind string
+
+const (
+	// LaunchExe starts an executable or script directly.
+	LaunchExe LaunchKind = "exe"
+	// LaunchShortcut resolves a .lnk file and starts its target.
+	LaunchShortcut LaunchKind = "shortcut"
+	// LaunchURI hands a URI such as steam://rungameid/1 to its protocol handler.
+	LaunchURI LaunchKind = "uri"
+	// LaunchAppID activates a packaged (Store/UWP) app by AppUserModelID.
+	LaunchAppID LaunchKind = "aumid"
+)
+
+// AppsFolderPrefix is the shell namespace prefix under which packaged apps
+// are listed; "shell:AppsFolder\<AUMID>" is a common way to copy one.
+const AppsFolderPrefix = `shell:AppsFolder\`
+
+// InferLaunchKind guesses the kind of a launch target entered by the user.
+func InferLaunchKind(target string) LaunchKind {
+	target = strings.Trim(strings.TrimSpace(target), `"`)
+	switch {
+	case target == "":
+		return LaunchExe
+	case len(target) > len(AppsFolderPrefix) && strings.EqualFold(target[:len(AppsFolderPrefix)], AppsFolderPrefix):
+		return LaunchAppID
+	case strings.EqualFold(filepath.Ext(target), ".lnk"):
+		return LaunchShortcut
+	case hasURIScheme(target):
+		return LaunchURI
+	case strings.Contains(target, "!") && !strings.ContainsAny(target, `\/`):
+		// Package family name, '!', application ID.
+		return LaunchAppID
+	default:
+		return LaunchExe
+	}
+}
+
+// hasURIScheme reports whether s starts with "scheme:". Single-letter schemes
+// are drive letters, not URIs.
+func hasURIScheme(s string) bool {
+	scheme, _, ok := strings.Cut(s, ":")
+	if !ok || len(scheme) < 2 {
+		return false
+	}
+	for i, r := range scheme {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case i > 0 && (r >= '0' && r <= '9' || r == '+' || r == '-' || r == '.'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func validLaunchKind(kind LaunchKind) bool {
+	switch kind {
+	case LaunchExe, LaunchShortcut, LaunchURI, LaunchAppID:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,28 @@
+package config
+
+import "testing"
+
+func TestInferLaunchKind(t *testing.T) {
+	tests := []struct {
+		target string
+		want   LaunchKind
+	}{
+		{"", LaunchExe},
+		{`C:\Tools\app.exe`, LaunchExe},
+		{`"C:\Tools\start.cmd"`, LaunchExe},
+		{`\\server\share\app.exe`, LaunchExe},
+		{`C:\Users\me\Desktop\Game.lnk`, LaunchShortcut},
+		{`C:\Users\me\Desktop\Game.LNK`, LaunchShortcut},
+		{"steam://rungameid/570", LaunchURI},
+		{"ms-settings:display", LaunchURI},
+		{"https://example.com", LaunchURI},
+		{"Microsoft.WindowsCalculator_8wekyb3d8bbwe!App", LaunchAppID},
+		{`shell:AppsFolder\Microsoft.WindowsCalculator_8wekyb3d8bbwe!App`, LaunchAppID},
+		{`C:\odd!dir\app.exe`, LaunchExe},
+	}
+	for _, tt := range tests {
+		if got := InferLaunchKind(tt.target); got != tt.want {
+			t.Errorf("InferLaunchKind(%q) = %q, want %q", tt.target, got, tt.want)
+		}
+	}
+}
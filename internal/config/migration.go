@@ -3,8 +3,12 @@ package config
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+
+	"wintray/internal/cmdline"
 )
 
 func TryMigrateFromWinTray(targetPath string) error {
@@ -24,11 +28,11 @@ func TryMigrateFromWinTray(targetPath string) error {
 	if err != nil {
 		return err
 	}
-	var settings Settings
-	if err = json.Unmarshal(data, &settings); err != nil {
+	settings, err := decodeSettings(data)
+	if err != nil {
 		return errors.New("legacy settings format invalid")
 	}
-	settings = migrate(settings)
+	settings = normalize(settings)
 	if err = os.MkdirAll(filepath.Dir(targetPath), 0o755); err != nil {
 		return err
 	}
@@ -51,3 +55,144 @@ func legacyAppDir() (string, error) {
 	}
 	return filepath.Join(base, "WinTray"), nil
 }
+
+// ErrSchemaTooNew is returned for settings written by a newer WinTray. They
+// are never downgraded.
+var ErrSchemaTooNew = errors.New("settings schema is newer than this build supports")
+
+// schemaMigration upgrades the raw settings object from schema version from
+// to from+1. Migrations work on JSON so that Settings only has to describe
+// the current shape.
+type schemaMigration struct {
+	from  int
+	apply func(root map[string]json.RawMessage) error
+}
+
+// schemaMigrations is ordered by from, one step per version, and ends at
+// CurrentSchemaVersion.
+var schemaMigrations = []schemaMigration{
+	// v2 only launches entries with runOnStartup during autorun; v1 launched
+	// them all.
+	{from: 1, apply: enableRunOnStartup},
+	// v3 stores args as a list instead of a command-line string.
+	{from: 2, apply: splitArgs},
+}
+
+// decodeSettings unmarshals settings of any schema version up to
+// CurrentSchemaVersion.
+func decodeSettings(data []byte) (Settings, error) {
+	settings, _, err := decodeSettingsVersion(data)
+	return settings, err
+}
+
+// decodeSettingsVersion is decodeSettings that also returns the schema
+// version data was written with.
+func decodeSettingsVersion(data []byte) (Settings, int, error) {
+	upgraded, version, err := upgradeSchema(data)
+	if err != nil {
+		return Settings{}, version, err
+	}
+	var settings Settings
+	if err = json.Unmarshal(upgraded, &settings); err != nil {
+		return Settings{}, version, err
+	}
+	return settings, version, nil
+}
+
+// upgradeSchema runs the migrations that take data to CurrentSchemaVersion
+// and returns the result with the version data started at. Files without a
+// schemaVersion are version 1.
+func upgradeSchema(data []byte) ([]byte, int, error) {
+	var root map[string]json.RawMessage
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, 0, err
+	}
+	if root == nil {
+		root = map[string]json.RawMessage{}
+	}
+	version := 1
+	if raw, ok := root["schemaVersion"]; ok {
+		if err := json.Unmarshal(raw, &version); err != nil {
+			return nil, 0, fmt.Errorf("schemaVersion: %w", err)
+		}
+		version = max(version, 1)
+	}
+	if version > CurrentSchemaVersion {
+		return nil, version, fmt.Errorf("%w: version %d, supported up to %d", ErrSchemaTooNew, version, CurrentSchemaVersion)
+	}
+	if version == CurrentSchemaVersion {
+		return data, version, nil
+	}
+	for _, m := range schemaMigrations {
+		if m.from < version {
+			continue
+		}
+		if err := m.apply(root); err != nil {
+			return nil, version, fmt.Errorf("upgrade settings from schema %d: %w", m.from, err)
+		}
+		root["schemaVersion"] = json.RawMessage(strconv.Itoa(m.from + 1))
+	}
+	upgraded, err := json.Marshal(root)
+	return upgraded, version, err
+}
+
+// managedApps returns the managedApps objects of root, or false when there
+// are none or they have an unexpected shape, which the typed decode reports.
+func managedApps(root map[string]json.RawMessage) ([]map[string]json.RawMessage, bool) {
+	raw, ok := root["managedApps"]
+	if !ok {
+		return nil, false
+	}
+	var apps []map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &apps); err != nil || apps == nil {
+		return nil, false
+	}
+	return apps, true
+}
+
+func setManagedApps(root map[string]json.RawMessage, apps []map[string]json.RawMessage) error {
+	encoded, err := json.Marshal(apps)
+	if err != nil {
+		return err
+	}
+	root["managedApps"] = encoded
+	return nil
+}
+
+func enableRunOnStartup(root map[string]json.RawMessage) error {
+	apps, ok := managedApps(root)
+	if !ok {
+		return nil
+	}
+	for _, app := range apps {
+		if app != nil {
+			app["runOnStartup"] = json.RawMessage("true")
+		}
+	}
+	return setManagedApps(root, apps)
+}
+
+// splitArgs rewrites "args" strings as argument lists, split with the rules
+// the launched program would have used.
+func splitArgs(root map[string]json.RawMessage) error {
+	apps, ok := managedApps(root)
+	if !ok {
+		return nil
+	}
+	for _, app := range apps {
+		var args string
+		if raw, ok := app["args"]; !ok || json.Unmarshal(raw, &args) != nil {
+			continue
+		}
+		list := cmdline.Split(args)
+		if list == nil {
+			list = []string{}
+		}
+		encoded, err := json.Marshal(list)
+		if err != nil {
+			return err
+		}
+		app["args"] = encoded
+	}
+	return setManagedApps(root, apps)
+}
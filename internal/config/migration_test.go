@@ -0,0 +1,132 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files")
+
+// TestSettingsCorpusGolden loads settings files written by earlier releases
+// and compares the result with testdata/settings/*.golden.
+func TestSettingsCorpusGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "settings", "*.json"))
+	if err != nil || len(inputs) == 0 {
+		t.Fatalf("no settings corpus: %v", err)
+	}
+	for _, input := range inputs {
+		t.Run(filepath.Base(input), func(t *testing.T) {
+			data, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			settings, err := decodeSettings(data)
+			if err != nil {
+				t.Fatalf("decodeSettings: %v", err)
+			}
+			got, err := json.MarshalIndent(normalize(settings), "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+			golden := strings.TrimSuffix(input, ".json") + ".golden"
+			if *update {
+				if err = os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatalf("write golden: %v", err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("read golden: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("settings mismatch (run with -update to accept)\n got:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestSchemaMigrationsAreContiguous(t *testing.T) {
+	for i, m := range schemaMigrations {
+		if m.from != i+1 {
+			t.Fatalf("migration %d upgrades from %d, want %d", i, m.from, i+1)
+		}
+	}
+	if len(schemaMigrations)+1 != CurrentSchemaVersion {
+		t.Fatalf("%d migrations end at schema %d, want %d", len(schemaMigrations), len(schemaMigrations)+1, CurrentSchemaVersion)
+	}
+}
+
+func TestStoreSaveBacksUpOlderSchemaOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	data := []byte(`{"schemaVersion": 2, "managedApps": [{"name": "A", "args": "-v"}]}`)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store := NewStore(path)
+
+	settings, report := store.LoadWithReport()
+
+	if report.UpgradedFrom != 2 || report.UpgradeBackup != path+".v2.bak" {
+		t.Fatalf("report = %+v, want an upgrade from 2 with a backup", report)
+	}
+	if len(settings.ManagedApps[0].Args) != 1 {
+		t.Fatalf("args = %q, want the upgraded list", settings.ManagedApps[0].Args)
+	}
+	if _, err := os.Stat(report.UpgradeBackup); !os.IsNotExist(err) {
+		t.Fatalf("Load wrote the upgrade backup: %v", err)
+	}
+
+	if err := store.Save(settings); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if backup, _ := os.ReadFile(report.UpgradeBackup); !bytes.Equal(backup, data) {
+		t.Fatalf("backup = %s, want the original file", backup)
+	}
+
+	// A later file of the same older version does not replace the copy.
+	if err := os.WriteFile(path, []byte(`{"schemaVersion": 2}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Overwrite(store.Load()); err != nil {
+		t.Fatalf("Overwrite: %v", err)
+	}
+	if backup, _ := os.ReadFile(report.UpgradeBackup); !bytes.Equal(backup, data) {
+		t.Fatalf("backup = %s, want the first original kept", backup)
+	}
+}
+
+func TestStoreRefusesToDowngradeNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	data := []byte(`{"schemaVersion": 99, "managedApps": [{"name": "From the future"}]}`)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store := NewStore(path)
+
+	settings, report := store.LoadWithReport()
+
+	if !errors.Is(report.Err, ErrSchemaTooNew) {
+		t.Fatalf("Err = %v, want ErrSchemaTooNew", report.Err)
+	}
+	if len(settings.ManagedApps) != 0 {
+		t.Fatalf("settings = %+v, want defaults", settings)
+	}
+	for _, save := range []func(Settings) error{store.Save, store.Overwrite} {
+		if err := save(settings); !errors.Is(err, ErrSchemaTooNew) {
+			t.Fatalf("save over a newer schema = %v, want ErrSchemaTooNew", err)
+		}
+	}
+	if got, _ := os.ReadFile(path); !bytes.Equal(got, data) {
+		t.Fatalf("newer settings file was replaced: %s", got)
+	}
+	if matches, _ := filepath.Glob(path + ".invalid-*"); len(matches) != 0 {
+		t.Fatalf("newer settings file was treated as invalid: %v", matches)
+	}
+}
@@ -13,19 +13,132 @@ type WindowMatchRule struct {
 	Strategy MatchStrategy `json:"strategy"`
 }
 
+// StopPolicy controls what "stop managed apps" does to an entry WinTray launched.
+type StopPolicy string
+
+const (
+	StopNever         StopPolicy = "never"
+	StopClose         StopPolicy = "close"
+	StopCloseThenKill StopPolicy = "closeThenKill"
+)
+
 type TrayBehavior struct {
 	AutoMinimizeAndHideOnLaunch bool `json:"autoMinimizeAndHideOnLaunch"`
+	// ProxyTrayIcon adds a WinTray-owned tray icon for windows hidden with
+	// SW_HIDE, for apps that have no tray icon of their own.
+	ProxyTrayIcon bool `json:"proxyTrayIcon"`
+}
+
+// SupervisePolicy restarts a background launch that exits with a non-zero code.
+// At most MaxRestarts restarts are allowed within WindowSeconds; beyond that the
+// entry is considered crash-looping and is left stopped.
+type SupervisePolicy struct {
+	Enabled       bool `json:"enabled"`
+	MaxRestarts   int  `json:"maxRestarts"`
+	WindowSeconds int  `json:"windowSeconds"`
 }
 
 type ManagedAppEntry struct {
-	ID                       string          `json:"id"`
-	Name                     string          `json:"name"`
-	ExePath                  string          `json:"exePath"`
-	Args                     string          `json:"args"`
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// ExePath is the launch target: a file path, URI or AppUserModelID
+	// depending on LaunchKind.
+	ExePath    string     `json:"exePath"`
+	LaunchKind LaunchKind `json:"launchKind"`
+	// ExpectedProcess overrides the exe path or process name used to find the
+	// app's windows. URI and AppUserModelID targets need it to be managed.
+	ExpectedProcess          string          `json:"expectedProcess"`
+	Args                     []string        `json:"args"`
 	RunOnStartup             bool            `json:"runOnStartup"`
 	LaunchHiddenInBackground bool            `json:"launchHiddenInBackground"`
 	WindowMatch              WindowMatchRule `json:"windowMatch"`
 	TrayBehavior             TrayBehavior    `json:"trayBehavior"`
+	Supervise                SupervisePolicy `json:"supervise"`
+	StopPolicy               StopPolicy      `json:"stopPolicy"`
+	CaptureOutput            bool            `json:"captureOutput"`
+	// WorkingDir overrides the launch directory; it defaults to the exe's
+	// directory. %VAR% references are expanded.
+	WorkingDir string        `json:"workingDir"`
+	Env        []EnvOverride `json:"env"`
+	// MuteNotifications leaves the entry out of autorun notifications.
+	MuteNotifications bool `json:"muteNotifications"`
+}
+
+// StartupBackend selects how run-at-logon is registered with Windows.
+type StartupBackend string
+
+const (
+	// StartupRunKey writes a value under the HKCU Run key.
+	StartupRunKey StartupBackend = "runKey"
+	// StartupTaskScheduler registers a scheduled task with a logon trigger.
+	StartupTaskScheduler StartupBackend = "taskScheduler"
+	// StartupFolder places a shortcut in the user's Startup folder.
+	StartupFolder StartupBackend = "startupFolder"
+)
+
+// AutorunNotify selects the tray notification shown after an autorun.
+type AutorunNotify string
+
+const (
+	NotifyOff AutorunNotify = "off"
+	// NotifyFailures lists only the entries that were not managed.
+	NotifyFailures AutorunNotify = "failures"
+	// NotifySummary lists every entry.
+	NotifySummary AutorunNotify = "summary"
+)
+
+// LogLevel is the minimum severity written to wintray.log.
+type LogLevel string
+
+const (
+	LogDebug LogLevel = "debug"
+	LogInfo  LogLevel = "info"
+	LogWarn  LogLevel = "warn"
+	LogError LogLevel = "error"
+)
+
+// LogFormat selects how wintray.log records are encoded.
+type LogFormat string
+
+const (
+	// LogText writes key=value lines.
+	LogText LogFormat = "text"
+	// LogJSON writes one JSON object per line.
+	LogJSON LogFormat = "json"
+)
+
+// LogPrivacy selects how window titles are written to wintray.log. Unless
+// it is LogPrivacyOff, the user profile directory is written as
+// %USERPROFILE%.
+type LogPrivacy string
+
+const (
+	LogPrivacyOff LogPrivacy = "off"
+	// LogPrivacyTruncate keeps the first few characters of a title.
+	LogPrivacyTruncate LogPrivacy = "truncate"
+	// LogPrivacyHash replaces a title with a short hash.
+	LogPrivacyHash LogPrivacy = "hash"
+)
+
+// LogRetention controls rotation of wintray.log. Zero fields use the
+// defaults.
+type LogRetention struct {
+	// MaxSizeMB rotates the log once it reaches this size.
+	MaxSizeMB int `json:"maxSizeMB"`
+	// MaxAgeDays rotates the log once it has been written to for this long.
+	MaxAgeDays int `json:"maxAgeDays"`
+	// Generations is the number of gzipped rotated logs kept.
+	Generations int `json:"generations"`
+	// TotalSizeMB caps the combined size of the rotated logs.
+	TotalSizeMB int `json:"totalSizeMB"`
+}
+
+// LogonTask configures the StartupTaskScheduler backend.
+type LogonTask struct {
+	DelaySeconds      int  `json:"delaySeconds"`
+	HighestPrivileges bool `json:"highestPrivileges"`
+	// DisallowOnBattery keeps the task from starting on battery power.
+	DisallowOnBattery bool `json:"disallowOnBattery"`
 }
 
 type Settings struct {
@@ -35,6 +148,17 @@ type Settings struct {
 	StartMinimizedToTray          bool              `json:"startMinimizedToTray"`
 	ExitAfterManagedAppsCompleted bool              `json:"exitAfterManagedAppsCompleted"`
 	CloseWindowRetrySeconds       int               `json:"closeWindowRetrySeconds"`
+	StopManagedAppsOnExit         bool              `json:"stopManagedAppsOnExit"`
+	StopGraceSeconds              int               `json:"stopGraceSeconds"`
+	StartupBackend                StartupBackend    `json:"startupBackend"`
+	LogonTask                     LogonTask         `json:"logonTask"`
+	RunHistoryLimit               int               `json:"runHistoryLimit"`
+	AutorunNotify                 AutorunNotify     `json:"autorunNotify"`
+	LogLevel                      LogLevel          `json:"logLevel"`
+	LogFormat                     LogFormat         `json:"logFormat"`
+	LogRetention                  LogRetention      `json:"logRetention"`
+	LogPrivacy                    LogPrivacy        `json:"logPrivacy"`
+	StrictValidation              bool              `json:"strictValidation"`
 	ManagedApps                   []ManagedAppEntry `json:"managedApps"`
 }
 
@@ -42,14 +166,69 @@ func ShouldLaunchViaWinTray(entry ManagedAppEntry) bool {
 	return entry.LaunchHiddenInBackground || entry.TrayBehavior.AutoMinimizeAndHideOnLaunch || entry.RunOnStartup
 }
 
+// CurrentSchemaVersion is the settings schema this build writes. Version 3
+// stores ManagedAppEntry.Args as a list instead of a command-line string.
+// Raising it requires a new entry in schemaMigrations.
+const CurrentSchemaVersion = 3
+
+const (
+	DefaultSuperviseMaxRestarts   = 5
+	DefaultSuperviseWindowSeconds = 300
+	DefaultStopGraceSeconds       = 10
+	MaxLogonDelaySeconds          = 600
+	DefaultRunHistoryLimit        = 50
+	MaxRunHistoryLimit            = 1000
+	DefaultLogMaxSizeMB           = 5
+	DefaultLogMaxAgeDays          = 7
+	DefaultLogGenerations         = 5
+	DefaultLogTotalSizeMB         = 50
+)
+
 func DefaultSettings() Settings {
 	return Settings{
-		SchemaVersion:                 2,
+		SchemaVersion:                 CurrentSchemaVersion,
 		Language:                      "zh-CN",
 		RunAtLogon:                    true,
 		StartMinimizedToTray:          false,
 		ExitAfterManagedAppsCompleted: false,
 		CloseWindowRetrySeconds:       10,
-		ManagedApps:                   make([]ManagedAppEntry, 0),
+		StopManagedAppsOnExit:         false,
+		StopGraceSeconds:              DefaultStopGraceSeconds,
+		StartupBackend:                StartupRunKey,
+		RunHistoryLimit:               DefaultRunHistoryLimit,
+		AutorunNotify:                 NotifyOff,
+		LogLevel:                      LogInfo,
+		LogFormat:                     LogText,
+		LogRetention: LogRetention{
+			MaxSizeMB:   DefaultLogMaxSizeMB,
+			MaxAgeDays:  DefaultLogMaxAgeDays,
+			Generations: DefaultLogGenerations,
+			TotalSizeMB: DefaultLogTotalSizeMB,
+		},
+		LogPrivacy:  LogPrivacyHash,
+		ManagedApps: make([]ManagedAppEntry, 0),
+	}
+}
+
+// NewManagedAppEntry returns an entry for target with the defaults used when
+// the user adds a program.
+func NewManagedAppEntry(id, name, target string) ManagedAppEntry {
+	return ManagedAppEntry{
+		ID:           id,
+		Name:         name,
+		ExePath:      target,
+		LaunchKind:   InferLaunchKind(target),
+		Args:         []string{},
+		RunOnStartup: true,
+		WindowMatch: WindowMatchRule{
+			Strategy: MatchProcessNameThenTitle,
+		},
+		LaunchHiddenInBackground: false,
+		TrayBehavior:             TrayBehavior{AutoMinimizeAndHideOnLaunch: true},
+		Supervise: SupervisePolicy{
+			MaxRestarts:   DefaultSuperviseMaxRestarts,
+			WindowSeconds: DefaultSuperviseWindowSeconds,
+		},
+		StopPolicy: StopClose,
 	}
 }
@@ -1,35 +1,121 @@
 package config
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 )
 
+// DefaultSettingsBackups is how many earlier good versions of the settings
+// file Save keeps.
+const DefaultSettingsBackups = 5
+
 type Store struct {
-	path string
+	path    string
+	backups int
+
+	mu sync.Mutex
+	// digest is the hash of the file content the Store last read or wrote;
+	// known is false until it has done either.
+	digest [sha256.Size]byte
+	known  bool
+	// polled and offered are the Watch state: the content seen by the last
+	// poll and the last external change passed to onChange.
+	polled  [sha256.Size]byte
+	offered [sha256.Size]byte
+	// newer is set when the file has a newer schema; Save refuses to
+	// replace it.
+	newer error
 }
 
+// ErrSettingsChanged is returned by Save when another program changed the
+// settings file after the Store last read or wrote it.
+var ErrSettingsChanged = errors.New("settings file was changed outside WinTray")
+
 func NewStore(path string) *Store {
-	return &Store{path: path}
+	return &Store{path: path, backups: DefaultSettingsBackups}
+}
+
+// LoadReport tells how Load obtained the settings and what was wrong with
+// them.
+type LoadReport struct {
+	// Err is why the settings file was rejected; nil when it loaded or did
+	// not exist.
+	Err error
+	// Backup is the backup the settings were restored from; empty when the
+	// defaults were used.
+	Backup string
+	// Issues are the problems Validate found in the file that was used,
+	// before loading corrected them.
+	Issues Issues
+	// UpgradedFrom is the older schema version the file was upgraded from;
+	// 0 when it was current.
+	UpgradedFrom int
+	// UpgradeBackup is where Save keeps the original file before the
+	// upgraded settings first replace it; empty when there was no upgrade.
+	UpgradeBackup string
 }
 
 func (s *Store) Load() Settings {
-	if _, err := os.Stat(s.path); err != nil {
-		return DefaultSettings()
-	}
+	settings, _ := s.LoadWithReport()
+	return settings
+}
+
+// LoadWithReport loads the settings file. A file that cannot be read or
+// decoded is kept aside and the newest valid backup is used instead, or the
+// defaults when there is none. A file from an older schema is upgraded in
+// memory only; one from a newer schema is left alone and Save refuses to
+// replace it.
+func (s *Store) LoadWithReport() (Settings, LoadReport) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	data, err := os.ReadFile(s.path)
-	if err != nil {
-		return DefaultSettings()
+	if err == nil || errors.Is(err, os.ErrNotExist) {
+		s.remember(data)
 	}
-	var settings Settings
-	if err = json.Unmarshal(data, &settings); err != nil {
-		_ = backupInvalidSettingsFile(s.path, data)
-		return DefaultSettings()
+	if errors.Is(err, os.ErrNotExist) {
+		return DefaultSettings(), LoadReport{}
 	}
-	return migrate(settings)
+	if err == nil {
+		var (
+			settings Settings
+			version  int
+		)
+		if settings, version, err = decodeSettingsVersion(data); err == nil {
+			report := LoadReport{Issues: Validate(settings, LaunchTargetExists)}
+			if version < CurrentSchemaVersion {
+				report.UpgradedFrom = version
+				report.UpgradeBackup = s.upgradeBackupPath(version)
+			}
+			return normalize(settings), report
+		}
+		if errors.Is(err, ErrSchemaTooNew) {
+			s.newer = err
+		} else {
+			_ = backupInvalidSettingsFile(s.path, data)
+		}
+	}
+
+	report := LoadReport{Err: err}
+	for n := 1; n <= s.backups; n++ {
+		backup := s.backupPath(n)
+		data, readErr := os.ReadFile(backup)
+		if readErr != nil {
+			continue
+		}
+		if settings, decodeErr := decodeSettings(data); decodeErr == nil {
+			report.Backup = backup
+			report.Issues = Validate(settings, LaunchTargetExists)
+			return normalize(settings), report
+		}
+	}
+	return DefaultSettings(), report
 }
 
 func backupInvalidSettingsFile(path string, data []byte) error {
@@ -41,34 +127,192 @@ func backupInvalidSettingsFile(path string, data []byte) error {
 	return os.WriteFile(backupPath, data, 0o644)
 }
 
+// backupPath returns the path of backup n, counting from 1 for the newest.
+func (s *Store) backupPath(n int) string {
+	return fmt.Sprintf("%s.%d.bak", s.path, n)
+}
+
+// upgradeBackupPath returns the path of the copy of a version file kept
+// before it is upgraded.
+func (s *Store) upgradeBackupPath(version int) string {
+	return fmt.Sprintf("%s.v%d.bak", s.path, version)
+}
+
+// keepUpgradeBackup copies previous to settings.json.vN.bak when it is from
+// an older schema, so the file as the older version wrote it survives the
+// upgrade. An existing copy is never replaced.
+func (s *Store) keepUpgradeBackup(previous []byte) error {
+	_, version, err := upgradeSchema(previous)
+	if err != nil || version >= CurrentSchemaVersion {
+		return nil
+	}
+	f, err := os.OpenFile(s.upgradeBackupPath(version), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, os.ErrExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(previous)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+	}
+	return err
+}
+
+// remember records data as the content the Store last read or wrote.
+func (s *Store) remember(data []byte) {
+	s.digest = sha256.Sum256(data)
+	s.known = true
+}
+
+// Save replaces the settings file atomically: the new content is written
+// and flushed to a temporary file that is then renamed over the old one.
+// The previous file is kept as a backup when it was valid and differs, and
+// in settings.json.vN.bak when it was from an older schema. Save returns
+// ErrSettingsChanged instead of replacing changes another program made
+// since the Store last read or wrote the file.
 func (s *Store) Save(settings Settings) error {
-	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+	return s.save(settings, false)
+}
+
+// Overwrite is Save without the check for changes made by other programs. It
+// still refuses to replace a newer schema.
+func (s *Store) Overwrite(settings Settings) error {
+	return s.save(settings, true)
+}
+
+func (s *Store) save(settings Settings, force bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.newer != nil {
+		return s.newer
+	}
+	previous, readErr := os.ReadFile(s.path)
+	if readErr == nil && !force && s.known && sha256.Sum256(previous) != s.digest {
+		return ErrSettingsChanged
+	}
+
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
 		return err
 	}
 	data, err := json.MarshalIndent(settings, "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(s.path, data, 0o644)
-}
 
-func migrate(settings Settings) Settings {
-	if settings.SchemaVersion <= 0 {
-		settings.SchemaVersion = 1
+	tmp, err := os.CreateTemp(dir, filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	if len(previous) > 0 {
+		if err = s.keepUpgradeBackup(previous); err != nil {
+			_ = os.Remove(tmp.Name())
+			return fmt.Errorf("keep settings from before the upgrade: %w", err)
+		}
+	}
+	if err = os.Rename(tmp.Name(), s.path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
 	}
-	// v2 enforces RunOnStartup during autorun; preserve legacy startup behavior.
-	if settings.SchemaVersion < 2 {
-		for i := range settings.ManagedApps {
-			settings.ManagedApps[i].RunOnStartup = true
+	s.remember(data)
+	// Backups only move once the file was replaced, so a failed Save leaves
+	// them as they were.
+	if len(previous) > 0 && !bytes.Equal(previous, data) {
+		if _, decodeErr := decodeSettings(previous); decodeErr == nil {
+			s.rotateBackups(previous)
 		}
-		settings.SchemaVersion = 2
 	}
+	return nil
+}
+
+// rotateBackups shifts the backups down by one, dropping the oldest, and
+// stores previous as the newest. Backups are best effort and never fail a
+// Save.
+func (s *Store) rotateBackups(previous []byte) {
+	if s.backups <= 0 {
+		return
+	}
+	_ = os.Remove(s.backupPath(s.backups))
+	for n := s.backups - 1; n >= 1; n-- {
+		_ = os.Rename(s.backupPath(n), s.backupPath(n+1))
+	}
+	_ = os.WriteFile(s.backupPath(1), previous, 0o644)
+}
+
+// normalize replaces unset and out-of-range values of decoded settings with
+// defaults and resolves conflicting options. Schema changes belong in
+// schemaMigrations instead.
+func normalize(settings Settings) Settings {
 	if settings.CloseWindowRetrySeconds < 0 {
 		settings.CloseWindowRetrySeconds = 0
 	}
 	if settings.CloseWindowRetrySeconds > 120 {
 		settings.CloseWindowRetrySeconds = 120
 	}
+	if settings.StopGraceSeconds <= 0 {
+		settings.StopGraceSeconds = DefaultStopGraceSeconds
+	}
+	if settings.StopGraceSeconds > 120 {
+		settings.StopGraceSeconds = 120
+	}
+	switch settings.StartupBackend {
+	case StartupRunKey, StartupTaskScheduler, StartupFolder:
+	default:
+		settings.StartupBackend = StartupRunKey
+	}
+	switch settings.AutorunNotify {
+	case NotifyOff, NotifyFailures, NotifySummary:
+	default:
+		settings.AutorunNotify = NotifyOff
+	}
+	switch settings.LogLevel {
+	case LogDebug, LogInfo, LogWarn, LogError:
+	default:
+		settings.LogLevel = LogInfo
+	}
+	switch settings.LogFormat {
+	case LogText, LogJSON:
+	default:
+		settings.LogFormat = LogText
+	}
+	switch settings.LogPrivacy {
+	case LogPrivacyOff, LogPrivacyTruncate, LogPrivacyHash:
+	default:
+		settings.LogPrivacy = LogPrivacyHash
+	}
+	if settings.LogonTask.DelaySeconds < 0 {
+		settings.LogonTask.DelaySeconds = 0
+	}
+	if settings.LogonTask.DelaySeconds > MaxLogonDelaySeconds {
+		settings.LogonTask.DelaySeconds = MaxLogonDelaySeconds
+	}
+	settings.LogRetention.MaxSizeMB = clampOrDefault(settings.LogRetention.MaxSizeMB, DefaultLogMaxSizeMB, 100)
+	settings.LogRetention.MaxAgeDays = clampOrDefault(settings.LogRetention.MaxAgeDays, DefaultLogMaxAgeDays, 365)
+	settings.LogRetention.Generations = clampOrDefault(settings.LogRetention.Generations, DefaultLogGenerations, 50)
+	settings.LogRetention.TotalSizeMB = clampOrDefault(settings.LogRetention.TotalSizeMB, DefaultLogTotalSizeMB, 1024)
+	if settings.RunHistoryLimit <= 0 {
+		settings.RunHistoryLimit = DefaultRunHistoryLimit
+	}
+	if settings.RunHistoryLimit > MaxRunHistoryLimit {
+		settings.RunHistoryLimit = MaxRunHistoryLimit
+	}
 	if settings.Language != "zh-CN" && settings.Language != "en-US" {
 		settings.Language = "zh-CN"
 	}
@@ -85,6 +329,30 @@ func migrate(settings Settings) Settings {
 		if settings.ManagedApps[i].LaunchHiddenInBackground {
 			settings.ManagedApps[i].TrayBehavior.AutoMinimizeAndHideOnLaunch = false
 		}
+		switch settings.ManagedApps[i].StopPolicy {
+		case StopNever, StopClose, StopCloseThenKill:
+		default:
+			settings.ManagedApps[i].StopPolicy = StopClose
+		}
+		if settings.ManagedApps[i].Supervise.MaxRestarts <= 0 {
+			settings.ManagedApps[i].Supervise.MaxRestarts = DefaultSuperviseMaxRestarts
+		}
+		if settings.ManagedApps[i].Supervise.WindowSeconds <= 0 {
+			settings.ManagedApps[i].Supervise.WindowSeconds = DefaultSuperviseWindowSeconds
+		}
+		settings.ManagedApps[i].Env = normalizeEnvOverrides(settings.ManagedApps[i].Env)
+		if !validLaunchKind(settings.ManagedApps[i].LaunchKind) {
+			settings.ManagedApps[i].LaunchKind = InferLaunchKind(settings.ManagedApps[i].ExePath)
+		}
 	}
 	return settings
 }
+
+// clampOrDefault returns def for unset (non-positive) values and caps the
+// rest at limit.
+func clampOrDefault(value, def, limit int) int {
+	if value <= 0 {
+		return def
+	}
+	return min(value, limit)
+}
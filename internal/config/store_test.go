@@ -1,21 +1,25 @@
 package config
 
-import "testing"
-
-func TestMigrate_LegacySchemaEnablesRunOnStartup(t *testing.T) {
-	input := Settings{
-		SchemaVersion: 1,
-		Language:      "en-US",
-		ManagedApps: []ManagedAppEntry{
-			{Name: "App A", RunOnStartup: false},
-			{Name: "App B"},
-		},
-	}
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeSettings_LegacySchemaEnablesRunOnStartup(t *testing.T) {
+	data := []byte(`{"schemaVersion": 1, "language": "en-US", "managedApps": [
+  {"name": "App A", "runOnStartup": false},
+  {"name": "App B"}
+]}`)
 
-	got := migrate(input)
+	got, err := decodeSettings(data)
+	if err != nil {
+		t.Fatalf("decodeSettings: %v", err)
+	}
 
-	if got.SchemaVersion != 2 {
-		t.Fatalf("migrate schema version = %d, want 2", got.SchemaVersion)
+	if got.SchemaVersion != CurrentSchemaVersion {
+		t.Fatalf("schema version = %d, want %d", got.SchemaVersion, CurrentSchemaVersion)
 	}
 	for i, app := range got.ManagedApps {
 		if !app.RunOnStartup {
@@ -24,20 +28,19 @@ func TestMigrate_LegacySchemaEnablesRunOnStartup(t *testing.T) {
 	}
 }
 
-func TestMigrate_SchemaV2PreservesRunOnStartupFalse(t *testing.T) {
-	input := Settings{
-		SchemaVersion: 2,
-		Language:      "en-US",
-		ManagedApps: []ManagedAppEntry{
-			{Name: "App A", RunOnStartup: false},
-			{Name: "App B", RunOnStartup: true},
-		},
-	}
+func TestDecodeSettings_SchemaV2PreservesRunOnStartupFalse(t *testing.T) {
+	data := []byte(`{"schemaVersion": 2, "language": "en-US", "managedApps": [
+  {"name": "App A", "runOnStartup": false},
+  {"name": "App B", "runOnStartup": true}
+]}`)
 
-	got := migrate(input)
+	got, err := decodeSettings(data)
+	if err != nil {
+		t.Fatalf("decodeSettings: %v", err)
+	}
 
-	if got.SchemaVersion != 2 {
-		t.Fatalf("migrate schema version = %d, want 2", got.SchemaVersion)
+	if got.SchemaVersion != CurrentSchemaVersion {
+		t.Fatalf("schema version = %d, want %d", got.SchemaVersion, CurrentSchemaVersion)
 	}
 	if got.ManagedApps[0].RunOnStartup {
 		t.Fatalf("managed app 0 RunOnStartup = true, want false")
@@ -47,7 +50,7 @@ func TestMigrate_SchemaV2PreservesRunOnStartupFalse(t *testing.T) {
 	}
 }
 
-func TestMigrate_NormalizesLanguageAndRetryBounds(t *testing.T) {
+func TestNormalize_LanguageAndRetryBounds(t *testing.T) {
 	tests := []struct {
 		name       string
 		retry      int
@@ -85,7 +88,7 @@ func TestMigrate_NormalizesLanguageAndRetryBounds(t *testing.T) {
 				},
 			}
 
-			got := migrate(input)
+			got := normalize(input)
 
 			if got.Language != tc.wantLang {
 				t.Fatalf("language = %q, want %q", got.Language, tc.wantLang)
@@ -96,3 +99,110 @@ func TestMigrate_NormalizesLanguageAndRetryBounds(t *testing.T) {
 		})
 	}
 }
+
+func TestDecodeSettings_UpgradesArgsStringToList(t *testing.T) {
+	data := []byte(`{
+  "schemaVersion": 2,
+  "language": "en-US",
+  "managedApps": [
+    {"name": "A", "args": "--config \"C:\\My Path\\cfg.json\" -v"},
+    {"name": "B", "args": ""},
+    {"name": "C", "args": ["already", "a list"]},
+    {"name": "D"}
+  ]
+}`)
+
+	settings, err := decodeSettings(data)
+	if err != nil {
+		t.Fatalf("decodeSettings: %v", err)
+	}
+	got := normalize(settings)
+	if got.SchemaVersion != CurrentSchemaVersion {
+		t.Fatalf("schema version = %d, want %d", got.SchemaVersion, CurrentSchemaVersion)
+	}
+	want := [][]string{
+		{"--config", `C:\My Path\cfg.json`, "-v"},
+		{},
+		{"already", "a list"},
+		nil,
+	}
+	for i, app := range got.ManagedApps {
+		if !reflect.DeepEqual(app.Args, want[i]) {
+			t.Fatalf("app %s args = %q, want %q", app.Name, app.Args, want[i])
+		}
+	}
+}
+
+func TestStoreSaveKeepsBackupGenerations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	store := NewStore(path)
+	store.backups = 2
+
+	for _, retry := range []int{1, 2, 2, 3, 4} {
+		settings := DefaultSettings()
+		settings.CloseWindowRetrySeconds = retry
+		if err := store.Save(settings); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+
+	for n, want := range map[int]int{1: 3, 2: 2} {
+		data, err := os.ReadFile(store.backupPath(n))
+		if err != nil {
+			t.Fatalf("backup %d: %v", n, err)
+		}
+		got, err := decodeSettings(data)
+		if err != nil || got.CloseWindowRetrySeconds != want {
+			t.Errorf("backup %d retry = %d (err %v), want %d", n, got.CloseWindowRetrySeconds, err, want)
+		}
+	}
+	if _, err := os.Stat(store.backupPath(3)); !os.IsNotExist(err) {
+		t.Errorf("backup 3 exists beyond the limit: %v", err)
+	}
+	if leftovers, _ := filepath.Glob(path + ".*.tmp"); len(leftovers) != 0 {
+		t.Errorf("temporary files left behind: %v", leftovers)
+	}
+}
+
+func TestStoreLoadFallsBackToNewestValidBackup(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "settings.json")
+	store := NewStore(path)
+	for _, retry := range []int{7, 8} {
+		settings := DefaultSettings()
+		settings.CloseWindowRetrySeconds = retry
+		if err := store.Save(settings); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+	// A save cut short leaves a truncated file behind.
+	if err := os.WriteFile(path, []byte(`{"language": "en-`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, report := store.LoadWithReport()
+	if report.Err == nil || report.Backup != store.backupPath(1) {
+		t.Fatalf("report = %+v, want a decode error and backup 1", report)
+	}
+	if got.CloseWindowRetrySeconds != 7 {
+		t.Fatalf("retry = %d, want 7 from the backup", got.CloseWindowRetrySeconds)
+	}
+	if kept, _ := filepath.Glob(path + ".invalid-*.bak"); len(kept) != 1 {
+		t.Fatalf("invalid file copies = %v, want 1", kept)
+	}
+
+	if err := os.Remove(store.backupPath(1)); err != nil {
+		t.Fatal(err)
+	}
+	got, report = store.LoadWithReport()
+	if report.Err == nil || report.Backup != "" || !reflect.DeepEqual(got, DefaultSettings()) {
+		t.Fatalf("report = %+v, want defaults when no backup is valid", report)
+	}
+}
+
+func TestStoreLoadMissingFileIsNotReported(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "settings.json"))
+	if _, report := store.LoadWithReport(); report.Err != nil || report.Backup != "" || len(report.Issues) != 0 {
+		t.Fatalf("report = %+v, want none for a first start", report)
+	}
+}
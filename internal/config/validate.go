@@ -0,0 +1,241 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Severity tells whether an Issue stops entries from working (error) or is
+// corrected or ignored when the settings are loaded (warning).
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// IssueCode identifies a kind of settings problem.
+type IssueCode string
+
+const (
+	// IssueDuplicateID: two entries share an ID, so history, hidden windows
+	// and output folders mix them up.
+	IssueDuplicateID IssueCode = "duplicateId"
+	// IssueEmptyTarget: the entry has nothing to launch.
+	IssueEmptyTarget IssueCode = "emptyTarget"
+	// IssueTargetNotFound: the exe or shortcut does not exist.
+	IssueTargetNotFound IssueCode = "targetNotFound"
+	// IssueUnknownStrategy: no window ever matches the window match strategy.
+	IssueUnknownStrategy IssueCode = "unknownStrategy"
+	// IssueUnknownValue: an enumerated field has a value this version does
+	// not know; the default is used instead.
+	IssueUnknownValue IssueCode = "unknownValue"
+	// IssueOutOfRange: a number is clamped to its allowed range.
+	IssueOutOfRange IssueCode = "outOfRange"
+	// IssueEmptyName: the entry has no display name.
+	IssueEmptyName IssueCode = "emptyName"
+	// IssueHiddenAutoHide: a hidden launch has no window to hide, so
+	// auto-hide is turned off.
+	IssueHiddenAutoHide IssueCode = "hiddenAutoHide"
+	// IssueNeedsHiddenLaunch: output capture and supervision only apply to
+	// hidden launches.
+	IssueNeedsHiddenLaunch IssueCode = "needsHiddenLaunch"
+	// IssueProxyWithoutAutoHide: a proxy icon is only added for windows
+	// WinTray hides.
+	IssueProxyWithoutAutoHide IssueCode = "proxyWithoutAutoHide"
+	// IssueNoExpectedProcess: URI and AppUserModelID targets need an
+	// expected process for their windows to be found.
+	IssueNoExpectedProcess IssueCode = "noExpectedProcess"
+)
+
+// IssueCodes lists every IssueCode.
+func IssueCodes() []IssueCode {
+	return []IssueCode{
+		IssueDuplicateID, IssueEmptyTarget, IssueTargetNotFound, IssueUnknownStrategy,
+		IssueUnknownValue, IssueOutOfRange, IssueEmptyName, IssueHiddenAutoHide,
+		IssueNeedsHiddenLaunch, IssueProxyWithoutAutoHide, IssueNoExpectedProcess,
+	}
+}
+
+// Issue is one problem found by Validate.
+type Issue struct {
+	Severity Severity  `json:"severity"`
+	Code     IssueCode `json:"code"`
+	// Path is the JSON path of the field, such as
+	// "managedApps[2].windowMatch.strategy".
+	Path string `json:"path"`
+	// Value is the offending value, if any.
+	Value string `json:"value,omitempty"`
+}
+
+func (i Issue) String() string {
+	s := fmt.Sprintf("%s %s: %s", i.Severity, i.Path, i.Code)
+	if i.Value != "" {
+		s += " " + strconv.Quote(i.Value)
+	}
+	return s
+}
+
+// Issues is the result of Validate.
+type Issues []Issue
+
+// HasErrors reports whether any issue has SeverityError.
+func (is Issues) HasErrors() bool {
+	for _, i := range is {
+		if i.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// LaunchTargetExists reports whether an exe or shortcut path can be started:
+// absolute paths must exist and bare names must be found on PATH.
+// %VAR% references are not expanded and are assumed to resolve.
+func LaunchTargetExists(path string) bool {
+	if strings.Contains(path, "%") {
+		return true
+	}
+	if !filepath.IsAbs(path) {
+		if strings.ContainsAny(path, `\/`) {
+			return true
+		}
+		_, err := exec.LookPath(path)
+		return err == nil
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Validate reports the problems in settings as read from the settings file,
+// including the ones that loading silently corrects. exists checks exe and
+// shortcut targets; nil skips that check.
+func Validate(settings Settings, exists func(path string) bool) Issues {
+	v := &validator{}
+
+	if settings.Language != "" && settings.Language != "zh-CN" && settings.Language != "en-US" {
+		v.warn(IssueUnknownValue, "language", settings.Language)
+	}
+	checkEnum(v, "startupBackend", settings.StartupBackend, StartupRunKey, StartupTaskScheduler, StartupFolder)
+	checkEnum(v, "autorunNotify", settings.AutorunNotify, NotifyOff, NotifyFailures, NotifySummary)
+	checkEnum(v, "logLevel", settings.LogLevel, LogDebug, LogInfo, LogWarn, LogError)
+	checkEnum(v, "logFormat", settings.LogFormat, LogText, LogJSON)
+	checkEnum(v, "logPrivacy", settings.LogPrivacy, LogPrivacyOff, LogPrivacyTruncate, LogPrivacyHash)
+	v.rangeCheck("closeWindowRetrySeconds", settings.CloseWindowRetrySeconds, 0, 120)
+	v.rangeCheck("stopGraceSeconds", settings.StopGraceSeconds, 0, 120)
+	v.rangeCheck("logonTask.delaySeconds", settings.LogonTask.DelaySeconds, 0, MaxLogonDelaySeconds)
+	v.rangeCheck("runHistoryLimit", settings.RunHistoryLimit, 0, MaxRunHistoryLimit)
+	v.rangeCheck("logRetention.maxSizeMB", settings.LogRetention.MaxSizeMB, 0, 100)
+	v.rangeCheck("logRetention.maxAgeDays", settings.LogRetention.MaxAgeDays, 0, 365)
+	v.rangeCheck("logRetention.generations", settings.LogRetention.Generations, 0, 50)
+	v.rangeCheck("logRetention.totalSizeMB", settings.LogRetention.TotalSizeMB, 0, 1024)
+
+	seen := map[string]bool{}
+	for i, entry := range settings.ManagedApps {
+		p := fmt.Sprintf("managedApps[%d].", i)
+		if entry.ID != "" {
+			if seen[entry.ID] {
+				v.fail(IssueDuplicateID, p+"id", entry.ID)
+			}
+			seen[entry.ID] = true
+		}
+		if strings.TrimSpace(entry.Name) == "" {
+			v.warn(IssueEmptyName, p+"name", "")
+		}
+
+		target := strings.Trim(strings.TrimSpace(entry.ExePath), `"`)
+		kind := entry.LaunchKind
+		if kind != "" && !validLaunchKind(kind) {
+			v.warn(IssueUnknownValue, p+"launchKind", string(kind))
+			kind = ""
+		}
+		if kind == "" {
+			kind = InferLaunchKind(target)
+		}
+		switch {
+		case target == "":
+			v.fail(IssueEmptyTarget, p+"exePath", "")
+		case (kind == LaunchExe || kind == LaunchShortcut) && exists != nil && !exists(target):
+			v.fail(IssueTargetNotFound, p+"exePath", target)
+		}
+
+		switch entry.WindowMatch.Strategy {
+		case "", MatchProcessNameThenTitle, MatchTitleContains, MatchClassName, MatchAny:
+		default:
+			v.fail(IssueUnknownStrategy, p+"windowMatch.strategy", string(entry.WindowMatch.Strategy))
+		}
+		checkEnum(v, p+"stopPolicy", entry.StopPolicy, StopNever, StopClose, StopCloseThenKill)
+
+		autoHide := entry.TrayBehavior.AutoMinimizeAndHideOnLaunch
+		if entry.LaunchHiddenInBackground && autoHide {
+			v.warn(IssueHiddenAutoHide, p+"trayBehavior.autoMinimizeAndHideOnLaunch", "")
+			autoHide = false
+		}
+		if !entry.LaunchHiddenInBackground {
+			if entry.CaptureOutput {
+				v.warn(IssueNeedsHiddenLaunch, p+"captureOutput", "")
+			}
+			if entry.Supervise.Enabled {
+				v.warn(IssueNeedsHiddenLaunch, p+"supervise.enabled", "")
+			}
+		}
+		if entry.TrayBehavior.ProxyTrayIcon && !autoHide {
+			v.warn(IssueProxyWithoutAutoHide, p+"trayBehavior.proxyTrayIcon", "")
+		}
+		if autoHide && (kind == LaunchURI || kind == LaunchAppID) && strings.TrimSpace(entry.ExpectedProcess) == "" {
+			v.warn(IssueNoExpectedProcess, p+"expectedProcess", "")
+		}
+	}
+	return v.issues
+}
+
+// ValidateFile decodes the settings file at path and validates it, checking
+// that exe and shortcut targets exist.
+func ValidateFile(path string) (Issues, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := decodeSettings(data)
+	if err != nil {
+		return nil, err
+	}
+	return Validate(settings, LaunchTargetExists), nil
+}
+
+type validator struct {
+	issues Issues
+}
+
+func (v *validator) fail(code IssueCode, path, value string) {
+	v.issues = append(v.issues, Issue{Severity: SeverityError, Code: code, Path: path, Value: value})
+}
+
+func (v *validator) warn(code IssueCode, path, value string) {
+	v.issues = append(v.issues, Issue{Severity: SeverityWarning, Code: code, Path: path, Value: value})
+}
+
+// checkEnum warns about a non-empty value outside allowed; empty values take
+// the default without comment.
+func checkEnum[T ~string](v *validator, path string, value T, allowed ...T) {
+	if value == "" {
+		return
+	}
+	for _, a := range allowed {
+		if a == value {
+			return
+		}
+	}
+	v.warn(IssueUnknownValue, path, string(value))
+}
+
+// rangeCheck warns about a value outside [lo, hi].
+func (v *validator) rangeCheck(path string, value, lo, hi int) {
+	if value < lo || value > hi {
+		v.warn(IssueOutOfRange, path, strconv.Itoa(value))
+	}
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestValidateReportsIssuesWithPaths(t *testing.T) {
+	missing := func(path string) bool { return path != `C:\missing.exe` }
+	tests := []struct {
+		name  string
+		entry ManagedAppEntry
+		want  Issues
+	}{
+		{
+			name:  "valid",
+			entry: ManagedAppEntry{ID: "b", Name: "B", ExePath: `C:\b.exe`},
+		},
+		{
+			name:  "duplicate id",
+			entry: ManagedAppEntry{ID: "a", Name: "B", ExePath: `C:\b.exe`},
+			want:  Issues{{Severity: SeverityError, Code: IssueDuplicateID, Path: "managedApps[1].id", Value: "a"}},
+		},
+		{
+			name:  "missing exe",
+			entry: ManagedAppEntry{ID: "b", Name: "B", ExePath: `"C:\missing.exe"`},
+			want:  Issues{{Severity: SeverityError, Code: IssueTargetNotFound, Path: "managedApps[1].exePath", Value: `C:\missing.exe`}},
+		},
+		{
+			name:  "uri is not checked on disk",
+			entry: ManagedAppEntry{ID: "b", Name: "B", ExePath: "ms-settings:display"},
+		},
+		{
+			name:  "empty target",
+			entry: ManagedAppEntry{ID: "b", Name: "B"},
+			want:  Issues{{Severity: SeverityError, Code: IssueEmptyTarget, Path: "managedApps[1].exePath"}},
+		},
+		{
+			name:  "unknown strategy",
+			entry: ManagedAppEntry{ID: "b", Name: "B", ExePath: `C:\b.exe`, WindowMatch: WindowMatchRule{Strategy: "Fuzzy"}},
+			want:  Issues{{Severity: SeverityError, Code: IssueUnknownStrategy, Path: "managedApps[1].windowMatch.strategy", Value: "Fuzzy"}},
+		},
+		{
+			name: "hidden launch with auto-hide",
+			entry: ManagedAppEntry{ID: "b", Name: "B", ExePath: `C:\b.exe`, LaunchHiddenInBackground: true,
+				TrayBehavior: TrayBehavior{AutoMinimizeAndHideOnLaunch: true, ProxyTrayIcon: true}},
+			want: Issues{
+				{Severity: SeverityWarning, Code: IssueHiddenAutoHide, Path: "managedApps[1].trayBehavior.autoMinimizeAndHideOnLaunch"},
+				{Severity: SeverityWarning, Code: IssueProxyWithoutAutoHide, Path: "managedApps[1].trayBehavior.proxyTrayIcon"},
+			},
+		},
+		{
+			name:  "capture without hidden launch",
+			entry: ManagedAppEntry{ID: "b", Name: " ", ExePath: `C:\b.exe`, CaptureOutput: true},
+			want: Issues{
+				{Severity: SeverityWarning, Code: IssueEmptyName, Path: "managedApps[1].name"},
+				{Severity: SeverityWarning, Code: IssueNeedsHiddenLaunch, Path: "managedApps[1].captureOutput"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		settings := DefaultSettings()
+		settings.ManagedApps = []ManagedAppEntry{{ID: "a", Name: "A", ExePath: `C:\a.exe`}, tt.entry}
+		got := Validate(settings, missing)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Validate = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidateWarnsAboutSettingsLoadCorrects(t *testing.T) {
+	settings := DefaultSettings()
+	settings.LogLevel = "verbose"
+	settings.RunHistoryLimit = MaxRunHistoryLimit + 1
+
+	got := Validate(settings, nil)
+
+	want := Issues{
+		{Severity: SeverityWarning, Code: IssueUnknownValue, Path: "logLevel", Value: "verbose"},
+		{Severity: SeverityWarning, Code: IssueOutOfRange, Path: "runHistoryLimit", Value: "1001"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Validate = %v, want %v", got, want)
+	}
+	if got.HasErrors() {
+		t.Fatalf("HasErrors = true for warnings only")
+	}
+}
+
+func TestStoreLoadReportsIssuesOfTheFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	data := `{"schemaVersion":3,"managedApps":[{"id":"a","name":"A","exePath":"ms-settings:"},{"id":"a","name":"B","exePath":"ms-settings:"}]}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, report := NewStore(path).LoadWithReport()
+
+	if !report.Issues.HasErrors() || report.Issues[0].Code != IssueDuplicateID {
+		t.Fatalf("Issues = %v, want a duplicate ID error", report.Issues)
+	}
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"os"
+	"time"
+)
+
+// DefaultWatchInterval is how often Watch polls the settings file.
+const DefaultWatchInterval = time.Second
+
+// Watch polls the settings file every interval until ctx is done and calls
+// onChange when another program has changed it. A change is reported once
+// its content has stayed the same for two polls, so half-written files are
+// skipped. Content that cannot be decoded is reported through LoadReport.Err.
+//
+// onChange returns whether the new settings were adopted. Until they are,
+// Save refuses to replace them; Recheck offers the file again.
+func (s *Store) Watch(ctx context.Context, interval time.Duration, onChange func(Settings, LoadReport) bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.poll(onChange)
+		}
+	}
+}
+
+// Recheck makes Watch offer the file again even if onChange declined it.
+func (s *Store) Recheck() {
+	s.mu.Lock()
+	s.offered = [sha256.Size]byte{}
+	s.mu.Unlock()
+}
+
+func (s *Store) poll(onChange func(Settings, LoadReport) bool) {
+	s.mu.Lock()
+	data, err := os.ReadFile(s.path)
+	sum := sha256.Sum256(data)
+	settled := err == nil && sum == s.polled
+	s.polled = sum
+	if !settled || sum == s.digest || sum == s.offered {
+		s.mu.Unlock()
+		return
+	}
+	s.offered = sum
+	s.mu.Unlock()
+
+	settings, err := decodeSettings(data)
+	if err != nil {
+		onChange(Settings{}, LoadReport{Err: err})
+		return
+	}
+	if !onChange(normalize(settings), LoadReport{Issues: Validate(settings, LaunchTargetExists)}) {
+		return
+	}
+	s.mu.Lock()
+	if s.polled == sum {
+		s.remember(data)
+		s.newer = nil
+	}
+	s.mu.Unlock()
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type changeRecorder struct {
+	adopt   bool
+	changes []LoadReport
+	names   []string
+}
+
+func (r *changeRecorder) onChange(settings Settings, report LoadReport) bool {
+	r.changes = append(r.changes, report)
+	if len(settings.ManagedApps) > 0 {
+		r.names = append(r.names, settings.ManagedApps[0].Name)
+	}
+	return r.adopt
+}
+
+// pollTwice lets a change settle.
+func pollTwice(store *Store, r *changeRecorder) {
+	store.poll(r.onChange)
+	store.poll(r.onChange)
+}
+
+func writeExternal(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStoreSaveRefusesToReplaceExternalChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	store := NewStore(path)
+	store.Load()
+	if err := store.Save(DefaultSettings()); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	external := `{"schemaVersion":3,"language":"en-US"}`
+	writeExternal(t, path, external)
+
+	if err := store.Save(DefaultSettings()); !errors.Is(err, ErrSettingsChanged) {
+		t.Fatalf("Save over an external change = %v, want ErrSettingsChanged", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != external {
+		t.Fatalf("external change was replaced: %s", data)
+	}
+	if err := store.Overwrite(DefaultSettings()); err != nil {
+		t.Fatalf("Overwrite: %v", err)
+	}
+	if err := store.Save(DefaultSettings()); err != nil {
+		t.Fatalf("Save after Overwrite: %v", err)
+	}
+}
+
+func TestStoreWatchOffersExternalChangesOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	store := NewStore(path)
+	store.Load()
+	settings := DefaultSettings()
+	settings.ManagedApps = []ManagedAppEntry{{ID: "a", Name: "Own", ExePath: "ms-settings:"}}
+	if err := store.Save(settings); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	r := &changeRecorder{}
+	pollTwice(store, r)
+	if len(r.changes) != 0 {
+		t.Fatalf("own save reported as a change: %v", r.changes)
+	}
+
+	writeExternal(t, path, `{"schemaVersion":3,"managedApps":[{"id":"a","name":"External","exePath":"ms-settings:"}]}`)
+	store.poll(r.onChange)
+	if len(r.changes) != 0 {
+		t.Fatalf("change reported before it settled")
+	}
+	store.poll(r.onChange)
+	pollTwice(store, r)
+	if len(r.names) != 1 || r.names[0] != "External" {
+		t.Fatalf("changes = %v, want External once", r.names)
+	}
+	if err := store.Save(settings); !errors.Is(err, ErrSettingsChanged) {
+		t.Fatalf("Save over a declined change = %v, want ErrSettingsChanged", err)
+	}
+
+	store.Recheck()
+	r.adopt = true
+	store.poll(r.onChange)
+	if len(r.names) != 2 {
+		t.Fatalf("Recheck did not offer the change again: %v", r.names)
+	}
+	if err := store.Save(settings); err != nil {
+		t.Fatalf("Save after adopting: %v", err)
+	}
+}
+
+func TestStoreWatchReportsUndecodableChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	store := NewStore(path)
+	store.Load()
+	writeExternal(t, path, `{"managedApps": [`)
+
+	r := &changeRecorder{adopt: true}
+	pollTwice(store, r)
+
+	if len(r.changes) != 1 || r.changes[0].Err == nil {
+		t.Fatalf("changes = %v, want one decode error", r.changes)
+	}
+	if err := store.Save(DefaultSettings()); !errors.Is(err, ErrSettingsChanged) {
+		t.Fatalf("Save over an undecodable change = %v, want ErrSettingsChanged", err)
+	}
+}
@@ -0,0 +1,311 @@
+// Package diagnostics builds the zip a user attaches to a bug report: redacted
+// settings, logs, run history and a snapshot of the windows WinTray would
+// consider for each managed entry.
+package diagnostics
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"wintray/internal/config"
+	"wintray/internal/history"
+	"wintray/internal/logging"
+	"wintray/internal/orchestrator"
+)
+
+// Snapshot is the live state a bundle records.
+type Snapshot struct {
+	CreatedAt time.Time
+	Settings  config.Settings
+	Windows   []orchestrator.ManagedWindowInfo
+	// Candidates holds the scored windows of each managed entry, by entry ID.
+	Candidates map[string][]orchestrator.MatchCandidate
+}
+
+// Capture enumerates the current windows and scores them for every managed
+// entry.
+func Capture(orch *orchestrator.Service, settings config.Settings, now time.Time) Snapshot {
+	snap := Snapshot{
+		CreatedAt:  now,
+		Settings:   settings,
+		Windows:    orch.Windows(),
+		Candidates: map[string][]orchestrator.MatchCandidate{},
+	}
+	for _, entry := range settings.ManagedApps {
+		snap.Candidates[entry.ID] = orch.ScoreWindows(entry, snap.Windows)
+	}
+	return snap
+}
+
+// Sources locates the files a bundle copies.
+type Sources struct {
+	// LogDir holds wintray.log and its rotated generations.
+	LogDir  string
+	History *history.Store
+}
+
+// DefaultPath returns the bundle path used when none is given.
+func DefaultPath(dir string, now time.Time) string {
+	return filepath.Join(dir, "wintray-diagnostics-"+now.Format("20060102-150405")+".zip")
+}
+
+// BuildInfo identifies the running binary.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Revision  string `json:"revision,omitempty"`
+	Time      string `json:"time,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+	GoVersion string `json:"goVersion"`
+}
+
+// ReadBuildInfo returns the module version and VCS stamp embedded by go build.
+func ReadBuildInfo() BuildInfo {
+	info := BuildInfo{Version: "(devel)", GoVersion: runtime.Version()}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	if bi.Main.Version != "" {
+		info.Version = bi.Main.Version
+	}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			info.Revision = s.Value
+		case "vcs.time":
+			info.Time = s.Value
+		case "vcs.modified":
+			info.Modified = s.Value == "true"
+		}
+	}
+	return info
+}
+
+type bundleInfo struct {
+	CreatedAt time.Time `json:"createdAt"`
+	Build     BuildInfo `json:"build"`
+	OS        string    `json:"os"`
+	Arch      string    `json:"arch"`
+	// Errors lists the parts that could not be collected.
+	Errors []string `json:"errors,omitempty"`
+}
+
+type window struct {
+	Handle      string `json:"hwnd"`
+	PID         uint32 `json:"pid"`
+	Process     string `json:"process"`
+	ProcessPath string `json:"processPath"`
+	Title       string `json:"title"`
+	Class       string `json:"class"`
+	Minimized   bool   `json:"minimized,omitempty"`
+	Foreground  bool   `json:"foreground,omitempty"`
+	Owner       string `json:"owner,omitempty"`
+	Tool        bool   `json:"tool,omitempty"`
+}
+
+type candidate struct {
+	Score  int    `json:"score"`
+	Window window `json:"window"`
+}
+
+type entryWindows struct {
+	EntryID    string      `json:"entryId"`
+	AppName    string      `json:"appName"`
+	Candidates []candidate `json:"candidates"`
+}
+
+type windowReport struct {
+	// Threshold is the lowest score WinTray acts on.
+	Threshold int            `json:"threshold"`
+	Entries   []entryWindows `json:"entries"`
+	Windows   []window       `json:"windows"`
+}
+
+// Write creates the bundle at path. Parts that cannot be collected are noted
+// in info.json instead of failing the bundle.
+func Write(path string, snap Snapshot, src Sources, redact logging.Redactor) (err error) {
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			_ = os.Remove(path)
+		}
+	}()
+
+	zw := zip.NewWriter(f)
+	var problems []string
+	if err = writeJSON(zw, "settings.json", redactSettings(snap.Settings, redact)); err != nil {
+		return err
+	}
+	if err = writeJSON(zw, "windows.json", newWindowReport(snap, redact)); err != nil {
+		return err
+	}
+	if logErr := copyLogs(zw, src.LogDir); logErr != nil {
+		problems = append(problems, fmt.Sprintf("logs: %v", logErr))
+	}
+	if src.History != nil {
+		runs, listErr := src.History.List(0)
+		if listErr != nil {
+			problems = append(problems, fmt.Sprintf("history: %v", listErr))
+		}
+		for _, run := range runs {
+			if err = writeJSON(zw, "history/run-"+run.ID+".json", redactRun(run, redact)); err != nil {
+				return err
+			}
+		}
+	}
+	info := bundleInfo{
+		CreatedAt: snap.CreatedAt,
+		Build:     ReadBuildInfo(),
+		OS:        osVersion(),
+		Arch:      runtime.GOARCH,
+		Errors:    problems,
+	}
+	if err = writeJSON(zw, "info.json", info); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func writeJSON(zw *zip.Writer, name string, v any) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// copyLogs adds wintray.log and its rotated generations. The logger has
+// already redacted their records.
+func copyLogs(zw *zip.Writer, dir string) error {
+	names, err := filepath.Glob(filepath.Join(dir, "wintray.log*"))
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, name := range names {
+		errs = append(errs, copyFile(zw, "logs/"+filepath.Base(name), name))
+	}
+	return errors.Join(errs...)
+}
+
+func copyFile(zw *zip.Writer, name, path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, in)
+	return err
+}
+
+const redactedValue = "<redacted>"
+
+// redactSettings rewrites the paths in settings and hides environment values,
+// which often hold tokens, whatever the Privacy mode.
+func redactSettings(settings config.Settings, redact logging.Redactor) config.Settings {
+	apps := make([]config.ManagedAppEntry, len(settings.ManagedApps))
+	for i, entry := range settings.ManagedApps {
+		entry.ExePath = redact.Paths(entry.ExePath)
+		entry.ExpectedProcess = redact.Paths(entry.ExpectedProcess)
+		entry.WorkingDir = redact.Paths(entry.WorkingDir)
+		args := make([]string, len(entry.Args))
+		for j, arg := range entry.Args {
+			args[j] = redact.Paths(arg)
+		}
+		entry.Args = args
+		env := make([]config.EnvOverride, len(entry.Env))
+		for j, e := range entry.Env {
+			if e.Value != "" {
+				e.Value = redactedValue
+			}
+			env[j] = e
+		}
+		entry.Env = env
+		apps[i] = entry
+	}
+	settings.ManagedApps = apps
+	return settings
+}
+
+// windowTitle matches the title="..." part of a history window description.
+var windowTitle = regexp.MustCompile(`title="(?:[^"\\]|\\.)*"`)
+
+func redactRun(run history.Run, redact logging.Redactor) history.Run {
+	entries := make([]history.Entry, len(run.Entries))
+	for i, e := range run.Entries {
+		e.Error = redact.Paths(e.Error)
+		e.Window = windowTitle.ReplaceAllStringFunc(e.Window, func(m string) string {
+			title, err := strconv.Unquote(strings.TrimPrefix(m, "title="))
+			if err != nil {
+				return `title=""`
+			}
+			return "title=" + strconv.Quote(redact.Title(title))
+		})
+		entries[i] = e
+	}
+	run.Entries = entries
+	return run
+}
+
+func newWindowReport(snap Snapshot, redact logging.Redactor) windowReport {
+	report := windowReport{
+		Threshold: orchestrator.MinManageScore,
+		Entries:   make([]entryWindows, 0, len(snap.Settings.ManagedApps)),
+		Windows:   make([]window, 0, len(snap.Windows)),
+	}
+	for _, entry := range snap.Settings.ManagedApps {
+		ew := entryWindows{EntryID: entry.ID, AppName: entry.Name, Candidates: make([]candidate, 0)}
+		for _, c := range snap.Candidates[entry.ID] {
+			ew.Candidates = append(ew.Candidates, candidate{Score: c.Score, Window: newWindow(c.Window, redact)})
+		}
+		report.Entries = append(report.Entries, ew)
+	}
+	for _, w := range snap.Windows {
+		report.Windows = append(report.Windows, newWindow(w, redact))
+	}
+	return report
+}
+
+func newWindow(w orchestrator.ManagedWindowInfo, redact logging.Redactor) window {
+	out := window{
+		Handle:      fmt.Sprintf("0x%X", w.Handle),
+		PID:         w.ProcessID,
+		Process:     w.ProcessName,
+		ProcessPath: redact.Paths(w.ProcessPath),
+		Title:       redact.Title(w.Title),
+		Class:       w.ClassName,
+		Minimized:   w.IsMinimized,
+		Foreground:  w.IsForeground,
+		Tool:        w.IsToolWindow,
+	}
+	if w.OwnerHandle != 0 {
+		out.Owner = fmt.Sprintf("0x%X", w.OwnerHandle)
+	}
+	return out
+}
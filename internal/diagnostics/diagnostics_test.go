@@ -0,0 +1,99 @@
+package diagnostics
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"wintray/internal/config"
+	"wintray/internal/history"
+	"wintray/internal/logging"
+	"wintray/internal/orchestrator"
+)
+
+func readZip(t *testing.T, path string) map[string]string {
+	t.Helper()
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("open bundle: %v", err)
+	}
+	defer zr.Close()
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+	return files
+}
+
+func TestWriteBundlesRedactedState(t *testing.T) {
+	dir := t.TempDir()
+	logDir := filepath.Join(dir, "app")
+	if err := os.MkdirAll(logDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"wintray.log", "wintray.log.1.gz", "settings.json"} {
+		if err := os.WriteFile(filepath.Join(logDir, name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store := history.NewStore(filepath.Join(dir, "history"), 10)
+	started := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	run := history.NewRun(history.TriggerAutorun, started)
+	run.Entries = []history.Entry{{EntryID: "a", AppName: "Mail", Window: `hwnd=0x10 pid=7 title="Payroll \"Q3\"" class="X"`, Error: `open C:\Users\alice\x: denied`}}
+	if err := store.Save(run); err != nil {
+		t.Fatal(err)
+	}
+
+	mail := orchestrator.ManagedWindowInfo{Handle: 0x10, ProcessID: 7, ProcessName: "mail.exe", ProcessPath: `C:\Users\alice\mail.exe`, Title: "Payroll Q3"}
+	snap := Snapshot{
+		CreatedAt: started,
+		Settings: config.Settings{ManagedApps: []config.ManagedAppEntry{{
+			ID:      "a",
+			Name:    "Mail",
+			ExePath: `C:\Users\alice\mail.exe`,
+			Env:     []config.EnvOverride{{Op: config.EnvSet, Name: "TOKEN", Value: "hunter2"}},
+		}}},
+		Windows:    []orchestrator.ManagedWindowInfo{mail},
+		Candidates: map[string][]orchestrator.MatchCandidate{"a": {{Window: mail, Score: 900}}},
+	}
+	path := DefaultPath(filepath.Join(dir, "out"), started)
+	redact := logging.NewRedactor(logging.PrivacyHash, `C:\Users\alice`)
+	if err := Write(path, snap, Sources{LogDir: logDir, History: store}, redact); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	files := readZip(t, path)
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	want := []string{"history/run-" + run.ID + ".json", "info.json", "logs/wintray.log", "logs/wintray.log.1.gz", "settings.json", "windows.json"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Fatalf("files = %v, want %v", names, want)
+	}
+	for name, content := range files {
+		for _, secret := range []string{"Payroll", "hunter2", `Users\\alice`} {
+			if strings.Contains(content, secret) {
+				t.Errorf("%s contains %q:\n%s", name, secret, content)
+			}
+		}
+	}
+	if !strings.Contains(files["windows.json"], `"score": 900`) || !strings.Contains(files["windows.json"], redact.Title("Payroll Q3")) {
+		t.Errorf("windows.json lacks the scored candidate:\n%s", files["windows.json"])
+	}
+	if !strings.Contains(files["info.json"], `"goVersion"`) {
+		t.Errorf("info.json lacks build info:\n%s", files["info.json"])
+	}
+}
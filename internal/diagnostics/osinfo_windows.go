@@ -0,0 +1,16 @@
+//go:build windows
+
+package diagnostics
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows"
+)
+
+// osVersion reports the real Windows version; RtlGetVersion is not subject
+// to the manifest-based version lie of GetVersionEx.
+func osVersion() string {
+	v := windows.RtlGetVersion()
+	return fmt.Sprintf("Windows %d.%d.%d", v.MajorVersion, v.MinorVersion, v.BuildNumber)
+}
@@ -0,0 +1,292 @@
+// Package history keeps a record of each run of the managed apps: what
+// triggered it and how every entry ended.
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"wintray/internal/config"
+	"wintray/internal/orchestrator"
+)
+
+// Trigger says what started a run.
+type Trigger string
+
+const (
+	// TriggerAutorun is a run started by --autorun, usually at logon.
+	TriggerAutorun Trigger = "autorun"
+	// TriggerSupervisor records a supervised background launch that entered
+	// the crash-loop state.
+	TriggerSupervisor Trigger = "supervisor"
+)
+
+const (
+	idLayout   = "20060102-150405.000"
+	filePrefix = "run-"
+	fileExt    = ".json"
+)
+
+// ErrNoHistory is returned by Store.Latest when no run has been recorded.
+var ErrNoHistory = errors.New("no run history")
+
+// Run is one run of the managed apps.
+type Run struct {
+	ID        string    `json:"id"`
+	Trigger   Trigger   `json:"trigger"`
+	StartedAt time.Time `json:"startedAt"`
+	EndedAt   time.Time `json:"endedAt"`
+	Entries   []Entry   `json:"entries"`
+	// Notified is set when the run was announced with a tray notification.
+	Notified bool `json:"notified,omitempty"`
+}
+
+// Entry is the outcome of one managed entry within a run. Timings are
+// measured from the start of window matching, which follows the launch.
+type Entry struct {
+	EntryID   string                  `json:"entryId"`
+	AppName   string                  `json:"appName"`
+	Code      orchestrator.ResultCode `json:"code"`
+	Managed   bool                    `json:"managed"`
+	Error     string                  `json:"error,omitempty"`
+	StartedAt time.Time               `json:"startedAt"`
+	EndedAt   time.Time               `json:"endedAt"`
+	// TimeToFirstWindowMs is zero when no candidate window appeared.
+	TimeToFirstWindowMs int64 `json:"timeToFirstWindowMs,omitempty"`
+	// TimeToActionMs is zero when no window action took effect.
+	TimeToActionMs int64    `json:"timeToActionMs,omitempty"`
+	Attempts       int      `json:"attempts,omitempty"`
+	Candidates     int      `json:"candidates,omitempty"`
+	Score          int      `json:"score,omitempty"`
+	Actions        []string `json:"actions,omitempty"`
+	Window         string   `json:"window,omitempty"`
+	// PID is the background process started for the entry, if any.
+	PID uint32 `json:"pid,omitempty"`
+	// ExitCode and RuntimeMs are set once that process has exited.
+	ExitCode  *int  `json:"exitCode,omitempty"`
+	RuntimeMs int64 `json:"runtimeMs,omitempty"`
+	// Restarts is how often a supervised launch was restarted.
+	Restarts int `json:"restarts,omitempty"`
+}
+
+// NewRun starts a run record. Its ID orders runs by start time.
+func NewRun(trigger Trigger, startedAt time.Time) Run {
+	return Run{
+		ID:        startedAt.Format(idLayout),
+		Trigger:   trigger,
+		StartedAt: startedAt,
+		Entries:   make([]Entry, 0),
+	}
+}
+
+// NewCrashLoopRun records state, a supervised launch that kept crashing, as a
+// run of its own.
+func NewCrashLoopRun(state orchestrator.SupervisorState) Run {
+	run := NewRun(TriggerSupervisor, state.UpdatedAt)
+	run.EndedAt = state.UpdatedAt
+	exitCode := state.LastExitCode
+	run.Entries = append(run.Entries, Entry{
+		EntryID:   state.EntryID,
+		AppName:   state.AppName,
+		Code:      orchestrator.ResultCrashLoop,
+		StartedAt: state.StartedAt,
+		EndedAt:   state.UpdatedAt,
+		PID:       state.PID,
+		ExitCode:  &exitCode,
+		Restarts:  state.Restarts,
+	})
+	return run
+}
+
+// NewEntry records result for entry, which was processed from startedAt to
+// endedAt.
+func NewEntry(entry config.ManagedAppEntry, result orchestrator.Result, startedAt, endedAt time.Time) Entry {
+	e := Entry{
+		EntryID:             entry.ID,
+		AppName:             entry.Name,
+		Code:                result.Code,
+		Managed:             result.Managed,
+		StartedAt:           startedAt,
+		EndedAt:             endedAt,
+		TimeToFirstWindowMs: millis(result.Details.FirstWindow),
+		TimeToActionMs:      millis(result.Details.Action),
+		Attempts:            result.Details.Attempts,
+		Candidates:          result.Details.Candidates,
+		Score:               result.Details.Score,
+		Actions:             result.Details.Actions,
+		Window:              result.Details.Window,
+		PID:                 result.PID,
+	}
+	if result.Err != nil {
+		e.Error = result.Err.Error()
+	}
+	return e
+}
+
+// millis rounds d up so that a measured duration is never recorded as zero.
+func millis(d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+	return int64((d + time.Millisecond - 1) / time.Millisecond)
+}
+
+// RecordExit adds the exit of a background process to the entry that started
+// it and reports whether r has such an entry.
+func (r *Run) RecordExit(record orchestrator.ExitRecord) bool {
+	if record.PID == 0 {
+		return false
+	}
+	for i := range r.Entries {
+		e := &r.Entries[i]
+		if e.EntryID == record.EntryID && e.PID == record.PID && e.ExitCode == nil {
+			code := record.ExitCode
+			e.ExitCode = &code
+			e.RuntimeMs = millis(record.Runtime)
+			return true
+		}
+	}
+	return false
+}
+
+// Managed returns how many entries of r were managed.
+func (r Run) Managed() int {
+	n := 0
+	for _, e := range r.Entries {
+		if e.Managed {
+			n++
+		}
+	}
+	return n
+}
+
+// Store keeps runs as one JSON file each in a directory, pruning the oldest
+// beyond its limit.
+type Store struct {
+	dir   string
+	limit int
+	mu    sync.Mutex
+}
+
+func NewStore(dir string, limit int) *Store {
+	return &Store{dir: dir, limit: max(1, limit)}
+}
+
+// Save writes run and removes the oldest runs beyond the limit.
+func (s *Store) Save(run Run) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.write(run); err != nil {
+		return err
+	}
+	return s.prune()
+}
+
+// RecordExit adds the exit of a background process to the newest saved run
+// that started it and reports whether one was found.
+func (s *Store) RecordExit(record orchestrator.ExitRecord) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	runs, err := s.List(0)
+	for _, run := range runs {
+		if run.RecordExit(record) {
+			return true, s.write(run)
+		}
+	}
+	return false, err
+}
+
+func (s *Store) write(run Run) error {
+	if run.ID == "" || strings.ContainsAny(run.ID, `/\`) {
+		return fmt.Errorf("invalid run id %q", run.ID)
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(s.dir, filePrefix+run.ID+fileExt), data, 0o644)
+}
+
+func (s *Store) prune() error {
+	names, err := s.names()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, name := range names[min(len(names), s.limit):] {
+		if err = os.Remove(filepath.Join(s.dir, name)); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// names lists the run files, newest first.
+func (s *Store) names() ([]string, error) {
+	dirEntries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	names := make([]string, 0, len(dirEntries))
+	for _, de := range dirEntries {
+		name := de.Name()
+		if !de.IsDir() && strings.HasPrefix(name, filePrefix) && strings.HasSuffix(name, fileExt) {
+			names = append(names, name)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+	return names, nil
+}
+
+// List returns up to n runs, newest first; n <= 0 returns all of them. Runs
+// that cannot be read are skipped and reported in the returned error.
+func (s *Store) List(n int) ([]Run, error) {
+	names, err := s.names()
+	if err != nil {
+		return nil, err
+	}
+	if n > 0 && len(names) > n {
+		names = names[:n]
+	}
+	runs := make([]Run, 0, len(names))
+	var errs []error
+	for _, name := range names {
+		data, readErr := os.ReadFile(filepath.Join(s.dir, name))
+		if readErr != nil {
+			errs = append(errs, readErr)
+			continue
+		}
+		var run Run
+		if readErr = json.Unmarshal(data, &run); readErr != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, readErr))
+			continue
+		}
+		runs = append(runs, run)
+	}
+	return runs, errors.Join(errs...)
+}
+
+// Latest returns the most recent run, or ErrNoHistory.
+func (s *Store) Latest() (Run, error) {
+	runs, err := s.List(1)
+	if len(runs) == 0 {
+		if err == nil {
+			err = ErrNoHistory
+		}
+		return Run{}, err
+	}
+	return runs[0], nil
+}
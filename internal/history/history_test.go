@@ -0,0 +1,150 @@
+package history
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"wintray/internal/config"
+	"wintray/internal/orchestrator"
+)
+
+func TestStoreSaveListAndRetention(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(dir, 3)
+	if _, err := store.Latest(); !errors.Is(err, ErrNoHistory) {
+		t.Fatalf("Latest on empty store err = %v, want ErrNoHistory", err)
+	}
+
+	base := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	var saved []Run
+	for i := 0; i < 5; i++ {
+		started := base.Add(time.Duration(i) * time.Minute)
+		run := NewRun(TriggerAutorun, started)
+		run.EndedAt = started.Add(3 * time.Second)
+		run.Entries = append(run.Entries, Entry{EntryID: "a", AppName: "App", Code: orchestrator.ResultManaged, Managed: true})
+		if err := store.Save(run); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		saved = append(saved, run)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	runs, err := store.List(0)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(runs) != 3 {
+		t.Fatalf("List returned %d runs, want 3 after retention", len(runs))
+	}
+	for i, run := range runs {
+		want := saved[4-i]
+		if run.ID != want.ID || !run.StartedAt.Equal(want.StartedAt) || !reflect.DeepEqual(run.Entries, want.Entries) {
+			t.Errorf("runs[%d] = %+v, want %+v", i, run, want)
+		}
+	}
+	if runs, _ = store.List(2); len(runs) != 2 || runs[0].ID != saved[4].ID {
+		t.Fatalf("List(2) = %+v", runs)
+	}
+
+	if err = os.WriteFile(filepath.Join(dir, "run-"+saved[2].ID+".json"), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runs, err = store.List(0)
+	if err == nil || len(runs) != 2 {
+		t.Fatalf("List with corrupt run = %d runs, err %v; want 2 and an error", len(runs), err)
+	}
+	latest, err := store.Latest()
+	if err != nil || latest.ID != saved[4].ID {
+		t.Fatalf("Latest = %s, %v", latest.ID, err)
+	}
+}
+
+func TestNewEntry(t *testing.T) {
+	start := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	result := orchestrator.Result{
+		AppName: "Chat",
+		Code:    orchestrator.ResultNoWindowManaged,
+		Err:     errors.New("access is denied"),
+		Details: orchestrator.ResultDetails{
+			Attempts:    3,
+			Candidates:  1,
+			Score:       750,
+			FirstWindow: 200 * time.Microsecond,
+			Actions:     []string{"close", "hide"},
+		},
+	}
+	got := NewEntry(config.ManagedAppEntry{ID: "id-1", Name: "Chat"}, result, start, start.Add(time.Second))
+	want := Entry{
+		EntryID:             "id-1",
+		AppName:             "Chat",
+		Code:                orchestrator.ResultNoWindowManaged,
+		Error:               "access is denied",
+		StartedAt:           start,
+		EndedAt:             start.Add(time.Second),
+		TimeToFirstWindowMs: 1,
+		Attempts:            3,
+		Candidates:          1,
+		Score:               750,
+		Actions:             []string{"close", "hide"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("NewEntry = %+v\nwant %+v", got, want)
+	}
+}
+
+func TestStoreRecordExit(t *testing.T) {
+	store := NewStore(t.TempDir(), 5)
+	started := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	result := orchestrator.Result{AppName: "Sync", Code: orchestrator.ResultStartedHidden, Managed: true, PID: 42}
+	run := NewRun(TriggerAutorun, started)
+	run.Entries = append(run.Entries, NewEntry(config.ManagedAppEntry{ID: "sync", Name: "Sync"}, result, started, started))
+	if err := store.Save(run); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	if ok, err := store.RecordExit(orchestrator.ExitRecord{EntryID: "sync", PID: 7, ExitCode: 1}); ok || err != nil {
+		t.Fatalf("RecordExit for another process = %v, %v; want false, nil", ok, err)
+	}
+	ok, err := store.RecordExit(orchestrator.ExitRecord{EntryID: "sync", PID: 42, ExitCode: 3, Runtime: 1500 * time.Millisecond})
+	if !ok || err != nil {
+		t.Fatalf("RecordExit = %v, %v; want true, nil", ok, err)
+	}
+	latest, err := store.Latest()
+	if err != nil {
+		t.Fatalf("Latest: %v", err)
+	}
+	e := latest.Entries[0]
+	if e.PID != 42 || e.ExitCode == nil || *e.ExitCode != 3 || e.RuntimeMs != 1500 {
+		t.Fatalf("entry = %+v, want exit code 3 after 1500ms", e)
+	}
+	if ok, _ = store.RecordExit(orchestrator.ExitRecord{EntryID: "sync", PID: 42, ExitCode: 0}); ok {
+		t.Fatalf("a second exit of the same process was recorded")
+	}
+}
+
+func TestNewCrashLoopRun(t *testing.T) {
+	started := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	state := orchestrator.SupervisorState{EntryID: "sync", AppName: "Sync", PID: 42, Restarts: 3, LastExitCode: 2, CrashLoop: true, StartedAt: started, UpdatedAt: started.Add(time.Minute)}
+	run := NewCrashLoopRun(state)
+	if run.Trigger != TriggerSupervisor || !run.StartedAt.Equal(state.UpdatedAt) || len(run.Entries) != 1 {
+		t.Fatalf("run = %+v, want one supervisor entry at the crash loop", run)
+	}
+	e := run.Entries[0]
+	if e.Code != orchestrator.ResultCrashLoop || e.Managed || e.Restarts != 3 || e.ExitCode == nil || *e.ExitCode != 2 || e.EndedAt.Sub(e.StartedAt) != time.Minute {
+		t.Fatalf("entry = %+v", e)
+	}
+
+	store := NewStore(t.TempDir(), 5)
+	if err := store.Save(run); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if latest, err := store.Latest(); err != nil || !reflect.DeepEqual(latest.Entries, run.Entries) {
+		t.Fatalf("Latest = %+v, %v; want the crash loop", latest, err)
+	}
+}
@@ -0,0 +1,132 @@
+package i18n
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"wintray/internal/history"
+	"wintray/internal/orchestrator"
+)
+
+const runTimeLayout = "2006-01-02 15:04:05"
+
+// FormatRunSummary formats the one-line summary of run.
+func FormatRunSummary(language string, run history.Run) string {
+	msg := For(language)
+	return fmt.Sprintf(msg.RunHistoryRunLine, run.StartedAt.Local().Format(runTimeLayout), triggerText(msg, run.Trigger), run.Managed(), len(run.Entries), formatElapsed(run.EndedAt.Sub(run.StartedAt)))
+}
+
+// FormatRun formats run with the outcome and timings of every entry.
+func FormatRun(language string, run history.Run) string {
+	msg := For(language)
+	var b strings.Builder
+	b.WriteString(FormatRunSummary(language, run))
+	for _, e := range run.Entries {
+		b.WriteString("\r\n\r\n")
+		fmt.Fprintf(&b, msg.RunHistoryEntryLine, e.AppName, ResultText(language, e.Code), formatElapsed(e.EndedAt.Sub(e.StartedAt)))
+		if e.Attempts > 0 {
+			b.WriteString("\r\n  ")
+			fmt.Fprintf(&b, msg.RunHistoryEntryMatch, e.Attempts, e.Candidates, e.Score)
+			b.WriteString("\r\n  ")
+			fmt.Fprintf(&b, msg.RunHistoryEntryTimings, formatMillis(msg, e.TimeToFirstWindowMs), formatMillis(msg, e.TimeToActionMs))
+		}
+		if len(e.Actions) > 0 {
+			b.WriteString("\r\n  ")
+			fmt.Fprintf(&b, msg.RunHistoryEntryActions, strings.Join(e.Actions, " → "))
+		}
+		if e.Window != "" {
+			b.WriteString("\r\n  ")
+			fmt.Fprintf(&b, msg.RunHistoryEntryWindow, e.Window)
+		}
+		if e.ExitCode != nil && e.Code == orchestrator.ResultCrashLoop {
+			b.WriteString("\r\n  ")
+			fmt.Fprintf(&b, msg.RunHistoryEntryRestarts, e.Restarts, *e.ExitCode)
+		} else if e.ExitCode != nil {
+			b.WriteString("\r\n  ")
+			fmt.Fprintf(&b, msg.RunHistoryEntryExit, *e.ExitCode, formatElapsed(time.Duration(e.RuntimeMs)*time.Millisecond))
+		}
+		if e.Error != "" {
+			b.WriteString("\r\n  ")
+			fmt.Fprintf(&b, msg.RunHistoryEntryError, e.Error)
+		}
+	}
+	return b.String()
+}
+
+func triggerText(msg Messages, trigger history.Trigger) string {
+	switch trigger {
+	case history.TriggerAutorun:
+		return msg.RunHistoryTriggerAutorun
+	case history.TriggerSupervisor:
+		return msg.RunHistoryTriggerSupervisor
+	default:
+		return string(trigger)
+	}
+}
+
+func formatMillis(msg Messages, ms int64) string {
+	if ms == 0 {
+		return msg.RunHistoryNotReached
+	}
+	return formatElapsed(time.Duration(ms) * time.Millisecond)
+}
+
+func formatElapsed(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	return d.Round(10 * time.Millisecond).String()
+}
+
+// maxNotifyLines is how many entries a run notification lists.
+const maxNotifyLines = 4
+
+// maxNotifyBody is the length limit of balloon text.
+const maxNotifyBody = 255
+
+// FormatRunNotification formats the tray notification for entries, the
+// entries of a run selected by package notify. failuresOnly selects the
+// failure title over the summary title.
+func FormatRunNotification(language string, entries []history.Entry, failuresOnly bool) (title, body string) {
+	msg := For(language)
+	title = msg.RunSummaryTitle
+	if failuresOnly {
+		title = fmt.Sprintf(msg.RunNotifyFailuresTitle, len(entries))
+	}
+
+	lines := make([]string, 0, maxNotifyLines)
+	permission := false
+	for i, e := range entries {
+		// The wrapped error is not recorded; the candidate count is enough here.
+		result := orchestrator.Result{Managed: e.Managed, Code: e.Code, Details: orchestrator.ResultDetails{Candidates: e.Candidates}}
+		permission = permission || IsLikelyPermissionIssue(result)
+		if i < maxNotifyLines {
+			lines = append(lines, fmt.Sprintf(msg.RunSummaryLine, e.AppName, ResultText(language, e.Code)))
+		}
+	}
+	var tail []string
+	if len(entries) > maxNotifyLines {
+		tail = append(tail, fmt.Sprintf(msg.RunNotifyMore, len(entries)-maxNotifyLines))
+	}
+	if permission {
+		tail = append(tail, msg.StatusPermissionHint)
+	}
+	tail = append(tail, msg.RunNotifyClickHint)
+
+	// Keep the tail whole and shorten the entry lines to fit.
+	end := "\n" + strings.Join(tail, "\n")
+	head := truncateRunes(strings.Join(lines, "\n"), maxNotifyBody-len([]rune(end)))
+	return title, head + end
+}
+
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	if n <= 0 {
+		return ""
+	}
+	return string(r[:n-1]) + "…"
+}
@@ -0,0 +1,32 @@
+package i18n
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"wintray/internal/history"
+	"wintray/internal/orchestrator"
+)
+
+func TestFormatRunNotificationFitsBalloon(t *testing.T) {
+	entries := make([]history.Entry, 6)
+	for i := range entries {
+		entries[i] = history.Entry{AppName: strings.Repeat("Long Application Name ", 3), Code: orchestrator.ResultNoWindowManaged, Candidates: 1}
+	}
+	for _, lang := range LanguageOptions() {
+		msg := For(lang)
+		title, body := FormatRunNotification(lang, entries, true)
+		if !strings.Contains(title, "6") {
+			t.Errorf("%s: title %q does not count the failures", lang, title)
+		}
+		if n := utf8.RuneCountInString(body); n > maxNotifyBody {
+			t.Errorf("%s: body has %d characters, want at most %d", lang, n, maxNotifyBody)
+		}
+		for _, want := range []string{msg.StatusPermissionHint, msg.RunNotifyClickHint} {
+			if !strings.Contains(body, want) {
+				t.Errorf("%s: body %q lacks %q", lang, body, want)
+			}
+		}
+	}
+}
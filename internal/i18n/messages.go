@@ -14,168 +14,495 @@ const (
 )
 
 type Messages struct {
-	WindowTitle              string
-	RunAtLogon               string
-	StartHidden              string
-	ExitOnDone               string
-	RetrySeconds             string
-	LanguageLabel            string
-	ManagedListTitle         string
-	ManagedEditorTitle       string
-	ManagedAppPath           string
-	ManagedAppArgs           string
-	SelectProgram            string
-	ManagedAutoHide          string
-	ManagedLaunchHidden      string
-	ManagedNoSelectionHint   string
-	AddProgram               string
-	RemoveSelected           string
-	OpenLogs                 string
-	CleanupRestore           string
-	ExitApp                  string
-	TrayOpenSettings         string
-	TrayOpenLogs             string
-	TrayCleanupRestore       string
-	TrayExit                 string
-	TrayToolTip              string
-	SelectManagedExe         string
-	ExeFilter                string
-	AllFilesFilter           string
-	NewAppName               string
-	ManagedListItemTemplate  string
-	RunSummaryTitle          string
-	RunSummaryNone           string
-	RunSummaryLine           string
-	RunSummaryHeader         string
-	FatalStartupTitle        string
-	FatalStartupBodyTemplate string
-	AlreadyRunningTitle      string
-	AlreadyRunningBody       string
-	StatusLaunchFailTemplate string
-	StatusManageFailTemplate string
-	StatusManageOkTemplate   string
-	StatusNoTasks            string
-	StatusRetryExhausted     string
-	StatusPermissionHint     string
-	StatusOpenLogsFailed     string
-	CleanupConfirmTitle      string
-	CleanupConfirmBody       string
-	CleanupDoneTitle         string
-	CleanupDoneBody          string
-	CleanupFailedTitle       string
-	CleanupFailedBody        string
-	LanguageZhLabel          string
-	LanguageEnLabel          string
+	WindowTitle                   string
+	RunAtLogon                    string
+	StartHidden                   string
+	ExitOnDone                    string
+	StopOnExit                    string
+	RetrySeconds                  string
+	AutorunNotifyLabel            string
+	AutorunNotifyOff              string
+	AutorunNotifyFailures         string
+	AutorunNotifySummary          string
+	StartupBackendLabel           string
+	StartupBackendRunKey          string
+	StartupBackendTask            string
+	StartupBackendFolder          string
+	LogonDelaySeconds             string
+	LogonDelayInvalid             string
+	LogonHighestPrivileges        string
+	LogonDisallowOnBattery        string
+	LogonStatusRegistered         string
+	LogonStatusRepaired           string
+	LogonStatusFallback           string
+	LogonStatusNotRegistered      string
+	LogonStatusDisabledByUser     string
+	LogonStatusOtherExe           string
+	LogonStatusUnavailable        string
+	LanguageLabel                 string
+	ManagedListTitle              string
+	ManagedEditorTitle            string
+	ManagedAppPath                string
+	ManagedAppPathHint            string
+	ManagedAppArgs                string
+	ManagedWorkingDir             string
+	ManagedWorkingDirHint         string
+	ManagedExpectedProcess        string
+	ManagedExpectedProcessHint    string
+	ManagedEnv                    string
+	ManagedEnvHint                string
+	ManagedEnvInvalid             string
+	SelectProgram                 string
+	ManagedAutoHide               string
+	ManagedLaunchHidden           string
+	ManagedSupervise              string
+	ManagedProxyTrayIcon          string
+	ManagedCaptureOutput          string
+	ManagedMuteNotifications      string
+	ManagedStopPolicy             string
+	StopPolicyNever               string
+	StopPolicyClose               string
+	StopPolicyCloseThenKill       string
+	ManagedNoSelectionHint        string
+	AddProgram                    string
+	RemoveSelected                string
+	OpenLogs                      string
+	CleanupRestore                string
+	ImportStartup                 string
+	UndoStartupImport             string
+	ImportStartupTitle            string
+	ImportStartupHint             string
+	ImportStartupDisabled         string
+	ImportStartupDisableOriginals string
+	ImportStartupConfirm          string
+	DialogCancel                  string
+	DialogClose                   string
+	ImportStartupNone             string
+	ImportStartupScanFailed       string
+	ImportStartupDone             string
+	ImportStartupDisableFailed    string
+	UndoStartupImportNone         string
+	UndoStartupImportDone         string
+	UndoStartupImportFailed       string
+	RunHistory                    string
+	RunHistoryNone                string
+	RunHistoryLoadFailed          string
+	RunHistoryRunLine             string
+	RunHistoryTriggerAutorun      string
+	RunHistoryTriggerSupervisor   string
+	RunHistoryEntryLine           string
+	RunHistoryEntryMatch          string
+	RunHistoryEntryTimings        string
+	RunHistoryEntryActions        string
+	RunHistoryEntryWindow         string
+	RunHistoryEntryError          string
+	RunHistoryEntryExit           string
+	RunHistoryEntryRestarts       string
+	RunHistoryNotReached          string
+	ExitApp                       string
+	TrayOpenSettings              string
+	TrayStopManaged               string
+	TrayLastRun                   string
+	TrayCollectDiagnostics        string
+	DiagnosticsTitle              string
+	DiagnosticsSaved              string
+	DiagnosticsFailed             string
+	SettingsRecoveredTitle        string
+	SettingsRestoredFromBackup    string
+	SettingsResetToDefaults       string
+	SettingsSchemaTooNew          string
+	SettingsExternalTitle         string
+	SettingsExternalReloaded      string
+	SettingsExternalInvalid       string
+	SettingsExternalDiscardEdits  string
+	SettingsExternalOverwrite     string
+	ValidationButton              string
+	ValidationTitle               string
+	ValidationNone                string
+	ValidationError               string
+	ValidationWarning             string
+	ValidationIssueLine           string
+	ValidationSummary             string
+	ValidationStrictSkipped       string
+	TrayOpenOutput                string
+	OpenOutputNoneTitle           string
+	OpenOutputNoneBody            string
+	OpenOutputFailedBody          string
+	TrayHiddenWindows             string
+	TrayRestoreAll                string
+	TrayRestorePreviousSession    string
+	TrayUntitledWindow            string
+	ProxyShow                     string
+	ProxyHide                     string
+	ProxyQuit                     string
+	TrayOpenLogs                  string
+	TrayCleanupRestore            string
+	TrayExit                      string
+	TrayToolTip                   string
+	SelectManagedExe              string
+	ExeFilter                     string
+	AllFilesFilter                string
+	NewAppName                    string
+	ManagedListItemTemplate       string
+	RunSummaryTitle               string
+	RunSummaryLine                string
+	RunSummaryHeader              string
+	RunNotifyFailuresTitle        string
+	RunNotifyMore                 string
+	RunNotifyClickHint            string
+	FatalStartupTitle             string
+	FatalStartupBodyTemplate      string
+	AlreadyRunningTitle           string
+	AlreadyRunningBody            string
+	StatusLaunchFailTemplate      string
+	StatusManageFailTemplate      string
+	StatusManageOkTemplate        string
+	StatusNoTasks                 string
+	StatusRetryExhausted          string
+	StatusPermissionHint          string
+	StatusOpenLogsFailed          string
+	CleanupConfirmTitle           string
+	CleanupConfirmBody            string
+	CleanupDoneTitle              string
+	CleanupDoneBody               string
+	CleanupFailedTitle            string
+	CleanupFailedBody             string
+	SupervisorCrashLoopTitle      string
+	SupervisorCrashLoopBody       string
+	StopManagedFailedTitle        string
+	StopManagedFailedBody         string
+	PreviousSessionHiddenTitle    string
+	PreviousSessionHiddenBody     string
+	LanguageZhLabel               string
+	LanguageEnLabel               string
 }
 
 var zhCN = Messages{
-	WindowTitle:              "WinTray",
-	RunAtLogon:               "WinTray 开机启动",
-	StartHidden:              "启动后最小化到托盘",
-	ExitOnDone:               "完成所有任务后自行退出",
-	RetrySeconds:             "窗口重试秒数 (0-120):",
-	LanguageLabel:            "语言：",
-	ManagedListTitle:         "受管程序列表（开机时按配置自动处理前台窗口）",
-	ManagedEditorTitle:       "程序设置",
-	ManagedAppPath:           "程序路径：",
-	ManagedAppArgs:           "启动参数（可选）：",
-	SelectProgram:            "选择程序",
-	ManagedAutoHide:          "启动后关闭界面",
-	ManagedLaunchHidden:      "隐藏后台启动（适用于 cmd/bat）",
-	ManagedNoSelectionHint:   "请选择一个程序进行编辑，或点击“选择程序”新增。",
-	AddProgram:               "添加程序",
-	RemoveSelected:           "删除选中",
-	OpenLogs:                 "打开日志",
-	CleanupRestore:           "清理并恢复默认",
-	ExitApp:                  "退出 WinTray",
-	TrayOpenSettings:         "打开设置",
-	TrayOpenLogs:             "打开日志",
-	TrayCleanupRestore:       "清理并恢复默认",
-	TrayExit:                 "退出 WinTray",
-	TrayToolTip:              "WinTray",
-	SelectManagedExe:         "选择要托管的 EXE",
-	ExeFilter:                "可执行文件 (*.exe)|*.exe",
-	AllFilesFilter:           "所有文件 (*.*)|*.*",
-	NewAppName:               "新程序",
-	ManagedListItemTemplate:  "%s | %s | 启动后关闭界面=%t",
-	RunSummaryTitle:          "受管任务结果",
-	RunSummaryNone:           "没有可执行的受管任务。",
-	RunSummaryLine:           "%s：%s",
-	RunSummaryHeader:         "执行完成：",
-	FatalStartupTitle:        "WinTray 启动失败",
-	FatalStartupBodyTemplate: "%s\n\n日志：%s",
-	AlreadyRunningTitle:      "WinTray",
-	AlreadyRunningBody:       "WinTray 已在运行。",
-	StatusLaunchFailTemplate: "启动失败：%s (%s)",
-	StatusManageFailTemplate: "托管失败：%s (%s)",
-	StatusManageOkTemplate:   "托管成功：%s",
-	StatusNoTasks:            "没有受管任务。",
-	StatusRetryExhausted:     "重试超时，未找到可托管窗口",
-	StatusPermissionHint:     "可能是权限限制（UIPI）：请尝试以管理员身份运行 WinTray。",
-	StatusOpenLogsFailed:     "打开日志失败",
-	CleanupConfirmTitle:      "清理并恢复默认",
-	CleanupConfirmBody:       "将清除 WinTray 的本地配置与日志，并恢复默认设置。\r\n\r\n是否继续？",
-	CleanupDoneTitle:         "已计划清理",
-	CleanupDoneBody:          "已恢复默认设置，WinTray 将在退出后清理本地数据。",
-	CleanupFailedTitle:       "清理失败",
-	CleanupFailedBody:        "清理并恢复默认失败：%s",
-	LanguageZhLabel:          "中文",
-	LanguageEnLabel:          "English",
+	WindowTitle:                   "WinTray",
+	RunAtLogon:                    "WinTray 开机启动",
+	StartHidden:                   "启动后最小化到托盘",
+	ExitOnDone:                    "完成所有任务后自行退出",
+	StopOnExit:                    "退出时停止受管程序",
+	RetrySeconds:                  "窗口重试秒数 (0-120):",
+	AutorunNotifyLabel:            "自动运行后通知",
+	AutorunNotifyOff:              "关闭",
+	AutorunNotifyFailures:         "仅失败项",
+	AutorunNotifySummary:          "完整摘要",
+	StartupBackendLabel:           "开机启动方式:",
+	StartupBackendRunKey:          "注册表 Run 键",
+	StartupBackendTask:            "任务计划程序",
+	StartupBackendFolder:          "启动文件夹快捷方式",
+	LogonDelaySeconds:             "登录后延迟秒数 (0-600):",
+	LogonDelayInvalid:             "延迟秒数必须是 0 到 600 的数字。",
+	LogonHighestPrivileges:        "以最高权限运行",
+	LogonDisallowOnBattery:        "使用电池时不启动",
+	LogonStatusRegistered:         "状态：已注册",
+	LogonStatusRepaired:           "状态：已修复",
+	LogonStatusFallback:           "状态：已改用「%s」注册",
+	LogonStatusNotRegistered:      "状态：未注册",
+	LogonStatusDisabledByUser:     "状态：已在 Windows 中被用户禁用",
+	LogonStatusOtherExe:           "状态：指向其他程序 %s",
+	LogonStatusUnavailable:        "状态：无法读取（%v）",
+	LanguageLabel:                 "语言：",
+	ManagedListTitle:              "受管程序列表（开机时按配置自动处理前台窗口）",
+	ManagedEditorTitle:            "程序设置",
+	ManagedAppPath:                "启动目标：",
+	ManagedAppPathHint:            "程序路径、.lnk 快捷方式、URI（如 steam://rungameid/570）或应用包 AppUserModelID",
+	ManagedAppArgs:                "启动参数（可选）：",
+	ManagedWorkingDir:             "工作目录：",
+	ManagedWorkingDirHint:         "留空则使用程序所在目录，支持 %VAR%",
+	ManagedExpectedProcess:        "预期进程：",
+	ManagedExpectedProcessHint:    "用于识别窗口的进程名或完整路径；URI 和应用包目标需要填写才能管理窗口",
+	ManagedEnv:                    "环境变量：",
+	ManagedEnvHint:                "每行一项：NAME=值 设置，-NAME 删除，+PATH=目录 前置到路径列表；支持 %VAR%",
+	ManagedEnvInvalid:             "环境变量格式错误：%v",
+	SelectProgram:                 "选择程序",
+	ManagedAutoHide:               "启动后关闭界面",
+	ManagedLaunchHidden:           "隐藏后台启动（适用于 cmd/bat）",
+	ManagedSupervise:              "崩溃后自动重启",
+	ManagedProxyTrayIcon:          "隐藏后显示代理托盘图标",
+	ManagedCaptureOutput:          "捕获后台输出",
+	ManagedMuteNotifications:      "不通知此程序",
+	ManagedStopPolicy:             "停止方式：",
+	StopPolicyNever:               "从不停止",
+	StopPolicyClose:               "仅关闭窗口",
+	StopPolicyCloseThenKill:       "关闭后强制结束",
+	ManagedNoSelectionHint:        "请选择一个程序进行编辑，或点击“选择程序”新增。",
+	AddProgram:                    "添加程序",
+	RemoveSelected:                "删除选中",
+	OpenLogs:                      "打开日志",
+	CleanupRestore:                "清理并恢复默认",
+	ImportStartup:                 "导入启动项…",
+	UndoStartupImport:             "撤销导入",
+	ImportStartupTitle:            "导入启动项",
+	ImportStartupHint:             "选择要交给 WinTray 管理的启动项：",
+	ImportStartupDisabled:         "（已禁用）",
+	ImportStartupDisableOriginals: "导入后禁用原启动项（可撤销）",
+	ImportStartupConfirm:          "导入",
+	DialogCancel:                  "取消",
+	DialogClose:                   "关闭",
+	ImportStartupNone:             "没有找到可导入的启动项。",
+	ImportStartupScanFailed:       "部分启动项位置无法读取",
+	ImportStartupDone:             "已导入 %d 个启动项。",
+	ImportStartupDisableFailed:    "部分原启动项未能禁用",
+	UndoStartupImportNone:         "没有可撤销的导入。",
+	UndoStartupImportDone:         "已恢复 %d 个原启动项。",
+	UndoStartupImportFailed:       "撤销导入失败",
+	RunHistory:                    "运行历史",
+	RunHistoryNone:                "暂无运行记录",
+	RunHistoryLoadFailed:          "读取运行历史失败",
+	RunHistoryRunLine:             "%s  %s  已托管 %d/%d  用时 %s",
+	RunHistoryTriggerAutorun:      "自动运行",
+	RunHistoryTriggerSupervisor:   "后台守护",
+	RunHistoryEntryLine:           "%s：%s（用时 %s）",
+	RunHistoryEntryMatch:          "匹配 %d 轮，候选窗口 %d 个，得分 %d",
+	RunHistoryEntryTimings:        "首个窗口 %s，完成动作 %s",
+	RunHistoryEntryActions:        "动作：%s",
+	RunHistoryEntryWindow:         "窗口：%s",
+	RunHistoryEntryError:          "错误：%s",
+	RunHistoryEntryExit:           "已退出，退出码 %d，运行 %s",
+	RunHistoryEntryRestarts:       "重启 %d 次，最后退出码 %d",
+	RunHistoryNotReached:          "—",
+	ExitApp:                       "退出 WinTray",
+	TrayOpenSettings:              "打开设置",
+	TrayStopManaged:               "停止所有受管程序",
+	TrayLastRun:                   "上次运行：已托管 %d/%d（%s）",
+	TrayCollectDiagnostics:        "收集诊断信息",
+	DiagnosticsTitle:              "诊断信息",
+	DiagnosticsSaved:              "诊断包已保存到：%s",
+	DiagnosticsFailed:             "收集诊断信息失败",
+	SettingsRecoveredTitle:        "设置文件已损坏",
+	SettingsRestoredFromBackup:    "已从备份恢复设置：%s",
+	SettingsResetToDefaults:       "没有可用的备份，已恢复默认设置。损坏的文件已另存为 .invalid 备份。",
+	SettingsSchemaTooNew:          "settings.json 由更新版本的 WinTray 写入（%v），本版本不会修改或覆盖它，请升级 WinTray。",
+	SettingsExternalTitle:         "设置已在外部修改",
+	SettingsExternalReloaded:      "已载入 settings.json 中的修改。",
+	SettingsExternalInvalid:       "settings.json 已被修改但无法读取，将继续使用当前设置，修复前不会覆盖该文件：%v",
+	SettingsExternalDiscardEdits:  "settings.json 已在外部修改。是否载入这些修改并放弃尚未保存的编辑？选择“否”将保留你的编辑，保存时会再次询问。",
+	SettingsExternalOverwrite:     "settings.json 在 WinTray 之外被修改过。是否用你的修改覆盖它？选择“否”将放弃此次修改并载入文件中的设置。",
+	ValidationButton:              "配置问题 (%d)",
+	ValidationTitle:               "配置检查",
+	ValidationNone:                "未发现配置问题。",
+	ValidationError:               "错误",
+	ValidationWarning:             "警告",
+	ValidationIssueLine:           "%s  %s：%s",
+	ValidationSummary:             "发现 %d 个错误、%d 个警告：",
+	ValidationStrictSkipped:       "配置存在错误，严格校验模式下已跳过自动运行。点击查看问题。",
+	TrayOpenOutput:                "打开最新输出",
+	OpenOutputNoneTitle:           "没有输出",
+	OpenOutputNoneBody:            "“%s” 还没有捕获到输出。",
+	OpenOutputFailedBody:          "打开输出失败：%v",
+	TrayHiddenWindows:             "已隐藏的窗口",
+	TrayRestoreAll:                "全部恢复",
+	TrayRestorePreviousSession:    "恢复上次会话隐藏的窗口",
+	TrayUntitledWindow:            "（无标题）",
+	ProxyShow:                     "显示窗口",
+	ProxyHide:                     "隐藏窗口",
+	ProxyQuit:                     "退出程序",
+	TrayOpenLogs:                  "打开日志",
+	TrayCleanupRestore:            "清理并恢复默认",
+	TrayExit:                      "退出 WinTray",
+	TrayToolTip:                   "WinTray",
+	SelectManagedExe:              "选择要托管的 EXE",
+	ExeFilter:                     "程序或快捷方式 (*.exe;*.lnk;*.bat;*.cmd)|*.exe;*.lnk;*.bat;*.cmd",
+	AllFilesFilter:                "所有文件 (*.*)|*.*",
+	NewAppName:                    "新程序",
+	ManagedListItemTemplate:       "%s | %s | 启动后关闭界面=%t",
+	RunSummaryTitle:               "受管任务结果",
+	RunSummaryLine:                "%s：%s",
+	RunSummaryHeader:              "执行完成：",
+	RunNotifyFailuresTitle:        "%d 个程序未能完成托管",
+	RunNotifyMore:                 "……另有 %d 项",
+	RunNotifyClickHint:            "点击查看详情",
+	FatalStartupTitle:             "WinTray 启动失败",
+	FatalStartupBodyTemplate:      "%s\n\n日志：%s",
+	AlreadyRunningTitle:           "WinTray",
+	AlreadyRunningBody:            "WinTray 已在运行。",
+	StatusLaunchFailTemplate:      "启动失败：%s (%s)",
+	StatusManageFailTemplate:      "托管失败：%s (%s)",
+	StatusManageOkTemplate:        "托管成功：%s",
+	StatusNoTasks:                 "没有受管任务。",
+	StatusRetryExhausted:          "重试超时，未找到可托管窗口",
+	StatusPermissionHint:          "可能是权限限制（UIPI）：请尝试以管理员身份运行 WinTray。",
+	StatusOpenLogsFailed:          "打开日志失败",
+	CleanupConfirmTitle:           "清理并恢复默认",
+	CleanupConfirmBody:            "将清除 WinTray 的本地配置与日志，并恢复默认设置。\r\n\r\n是否继续？",
+	CleanupDoneTitle:              "已计划清理",
+	CleanupDoneBody:               "已恢复默认设置，WinTray 将在退出后清理本地数据。",
+	CleanupFailedTitle:            "清理失败",
+	CleanupFailedBody:             "清理并恢复默认失败：%s",
+	SupervisorCrashLoopTitle:      "后台程序反复崩溃",
+	SupervisorCrashLoopBody:       "%s 在 %d 次重启后仍然崩溃（退出码 %d），已停止自动重启。",
+	StopManagedFailedTitle:        "部分程序未能停止",
+	StopManagedFailedBody:         "以下程序仍在运行：%s",
+	PreviousSessionHiddenTitle:    "发现上次隐藏的窗口",
+	PreviousSessionHiddenBody:     "上次运行时隐藏的 %d 个窗口仍处于隐藏状态，可在托盘菜单“已隐藏的窗口”中恢复。",
+	LanguageZhLabel:               "中文",
+	LanguageEnLabel:               "English",
 }
 
 var enUS = Messages{
-	WindowTitle:              "WinTray",
-	RunAtLogon:               "Run WinTray at logon",
-	StartHidden:              "Minimize to tray after launch",
-	ExitOnDone:               "Exit automatically after all tasks complete",
-	RetrySeconds:             "Window retry seconds (0-120):",
-	LanguageLabel:            "Language:",
-	ManagedListTitle:         "Managed apps (apply window handling at startup)",
-	ManagedEditorTitle:       "Program Settings",
-	ManagedAppPath:           "Executable path:",
-	ManagedAppArgs:           "Launch arguments (optional):",
-	SelectProgram:            "Select Program",
-	ManagedAutoHide:          "Close window after launch",
-	ManagedLaunchHidden:      "Launch hidden in background (for cmd/bat)",
-	ManagedNoSelectionHint:   "Select a program to edit, or click Select Program to add one.",
-	AddProgram:               "Add Program",
-	RemoveSelected:           "Remove Selected",
-	OpenLogs:                 "Open Logs",
-	CleanupRestore:           "Cleanup && Restore Defaults",
-	ExitApp:                  "Exit WinTray",
-	TrayOpenSettings:         "Open Settings",
-	TrayOpenLogs:             "Open Logs",
-	TrayCleanupRestore:       "Cleanup && Restore Defaults",
-	TrayExit:                 "Exit WinTray",
-	TrayToolTip:              "WinTray",
-	SelectManagedExe:         "Select EXE to manage",
-	ExeFilter:                "Executable (*.exe)|*.exe",
-	AllFilesFilter:           "All Files (*.*)|*.*",
-	NewAppName:               "New App",
-	ManagedListItemTemplate:  "%s | %s | CloseAfterLaunch=%t",
-	RunSummaryTitle:          "Managed Task Results",
-	RunSummaryNone:           "No managed tasks to run.",
-	RunSummaryLine:           "%s: %s",
-	RunSummaryHeader:         "Completed:",
-	FatalStartupTitle:        "WinTray startup failed",
-	FatalStartupBodyTemplate: "%s\n\nLog: %s",
-	AlreadyRunningTitle:      "WinTray",
-	AlreadyRunningBody:       "WinTray is already running.",
-	StatusLaunchFailTemplate: "Launch failed: %s (%s)",
-	StatusManageFailTemplate: "Manage failed: %s (%s)",
-	StatusManageOkTemplate:   "Managed: %s",
-	StatusNoTasks:            "No managed tasks.",
-	StatusRetryExhausted:     "Retry exhausted, no manageable window found",
-	StatusPermissionHint:     "Possible UIPI permission limitation: try running WinTray as administrator.",
-	StatusOpenLogsFailed:     "Failed to open logs",
-	CleanupConfirmTitle:      "Cleanup && Restore Defaults",
-	CleanupConfirmBody:       "This will clear WinTray local settings and logs, then restore defaults.\r\n\r\nContinue?",
-	CleanupDoneTitle:         "Cleanup Scheduled",
-	CleanupDoneBody:          "Default settings restored. WinTray data will be cleaned after exit.",
-	CleanupFailedTitle:       "Cleanup Failed",
-	CleanupFailedBody:        "Cleanup and restore failed: %s",
-	LanguageZhLabel:          "中文",
-	LanguageEnLabel:          "English",
+	WindowTitle:                   "WinTray",
+	RunAtLogon:                    "Run WinTray at logon",
+	StartHidden:                   "Minimize to tray after launch",
+	ExitOnDone:                    "Exit automatically after all tasks complete",
+	StopOnExit:                    "Stop managed apps on exit",
+	RetrySeconds:                  "Window retry seconds (0-120):",
+	AutorunNotifyLabel:            "Notify after autorun",
+	AutorunNotifyOff:              "Off",
+	AutorunNotifyFailures:         "Failures only",
+	AutorunNotifySummary:          "Full summary",
+	StartupBackendLabel:           "Run at logon via:",
+	StartupBackendRunKey:          "Registry Run key",
+	StartupBackendTask:            "Task Scheduler",
+	StartupBackendFolder:          "Startup folder shortcut",
+	LogonDelaySeconds:             "Delay after sign-in, seconds (0-600):",
+	LogonDelayInvalid:             "Delay must be a number between 0 and 600.",
+	LogonHighestPrivileges:        "Run with highest privileges",
+	LogonDisallowOnBattery:        "Do not start on battery power",
+	LogonStatusRegistered:         "Status: registered",
+	LogonStatusRepaired:           "Status: repaired",
+	LogonStatusFallback:           "Status: registered via %s instead",
+	LogonStatusNotRegistered:      "Status: not registered",
+	LogonStatusDisabledByUser:     "Status: disabled by the user in Windows",
+	LogonStatusOtherExe:           "Status: points to another program: %s",
+	LogonStatusUnavailable:        "Status: unavailable (%v)",
+	LanguageLabel:                 "Language:",
+	ManagedListTitle:              "Managed apps (apply window handling at startup)",
+	ManagedEditorTitle:            "Program Settings",
+	ManagedAppPath:                "Launch target:",
+	ManagedAppPathHint:            "Program path, .lnk shortcut, URI (such as steam://rungameid/570) or packaged app AppUserModelID",
+	ManagedAppArgs:                "Launch arguments (optional):",
+	ManagedWorkingDir:             "Working dir:",
+	ManagedWorkingDirHint:         "Leave empty to use the program's directory; %VAR% is expanded",
+	ManagedExpectedProcess:        "Expected process:",
+	ManagedExpectedProcessHint:    "Process name or full exe path used to find the app's windows; URI and packaged app targets need it for window management",
+	ManagedEnv:                    "Environment:",
+	ManagedEnvHint:                "One per line: NAME=value sets, -NAME unsets, +PATH=dir prepends to a path list; %VAR% is expanded",
+	ManagedEnvInvalid:             "Invalid environment entry: %v",
+	SelectProgram:                 "Select Program",
+	ManagedAutoHide:               "Close window after launch",
+	ManagedLaunchHidden:           "Launch hidden in background (for cmd/bat)",
+	ManagedSupervise:              "Restart on crash",
+	ManagedProxyTrayIcon:          "Proxy tray icon when hidden",
+	ManagedCaptureOutput:          "Capture output",
+	ManagedMuteNotifications:      "Don't notify about this app",
+	ManagedStopPolicy:             "Stop policy:",
+	StopPolicyNever:               "Never stop",
+	StopPolicyClose:               "Close only",
+	StopPolicyCloseThenKill:       "Close, then kill",
+	ManagedNoSelectionHint:        "Select a program to edit, or click Select Program to add one.",
+	AddProgram:                    "Add Program",
+	RemoveSelected:                "Remove Selected",
+	OpenLogs:                      "Open Logs",
+	CleanupRestore:                "Cleanup && Restore Defaults",
+	ImportStartup:                 "Import startup items…",
+	UndoStartupImport:             "Undo import",
+	ImportStartupTitle:            "Import startup items",
+	ImportStartupHint:             "Select the startup items WinTray should manage:",
+	ImportStartupDisabled:         " (disabled)",
+	ImportStartupDisableOriginals: "Disable the original startup items after import (can be undone)",
+	ImportStartupConfirm:          "Import",
+	DialogCancel:                  "Cancel",
+	DialogClose:                   "Close",
+	ImportStartupNone:             "No startup items to import were found.",
+	ImportStartupScanFailed:       "Some startup locations could not be read",
+	ImportStartupDone:             "Imported %d startup items.",
+	ImportStartupDisableFailed:    "Some original startup items could not be disabled",
+	UndoStartupImportNone:         "There is no import to undo.",
+	UndoStartupImportDone:         "Restored %d original startup items.",
+	UndoStartupImportFailed:       "Undo import failed",
+	RunHistory:                    "Run History",
+	RunHistoryNone:                "No runs recorded yet",
+	RunHistoryLoadFailed:          "Failed to read run history",
+	RunHistoryRunLine:             "%s  %s  managed %d/%d  took %s",
+	RunHistoryTriggerAutorun:      "autorun",
+	RunHistoryTriggerSupervisor:   "supervisor",
+	RunHistoryEntryLine:           "%s: %s (took %s)",
+	RunHistoryEntryMatch:          "%d match rounds, %d candidate windows, score %d",
+	RunHistoryEntryTimings:        "first window after %s, action after %s",
+	RunHistoryEntryActions:        "actions: %s",
+	RunHistoryEntryWindow:         "window: %s",
+	RunHistoryEntryError:          "error: %s",
+	RunHistoryEntryExit:           "exited with code %d after %s",
+	RunHistoryEntryRestarts:       "restarted %d times, last exit code %d",
+	RunHistoryNotReached:          "—",
+	ExitApp:                       "Exit WinTray",
+	TrayOpenSettings:              "Open Settings",
+	TrayStopManaged:               "Stop All Managed Apps",
+	TrayLastRun:                   "Last Run: %d/%d Managed (%s)",
+	TrayCollectDiagnostics:        "Collect Diagnostics",
+	DiagnosticsTitle:              "Diagnostics",
+	DiagnosticsSaved:              "Diagnostics bundle saved to: %s",
+	DiagnosticsFailed:             "Failed to collect diagnostics",
+	SettingsRecoveredTitle:        "Settings File Damaged",
+	SettingsRestoredFromBackup:    "Settings were restored from the backup %s.",
+	SettingsResetToDefaults:       "No usable backup was found, so the default settings are in use. The damaged file was kept as an .invalid backup.",
+	SettingsSchemaTooNew:          "settings.json was written by a newer WinTray (%v). This version will not change or overwrite it; please upgrade WinTray.",
+	SettingsExternalTitle:         "Settings changed outside WinTray",
+	SettingsExternalReloaded:      "The changes made to settings.json are now in use.",
+	SettingsExternalInvalid:       "settings.json was changed but cannot be read. The current settings stay in use, and the file is not overwritten until it is fixed: %v",
+	SettingsExternalDiscardEdits:  "settings.json was changed outside WinTray. Load those changes and discard your unsaved edits? Choose No to keep your edits; you will be asked again when they are saved.",
+	SettingsExternalOverwrite:     "settings.json was changed outside WinTray. Overwrite it with your change? Choose No to discard your change and load the settings from the file.",
+	ValidationButton:              "Issues (%d)",
+	ValidationTitle:               "Settings check",
+	ValidationNone:                "No problems found in the settings.",
+	ValidationError:               "Error",
+	ValidationWarning:             "Warning",
+	ValidationIssueLine:           "%s  %s: %s",
+	ValidationSummary:             "Found %d errors and %d warnings:",
+	ValidationStrictSkipped:       "The settings have errors, so strict validation skipped the autorun launch. Click to see the issues.",
+	TrayOpenOutput:                "Open latest output",
+	OpenOutputNoneTitle:           "No output",
+	OpenOutputNoneBody:            "No output has been captured for \"%s\" yet.",
+	OpenOutputFailedBody:          "Failed to open output: %v",
+	TrayHiddenWindows:             "Hidden Windows",
+	TrayRestoreAll:                "Restore All",
+	TrayRestorePreviousSession:    "Restore Windows Hidden in Previous Session",
+	TrayUntitledWindow:            "(untitled)",
+	ProxyShow:                     "Show Window",
+	ProxyHide:                     "Hide Window",
+	ProxyQuit:                     "Quit App",
+	TrayOpenLogs:                  "Open Logs",
+	TrayCleanupRestore:            "Cleanup && Restore Defaults",
+	TrayExit:                      "Exit WinTray",
+	TrayToolTip:                   "WinTray",
+	SelectManagedExe:              "Select EXE to manage",
+	ExeFilter:                     "Programs and shortcuts (*.exe;*.lnk;*.bat;*.cmd)|*.exe;*.lnk;*.bat;*.cmd",
+	AllFilesFilter:                "All Files (*.*)|*.*",
+	NewAppName:                    "New App",
+	ManagedListItemTemplate:       "%s | %s | CloseAfterLaunch=%t",
+	RunSummaryTitle:               "Managed Task Results",
+	RunSummaryLine:                "%s: %s",
+	RunSummaryHeader:              "Completed:",
+	RunNotifyFailuresTitle:        "%d apps were not managed",
+	RunNotifyMore:                 "…and %d more",
+	RunNotifyClickHint:            "Click for details",
+	FatalStartupTitle:             "WinTray startup failed",
+	FatalStartupBodyTemplate:      "%s\n\nLog: %s",
+	AlreadyRunningTitle:           "WinTray",
+	AlreadyRunningBody:            "WinTray is already running.",
+	StatusLaunchFailTemplate:      "Launch failed: %s (%s)",
+	StatusManageFailTemplate:      "Manage failed: %s (%s)",
+	StatusManageOkTemplate:        "Managed: %s",
+	StatusNoTasks:                 "No managed tasks.",
+	StatusRetryExhausted:          "Retry exhausted, no manageable window found",
+	StatusPermissionHint:          "Possible UIPI permission limitation: try running WinTray as administrator.",
+	StatusOpenLogsFailed:          "Failed to open logs",
+	CleanupConfirmTitle:           "Cleanup && Restore Defaults",
+	CleanupConfirmBody:            "This will clear WinTray local settings and logs, then restore defaults.\r\n\r\nContinue?",
+	CleanupDoneTitle:              "Cleanup Scheduled",
+	CleanupDoneBody:               "Default settings restored. WinTray data will be cleaned after exit.",
+	CleanupFailedTitle:            "Cleanup Failed",
+	CleanupFailedBody:             "Cleanup and restore failed: %s",
+	SupervisorCrashLoopTitle:      "Background app keeps crashing",
+	SupervisorCrashLoopBody:       "%s crashed again after %d restarts (exit code %d). Automatic restarts stopped.",
+	StopManagedFailedTitle:        "Some apps did not stop",
+	StopManagedFailedBody:         "Still running: %s",
+	PreviousSessionHiddenTitle:    "Windows hidden in previous session",
+	PreviousSessionHiddenBody:     "%d windows hidden by the previous WinTray session are still hidden. Restore them from the tray menu under Hidden Windows.",
+	LanguageZhLabel:               "中文",
+	LanguageEnLabel:               "English",
 }
 
 func Resolve(language string) Lang {
@@ -200,62 +527,3 @@ func FormatManagedListItem(language string, app config.ManagedAppEntry) string {
 	msg := For(language)
 	return fmt.Sprintf(msg.ManagedListItemTemplate, app.Name, app.ExePath, app.TrayBehavior.AutoMinimizeAndHideOnLaunch)
 }
-
-func IsLikelyPermissionIssue(message string) bool {
-	return message == "no window managed" || message == "no existing window managed"
-}
-
-func TranslateResultMessage(language, message string) string {
-	msg := For(language)
-	switch message {
-	case "empty exe path":
-		if Resolve(language) == LangEnUS {
-			return "empty executable path"
-		}
-		return "可执行路径为空"
-	case "invalid exe path":
-		if Resolve(language) == LangEnUS {
-			return "invalid executable path"
-		}
-		return "可执行路径无效"
-	case "process start failed":
-		if Resolve(language) == LangEnUS {
-			return "process start failed"
-		}
-		return "启动进程失败"
-	case "started only":
-		if Resolve(language) == LangEnUS {
-			return "started only"
-		}
-		return "仅启动（未执行托管动作）"
-	case "started hidden":
-		if Resolve(language) == LangEnUS {
-			return "started hidden in background"
-		}
-		return "已隐藏后台启动"
-	case "already running skipped":
-		if Resolve(language) == LangEnUS {
-			return "already running, skipped relaunch"
-		}
-		return "程序已在运行，已跳过重复拉起"
-	case "already running managed existing":
-		if Resolve(language) == LangEnUS {
-			return "already running, managed existing window"
-		}
-		return "程序已在运行，已处理现有窗口"
-	case "no window managed", "no existing window managed":
-		return msg.StatusRetryExhausted
-	case "managed", "managed existing":
-		if Resolve(language) == LangEnUS {
-			return "front window closed"
-		}
-		return "前台界面已关闭"
-	case "invalid process name":
-		if Resolve(language) == LangEnUS {
-			return "invalid process name"
-		}
-		return "进程名无效"
-	default:
-		return message
-	}
-}
@@ -0,0 +1,69 @@
+package i18n
+
+import (
+	"errors"
+	"os"
+
+	"wintray/internal/orchestrator"
+)
+
+var resultTexts = map[Lang]map[orchestrator.ResultCode]string{
+	LangZhCN: {
+		orchestrator.ResultEmptyExePath:            "可执行路径为空",
+		orchestrator.ResultInvalidLaunchTarget:     "启动目标无效",
+		orchestrator.ResultInvalidProcessName:      "进程名无效",
+		orchestrator.ResultStartFailed:             "启动进程失败",
+		orchestrator.ResultStartedOnly:             "仅启动（未执行托管动作）",
+		orchestrator.ResultStartedHidden:           "已隐藏后台启动",
+		orchestrator.ResultAlreadyRunningSkipped:   "程序已在运行，已跳过重复拉起",
+		orchestrator.ResultAlreadyRunningManaged:   "程序已在运行，已处理现有窗口",
+		orchestrator.ResultNoExpectedProcess:       "无法确定要托管的进程",
+		orchestrator.ResultNoWindowManaged:         zhCN.StatusRetryExhausted,
+		orchestrator.ResultNoExistingWindowManaged: zhCN.StatusRetryExhausted,
+		orchestrator.ResultManaged:                 "前台界面已关闭",
+		orchestrator.ResultManagedExisting:         "前台界面已关闭",
+		orchestrator.ResultCrashLoop:               "反复崩溃，已停止自动重启",
+	},
+	LangEnUS: {
+		orchestrator.ResultEmptyExePath:            "empty executable path",
+		orchestrator.ResultInvalidLaunchTarget:     "invalid launch target",
+		orchestrator.ResultInvalidProcessName:      "invalid process name",
+		orchestrator.ResultStartFailed:             "process start failed",
+		orchestrator.ResultStartedOnly:             "started only",
+		orchestrator.ResultStartedHidden:           "started hidden in background",
+		orchestrator.ResultAlreadyRunningSkipped:   "already running, skipped relaunch",
+		orchestrator.ResultAlreadyRunningManaged:   "already running, managed existing window",
+		orchestrator.ResultNoExpectedProcess:       "cannot tell which process to manage",
+		orchestrator.ResultNoWindowManaged:         enUS.StatusRetryExhausted,
+		orchestrator.ResultNoExistingWindowManaged: enUS.StatusRetryExhausted,
+		orchestrator.ResultManaged:                 "front window closed",
+		orchestrator.ResultManagedExisting:         "front window closed",
+		orchestrator.ResultCrashLoop:               "kept crashing, automatic restarts stopped",
+	},
+}
+
+// ResultText translates code. Unknown codes are returned as is.
+func ResultText(language string, code orchestrator.ResultCode) string {
+	if text, ok := resultTexts[Resolve(language)][code]; ok {
+		return text
+	}
+	return string(code)
+}
+
+// IsLikelyPermissionIssue reports whether result probably failed because
+// WinTray cannot act on a more privileged process's windows (UIPI): either a
+// window action was denied, or matching windows were found but none could be
+// managed.
+func IsLikelyPermissionIssue(result orchestrator.Result) bool {
+	if result.Managed {
+		return false
+	}
+	if errors.Is(result.Err, os.ErrPermission) {
+		return true
+	}
+	switch result.Code {
+	case orchestrator.ResultNoWindowManaged, orchestrator.ResultNoExistingWindowManaged:
+		return result.Details.Candidates > 0
+	}
+	return false
+}
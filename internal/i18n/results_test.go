@@ -0,0 +1,50 @@
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"wintray/internal/orchestrator"
+)
+
+func TestResultTextCoversEveryCode(t *testing.T) {
+	for _, lang := range LanguageOptions() {
+		texts := resultTexts[Lang(lang)]
+		if len(texts) != len(orchestrator.ResultCodes()) {
+			t.Errorf("%s: %d translations for %d codes", lang, len(texts), len(orchestrator.ResultCodes()))
+		}
+		for _, code := range orchestrator.ResultCodes() {
+			if text := texts[code]; text == "" {
+				t.Errorf("%s: no translation for %q", lang, code)
+			}
+		}
+	}
+}
+
+func TestIsLikelyPermissionIssue(t *testing.T) {
+	denied := fmt.Errorf("showwindowasync hide failed: %w", errPermission{})
+	tests := []struct {
+		name   string
+		result orchestrator.Result
+		want   bool
+	}{
+		{"denied", orchestrator.Result{Code: orchestrator.ResultNoWindowManaged, Err: denied}, true},
+		{"candidates not managed", orchestrator.Result{Code: orchestrator.ResultNoExistingWindowManaged, Details: orchestrator.ResultDetails{Candidates: 2}}, true},
+		{"nothing found", orchestrator.Result{Code: orchestrator.ResultNoWindowManaged}, false},
+		{"start failed", orchestrator.Result{Code: orchestrator.ResultStartFailed}, false},
+		{"managed", orchestrator.Result{Managed: true, Code: orchestrator.ResultManaged, Err: denied}, false},
+	}
+	for _, tt := range tests {
+		if got := IsLikelyPermissionIssue(tt.result); got != tt.want {
+			t.Errorf("%s: IsLikelyPermissionIssue = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+// errPermission stands in for ERROR_ACCESS_DENIED, which matches
+// os.ErrPermission on Windows.
+type errPermission struct{}
+
+func (errPermission) Error() string        { return "access is denied" }
+func (errPermission) Is(target error) bool { return target == os.ErrPermission }
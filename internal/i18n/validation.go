@@ -0,0 +1,80 @@
+package i18n
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"wintray/internal/config"
+)
+
+var issueTexts = map[Lang]map[config.IssueCode]string{
+	LangZhCN: {
+		config.IssueDuplicateID:          "ID 与前面的程序重复",
+		config.IssueEmptyTarget:          "未填写启动目标",
+		config.IssueTargetNotFound:       "找不到可执行文件或快捷方式",
+		config.IssueUnknownStrategy:      "未知的窗口匹配策略，不会匹配任何窗口",
+		config.IssueUnknownValue:         "未知的取值，将使用默认值",
+		config.IssueOutOfRange:           "超出允许范围，将被调整",
+		config.IssueEmptyName:            "未填写显示名称",
+		config.IssueHiddenAutoHide:       "隐藏后台启动时没有窗口可隐藏，自动隐藏将被关闭",
+		config.IssueNeedsHiddenLaunch:    "仅在隐藏后台启动时生效",
+		config.IssueProxyWithoutAutoHide: "仅对自动隐藏的窗口显示代理托盘图标",
+		config.IssueNoExpectedProcess:    "URI 或应用 ID 启动需要填写预期进程才能找到窗口",
+	},
+	LangEnUS: {
+		config.IssueDuplicateID:          "ID repeats an earlier program",
+		config.IssueEmptyTarget:          "no launch target",
+		config.IssueTargetNotFound:       "executable or shortcut not found",
+		config.IssueUnknownStrategy:      "unknown window match strategy, no window will match",
+		config.IssueUnknownValue:         "unknown value, the default is used",
+		config.IssueOutOfRange:           "out of range, will be clamped",
+		config.IssueEmptyName:            "no display name",
+		config.IssueHiddenAutoHide:       "a hidden launch has no window to hide, auto-hide is turned off",
+		config.IssueNeedsHiddenLaunch:    "only applies to hidden launches",
+		config.IssueProxyWithoutAutoHide: "proxy tray icons are only added for auto-hidden windows",
+		config.IssueNoExpectedProcess:    "URI and AppUserModelID launches need an expected process to find the window",
+	},
+}
+
+// IssueText translates code. Unknown codes are returned as is.
+func IssueText(language string, code config.IssueCode) string {
+	if text, ok := issueTexts[Resolve(language)][code]; ok {
+		return text
+	}
+	return string(code)
+}
+
+// FormatIssue formats issue as one line naming its severity and field.
+func FormatIssue(language string, issue config.Issue) string {
+	msg := For(language)
+	severity := msg.ValidationWarning
+	if issue.Severity == config.SeverityError {
+		severity = msg.ValidationError
+	}
+	text := IssueText(language, issue.Code)
+	if issue.Value != "" {
+		text += " (" + strconv.Quote(issue.Value) + ")"
+	}
+	return fmt.Sprintf(msg.ValidationIssueLine, severity, issue.Path, text)
+}
+
+// FormatIssues formats issues as a summary line followed by one line per
+// issue.
+func FormatIssues(language string, issues config.Issues) string {
+	msg := For(language)
+	if len(issues) == 0 {
+		return msg.ValidationNone
+	}
+	errs := 0
+	for _, issue := range issues {
+		if issue.Severity == config.SeverityError {
+			errs++
+		}
+	}
+	lines := []string{fmt.Sprintf(msg.ValidationSummary, errs, len(issues)-errs)}
+	for _, issue := range issues {
+		lines = append(lines, FormatIssue(language, issue))
+	}
+	return strings.Join(lines, "\r\n")
+}
@@ -0,0 +1,29 @@
+package i18n
+
+import (
+	"testing"
+
+	"wintray/internal/config"
+)
+
+func TestIssueTextCoversEveryCode(t *testing.T) {
+	for _, lang := range LanguageOptions() {
+		texts := issueTexts[Lang(lang)]
+		if len(texts) != len(config.IssueCodes()) {
+			t.Errorf("%s: %d translations for %d codes", lang, len(texts), len(config.IssueCodes()))
+		}
+		for _, code := range config.IssueCodes() {
+			if text := texts[code]; text == "" {
+				t.Errorf("%s: no translation for %q", lang, code)
+			}
+		}
+	}
+}
+
+func TestFormatIssueNamesSeverityAndField(t *testing.T) {
+	issue := config.Issue{Severity: config.SeverityError, Code: config.IssueUnknownStrategy, Path: "managedApps[0].windowMatch.strategy", Value: "Fuzzy"}
+	want := `Error  managedApps[0].windowMatch.strategy: unknown window match strategy, no window will match ("Fuzzy")`
+	if got := FormatIssue("en-US", issue); got != want {
+		t.Fatalf("FormatIssue = %q, want %q", got, want)
+	}
+}
@@ -1,77 +1,74 @@
 package logging
 
 import (
-	"fmt"
-	"os"
-	"path/filepath"
-	"sync"
-	"time"
+	"log/slog"
 )
 
-// maxLogSize is the maximum log file size before rotation (5 MB).
-const maxLogSize = 5 * 1024 * 1024
+// Options configures a Logger.
+type Options struct {
+	// Level is the minimum level written.
+	Level slog.Level
+	// JSON writes one JSON object per line instead of key=value text.
+	JSON bool
+	// Retention controls rotation of the log file.
+	Retention Retention
+	// Privacy controls how window titles are written. Unless it is
+	// PrivacyOff, ProfileDir is also written as %USERPROFILE%.
+	Privacy Privacy
+	// ProfileDir is the user profile directory; empty uses the home
+	// directory.
+	ProfileDir string
+}
 
+// Logger writes leveled, structured records to wintray.log in the app
+// directory.
 type Logger struct {
-	mu      sync.Mutex
-	file    *os.File
-	appDir  string
-	written int64
+	*slog.Logger
+	level *slog.LevelVar
+	file  *rotatingFile
 }
 
-func New(appDir string) (*Logger, error) {
-	if err := os.MkdirAll(appDir, 0o755); err != nil {
-		return nil, err
-	}
-	path := filepath.Join(appDir, "wintray.log")
-	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+func New(appDir string, opts Options) (*Logger, error) {
+	file, err := openRotatingFile(appDir, opts.Retention)
 	if err != nil {
 		return nil, err
 	}
-	info, _ := f.Stat()
-	var size int64
-	if info != nil {
-		size = info.Size()
+	level := new(slog.LevelVar)
+	level.Set(opts.Level)
+	redact := NewRedactor(opts.Privacy, opts.ProfileDir)
+	handlerOpts := &slog.HandlerOptions{Level: level, ReplaceAttr: redact.replaceAttr}
+	var handler slog.Handler
+	if opts.JSON {
+		handler = slog.NewJSONHandler(file, handlerOpts)
+	} else {
+		handler = slog.NewTextHandler(file, handlerOpts)
 	}
-	return &Logger{file: f, appDir: appDir, written: size}, nil
+	l := &Logger{Logger: slog.New(handler), level: level, file: file}
+	file.report = func(err error) { l.Warn("log rotation failed", "err", err) }
+	return l, nil
 }
 
-func (l *Logger) Close() error {
-	if l == nil || l.file == nil {
-		return nil
+// ParseLevel maps a settings level name ("debug", "info", "warn", "error")
+// to a slog.Level, falling back to Info.
+func ParseLevel(name string) slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return slog.LevelInfo
 	}
-	return l.file.Close()
+	return level
 }
 
-func (l *Logger) Info(msg string)  { l.write("INFO", msg) }
-func (l *Logger) Warn(msg string)  { l.write("WARN", msg) }
-func (l *Logger) Error(msg string) { l.write("ERROR", msg) }
-
-func (l *Logger) write(level, msg string) {
-	if l == nil || l.file == nil {
+// SetLevel changes the minimum level of l and every logger derived from it.
+func (l *Logger) SetLevel(level slog.Level) {
+	if l == nil {
 		return
 	}
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	n, _ := fmt.Fprintf(l.file, "%s [%s] %s\n", time.Now().Format(time.RFC3339), level, msg)
-	l.written += int64(n)
-	if l.written >= maxLogSize {
-		l.rotateUnlocked()
-	}
+	l.level.Set(level)
 }
 
-// rotateUnlocked renames the current log to wintray.log.old and opens a new file.
-// Must be called with l.mu held.
-func (l *Logger) rotateUnlocked() {
-	_ = l.file.Close()
-	logPath := filepath.Join(l.appDir, "wintray.log")
-	oldPath := filepath.Join(l.appDir, "wintray.log.old")
-	_ = os.Remove(oldPath)
-	_ = os.Rename(logPath, oldPath)
-	f, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
-	if err != nil {
-		l.file = nil
-		return
+func (l *Logger) Close() error {
+	if l == nil || l.file == nil {
+		return nil
 	}
-	l.file = f
-	l.written = 0
+	return l.file.Close()
 }
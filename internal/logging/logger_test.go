@@ -0,0 +1,60 @@
+package logging
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoggerJSONLinesAndLevel(t *testing.T) {
+	dir := t.TempDir()
+	logger, err := New(dir, Options{Level: slog.LevelInfo, JSON: true})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	logger.Debug("hidden")
+	logger.Info("started", "entry", "a", "pid", 42)
+	logger.SetLevel(slog.LevelDebug)
+	logger.Debug("visible")
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "wintray.log"))
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("lines = %q, want 2", lines)
+	}
+	var record map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("line %q: %v", lines[0], err)
+	}
+	if record["msg"] != "started" || record["level"] != "INFO" || record["entry"] != "a" || record["pid"] != float64(42) {
+		t.Errorf("record = %v", record)
+	}
+	if !strings.Contains(lines[1], `"msg":"visible"`) {
+		t.Errorf("line 2 = %q, want the debug record after SetLevel", lines[1])
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := map[string]slog.Level{
+		"debug": slog.LevelDebug,
+		"info":  slog.LevelInfo,
+		"warn":  slog.LevelWarn,
+		"error": slog.LevelError,
+		"":      slog.LevelInfo,
+		"loud":  slog.LevelInfo,
+	}
+	for name, want := range tests {
+		if got := ParseLevel(name); got != want {
+			t.Errorf("ParseLevel(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package logging
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// TitleKey is the attribute key, at any group depth, whose value is treated
+// as a window title.
+const TitleKey = "title"
+
+// Privacy selects how window titles are written to the log.
+type Privacy int
+
+const (
+	// PrivacyOff writes titles and paths unchanged.
+	PrivacyOff Privacy = iota
+	// PrivacyTruncate keeps the first truncatedTitleRunes of a title.
+	PrivacyTruncate
+	// PrivacyHash replaces a title with a short hash, so records about the
+	// same window can still be matched up.
+	PrivacyHash
+)
+
+const truncatedTitleRunes = 8
+
+// profileToken replaces the user profile directory in redacted records.
+const profileToken = "%USERPROFILE%"
+
+// Redactor rewrites window titles and user profile paths according to a
+// Privacy mode. Logger passes every attribute through one before it is
+// encoded, so call sites cannot bypass it.
+type Redactor struct {
+	privacy Privacy
+	profile string
+}
+
+// NewRedactor returns a Redactor for privacy; an empty profileDir uses the
+// home directory.
+func NewRedactor(privacy Privacy, profileDir string) Redactor {
+	if profileDir == "" {
+		profileDir, _ = os.UserHomeDir()
+	}
+	return Redactor{privacy: privacy, profile: strings.TrimRight(profileDir, `\/`)}
+}
+
+func (r Redactor) replaceAttr(_ []string, a slog.Attr) slog.Attr {
+	if r.privacy == PrivacyOff {
+		return a
+	}
+	a.Value = a.Value.Resolve()
+	var text string
+	switch a.Value.Kind() {
+	case slog.KindString:
+		text = a.Value.String()
+	case slog.KindAny:
+		switch v := a.Value.Any().(type) {
+		case error:
+			text = v.Error()
+		case fmt.Stringer:
+			text = v.String()
+		default:
+			return a
+		}
+	default:
+		return a
+	}
+	if a.Key == TitleKey {
+		return slog.String(a.Key, r.Title(text))
+	}
+	return slog.String(a.Key, r.Paths(text))
+}
+
+// Title returns title as it may be written under the Privacy mode.
+func (r Redactor) Title(title string) string {
+	if title == "" || r.privacy == PrivacyOff {
+		return title
+	}
+	if r.privacy == PrivacyTruncate {
+		if utf8.RuneCountInString(title) <= truncatedTitleRunes {
+			return title
+		}
+		return string([]rune(title)[:truncatedTitleRunes]) + "…"
+	}
+	sum := sha256.Sum256([]byte(title))
+	return "#" + hex.EncodeToString(sum[:4])
+}
+
+// Paths replaces the profile directory in s with %USERPROFILE%, ignoring
+// case as Windows does. It returns s unchanged under PrivacyOff.
+func (r Redactor) Paths(s string) string {
+	n := len(r.profile)
+	if n < 2 || r.privacy == PrivacyOff {
+		return s
+	}
+	var b strings.Builder
+	last := 0
+	for i := 0; i+n <= len(s); {
+		if strings.EqualFold(s[i:i+n], r.profile) {
+			b.WriteString(s[last:i])
+			b.WriteString(profileToken)
+			i += n
+			last = i
+			continue
+		}
+		i++
+	}
+	if last == 0 {
+		return s
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
@@ -0,0 +1,72 @@
+package logging
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const secretTitle = "Re: Q3 layoffs - Inbox - Outlook"
+
+func logWindow(t *testing.T, opts Options) string {
+	t.Helper()
+	dir := t.TempDir()
+	opts.ProfileDir = `C:\Users\alice`
+	logger, err := New(dir, opts)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	logger.Info(`opened C:\Users\Alice\Documents\report.docx`,
+		slog.Group("window", slog.String("title", secretTitle), slog.Int("pid", 7)),
+		slog.Group("top", slog.Group("1", slog.Group("window", slog.String("title", secretTitle)))),
+		"path", `c:\users\alice\AppData\Local\app.exe`,
+		"err", errors.New(`open C:\Users\alice\secret.txt: access denied`),
+	)
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, logName))
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	// Text and JSON both escape backslashes in quoted values.
+	return strings.ReplaceAll(string(data), `\\`, `\`)
+}
+
+func TestRedactionKeepsTitlesAndProfileOutOfTheFile(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		opts      Options
+		wantTitle string
+	}{
+		{name: "hash text", opts: Options{Privacy: PrivacyHash}, wantTitle: NewRedactor(PrivacyHash, "").Title(secretTitle)},
+		{name: "hash json", opts: Options{Privacy: PrivacyHash, JSON: true}, wantTitle: NewRedactor(PrivacyHash, "").Title(secretTitle)},
+		{name: "truncate", opts: Options{Privacy: PrivacyTruncate, JSON: true}, wantTitle: "Re: Q3 l…"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			out := logWindow(t, tc.opts)
+			if strings.Contains(out, "layoffs") {
+				t.Fatalf("raw title reached the log:\n%s", out)
+			}
+			if strings.Contains(strings.ToLower(out), `users\alice`) {
+				t.Fatalf("profile path reached the log:\n%s", out)
+			}
+			if got := strings.Count(out, tc.wantTitle); got != 2 {
+				t.Errorf("redacted title %q appears %d times, want 2:\n%s", tc.wantTitle, got, out)
+			}
+			if !strings.Contains(out, `%USERPROFILE%`) {
+				t.Errorf("profile token missing:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestRedactionOffWritesRawValues(t *testing.T) {
+	out := logWindow(t, Options{Privacy: PrivacyOff})
+	if !strings.Contains(out, secretTitle) || !strings.Contains(out, `C:\Users\Alice`) {
+		t.Fatalf("raw values missing with privacy off:\n%s", out)
+	}
+}
@@ -0,0 +1,279 @@
+package logging
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const logName = "wintray.log"
+
+// Retention controls when wintray.log is rotated and how many rotated logs
+// are kept. Zero fields use the DefaultRetention value.
+type Retention struct {
+	// MaxSize rotates the log once it reaches this many bytes.
+	MaxSize int64
+	// MaxAge rotates the log once it has been written to for this long.
+	MaxAge time.Duration
+	// Generations is the number of gzipped logs kept; wintray.log.1.gz is
+	// the newest.
+	Generations int
+	// TotalSize caps the combined size of the kept generations; the oldest
+	// are removed first.
+	TotalSize int64
+}
+
+var DefaultRetention = Retention{
+	MaxSize:     5 * 1024 * 1024,
+	MaxAge:      7 * 24 * time.Hour,
+	Generations: 5,
+	TotalSize:   50 * 1024 * 1024,
+}
+
+func (r Retention) normalize() Retention {
+	if r.MaxSize <= 0 {
+		r.MaxSize = DefaultRetention.MaxSize
+	}
+	if r.MaxAge <= 0 {
+		r.MaxAge = DefaultRetention.MaxAge
+	}
+	if r.Generations <= 0 {
+		r.Generations = DefaultRetention.Generations
+	}
+	if r.TotalSize <= 0 {
+		r.TotalSize = DefaultRetention.TotalSize
+	}
+	return r
+}
+
+// retryDelay is how long a failed rotation or reopen waits before the next
+// attempt, so a log held open by another program is not retried on every
+// record.
+const retryDelay = 30 * time.Second
+
+// rotatingFile appends to wintray.log and rotates it into numbered, gzipped
+// generations according to its Retention.
+type rotatingFile struct {
+	mu        sync.Mutex
+	file      *os.File
+	dir       string
+	retention Retention
+	written   int64
+	openedAt  time.Time
+	// retryAt holds off the next rotation or reopen after a failure.
+	retryAt time.Time
+	now     func() time.Time
+	// report receives rotation errors. It is called without mu held and may
+	// write to the file again.
+	report func(error)
+}
+
+func openRotatingFile(dir string, retention Retention) (*rotatingFile, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	r := &rotatingFile{dir: dir, retention: retention.normalize(), now: time.Now}
+	if err := r.openUnlocked(r.now()); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GenerationPath returns the path of rotated log n in dir, counting from 1
+// for the newest.
+func GenerationPath(dir string, n int) string {
+	return filepath.Join(dir, fmt.Sprintf("%s.%d.gz", logName, n))
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	err := r.prepareUnlocked()
+	n := len(p)
+	var writeErr error
+	if r.file != nil {
+		n, writeErr = r.file.Write(p)
+		r.written += int64(n)
+	}
+	report := r.report
+	r.mu.Unlock()
+
+	if err != nil && report != nil {
+		report(err)
+	}
+	return n, writeErr
+}
+
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// prepareUnlocked reopens the log after an earlier failure and rotates it
+// when it is due. Must be called with r.mu held.
+func (r *rotatingFile) prepareUnlocked() error {
+	now := r.now()
+	if now.Before(r.retryAt) {
+		return nil
+	}
+	if r.file == nil {
+		if err := r.openUnlocked(now); err != nil {
+			r.retryAt = now.Add(retryDelay)
+			return err
+		}
+	}
+	if r.written == 0 {
+		return nil
+	}
+	if r.written < r.retention.MaxSize && now.Sub(r.openedAt) < r.retention.MaxAge {
+		return nil
+	}
+	if err := r.rotateUnlocked(now); err != nil {
+		r.retryAt = now.Add(retryDelay)
+		return fmt.Errorf("rotate log: %w", err)
+	}
+	return nil
+}
+
+// startName records when the current wintray.log was started. Its
+// modification time only tells when it was last written, and Windows file
+// system tunneling hands the creation time of a rotated log on to the new
+// file of the same name.
+const startName = logName + ".start"
+
+func (r *rotatingFile) openUnlocked(now time.Time) error {
+	f, err := os.OpenFile(filepath.Join(r.dir, logName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	r.file = f
+	r.written = 0
+	r.openedAt = now
+	if info, statErr := f.Stat(); statErr == nil && info.Size() > 0 {
+		r.written = info.Size()
+		r.openedAt = readLogStart(r.dir, info.ModTime())
+	} else {
+		_ = os.WriteFile(filepath.Join(r.dir, startName), []byte(now.Format(time.RFC3339Nano)), 0o644)
+	}
+	return nil
+}
+
+// readLogStart returns when the log in dir was started. Logs written before
+// the start was recorded fall back to lastWrite.
+func readLogStart(dir string, lastWrite time.Time) time.Time {
+	data, err := os.ReadFile(filepath.Join(dir, startName))
+	if err != nil {
+		return lastWrite
+	}
+	started, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(string(data)))
+	if err != nil || started.After(lastWrite) {
+		return lastWrite
+	}
+	return started
+}
+
+// rotateUnlocked moves wintray.log to generation 1, shifting the older ones,
+// and opens a new log. When the log cannot be moved (it may be open in an
+// editor) the current file is reopened so records keep being written and the
+// older generations are left as they are.
+// Must be called with r.mu held.
+func (r *rotatingFile) rotateUnlocked(now time.Time) error {
+	logPath := filepath.Join(r.dir, logName)
+	pending := logPath + ".1"
+	openedAt := r.openedAt
+	_ = r.file.Close()
+	r.file = nil
+
+	// A previous rotation may have stopped before compressing.
+	if info, err := os.Stat(pending); err == nil && info.Mode().IsRegular() {
+		if _, err = os.Stat(GenerationPath(r.dir, 1)); err == nil {
+			r.shiftGenerations()
+		}
+		_ = archive(pending, GenerationPath(r.dir, 1))
+	}
+
+	if err := os.Rename(logPath, pending); err != nil {
+		if openErr := r.openUnlocked(now); openErr != nil {
+			return errors.Join(err, openErr)
+		}
+		r.openedAt = openedAt
+		return err
+	}
+	r.shiftGenerations()
+	if err := r.openUnlocked(now); err != nil {
+		return err
+	}
+	if err := archive(pending, GenerationPath(r.dir, 1)); err != nil {
+		return err
+	}
+	r.pruneGenerations()
+	return nil
+}
+
+// shiftGenerations renames generation n to n+1, dropping the oldest.
+func (r *rotatingFile) shiftGenerations() {
+	_ = os.Remove(GenerationPath(r.dir, r.retention.Generations))
+	for n := r.retention.Generations - 1; n >= 1; n-- {
+		_ = os.Rename(GenerationPath(r.dir, n), GenerationPath(r.dir, n+1))
+	}
+}
+
+// pruneGenerations removes the oldest generations until the kept ones fit
+// in TotalSize.
+func (r *rotatingFile) pruneGenerations() {
+	sizes := make([]int64, r.retention.Generations+1)
+	var total int64
+	for n := 1; n <= r.retention.Generations; n++ {
+		if info, err := os.Stat(GenerationPath(r.dir, n)); err == nil {
+			sizes[n] = info.Size()
+			total += info.Size()
+		}
+	}
+	for n := r.retention.Generations; n >= 1 && total > r.retention.TotalSize; n-- {
+		if sizes[n] == 0 {
+			continue
+		}
+		if os.Remove(GenerationPath(r.dir, n)) == nil {
+			total -= sizes[n]
+		}
+	}
+}
+
+// archive gzips src into dst and removes src. dst is only replaced once the
+// archive is complete.
+func archive(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	tmp := dst + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	zw.Name = filepath.Base(src)
+	_, err = io.Copy(zw, in)
+	err = errors.Join(err, zw.Close(), out.Close())
+	if err == nil {
+		err = os.Rename(tmp, dst)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	_ = in.Close()
+	return os.Remove(src)
+}
@@ -0,0 +1,181 @@
+package logging
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readGeneration(t *testing.T, dir string, n int) string {
+	t.Helper()
+	f, err := os.Open(GenerationPath(dir, n))
+	if err != nil {
+		t.Fatalf("open generation %d: %v", n, err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("gzip generation %d: %v", n, err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("read generation %d: %v", n, err)
+	}
+	return string(data)
+}
+
+func TestRotatingFileKeepsNumberedGzipGenerations(t *testing.T) {
+	dir := t.TempDir()
+	r, err := openRotatingFile(dir, Retention{MaxSize: 10, Generations: 2})
+	if err != nil {
+		t.Fatalf("openRotatingFile: %v", err)
+	}
+	defer r.Close()
+
+	for _, line := range []string{"first line\n", "second line\n", "third line\n", "fourth line\n"} {
+		if _, err := r.Write([]byte(line)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	if got := readGeneration(t, dir, 1); got != "third line\n" {
+		t.Errorf("generation 1 = %q", got)
+	}
+	if got := readGeneration(t, dir, 2); got != "second line\n" {
+		t.Errorf("generation 2 = %q", got)
+	}
+	if _, err := os.Stat(GenerationPath(dir, 3)); !os.IsNotExist(err) {
+		t.Errorf("generation 3 exists beyond the limit: %v", err)
+	}
+	current, _ := os.ReadFile(filepath.Join(dir, logName))
+	if string(current) != "fourth line\n" {
+		t.Errorf("current log = %q", current)
+	}
+}
+
+func TestRotatingFileRotatesByAgeAndCapsTotalSize(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	r, err := openRotatingFile(dir, Retention{MaxAge: time.Hour, Generations: 5, TotalSize: 1})
+	if err != nil {
+		t.Fatalf("openRotatingFile: %v", err)
+	}
+	defer r.Close()
+	r.now = func() time.Time { return now }
+	r.openedAt = now
+
+	_, _ = r.Write([]byte("morning\n"))
+	now = now.Add(2 * time.Hour)
+	_, _ = r.Write([]byte("later\n"))
+
+	// The archive is larger than TotalSize, so it is pruned straight away.
+	if _, err := os.Stat(GenerationPath(dir, 1)); !os.IsNotExist(err) {
+		t.Errorf("generation 1 kept over the total cap: %v", err)
+	}
+	current, _ := os.ReadFile(filepath.Join(dir, logName))
+	if string(current) != "later\n" {
+		t.Errorf("current log = %q", current)
+	}
+}
+
+func TestRotatingFileKeepsWritingWhenRotationFails(t *testing.T) {
+	dir := t.TempDir()
+	// A non-empty directory in the way makes the rename fail, like a log
+	// held open by another program on Windows.
+	blocker := filepath.Join(dir, logName+".1")
+	if err := os.MkdirAll(filepath.Join(blocker, "x"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	r, err := openRotatingFile(dir, Retention{MaxSize: 5})
+	if err != nil {
+		t.Fatalf("openRotatingFile: %v", err)
+	}
+	defer r.Close()
+	var reported []error
+	r.report = func(err error) { reported = append(reported, err) }
+
+	for _, line := range []string{"one\n", "two\n", "three\n"} {
+		if _, err := r.Write([]byte(line)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	current, _ := os.ReadFile(filepath.Join(dir, logName))
+	if string(current) != "one\ntwo\nthree\n" {
+		t.Errorf("current log = %q, want every line kept", current)
+	}
+	if len(reported) != 1 || !strings.Contains(reported[0].Error(), "rotate log") {
+		t.Errorf("reported = %v, want one rotation error held off by the retry delay", reported)
+	}
+}
+
+func TestRotatingFileKeepsGenerationsWhenRotationFails(t *testing.T) {
+	dir := t.TempDir()
+	for n, text := range map[int]string{1: "newest\n", 2: "oldest\n"} {
+		plain := filepath.Join(dir, "plain")
+		if err := os.WriteFile(plain, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := archive(plain, GenerationPath(dir, n)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	blocker := filepath.Join(dir, logName+".1")
+	if err := os.MkdirAll(filepath.Join(blocker, "x"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	r, err := openRotatingFile(dir, Retention{MaxSize: 5, Generations: 2})
+	if err != nil {
+		t.Fatalf("openRotatingFile: %v", err)
+	}
+	defer r.Close()
+	r.report = func(error) {}
+
+	for _, line := range []string{"one\n", "two\n", "three\n"} {
+		if _, err := r.Write([]byte(line)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	if got := readGeneration(t, dir, 1); got != "newest\n" {
+		t.Errorf("generation 1 = %q, want it left in place", got)
+	}
+	if got := readGeneration(t, dir, 2); got != "oldest\n" {
+		t.Errorf("generation 2 = %q, want it left in place", got)
+	}
+}
+
+func TestRotatingFileMeasuresAgeFromLogStart(t *testing.T) {
+	dir := t.TempDir()
+	r, err := openRotatingFile(dir, Retention{MaxAge: time.Hour})
+	if err != nil {
+		t.Fatalf("openRotatingFile: %v", err)
+	}
+	_, _ = r.Write([]byte("yesterday\n"))
+	_ = r.Close()
+	// The log was started two days ago but written to a moment ago, as by
+	// a session that ran until the last logoff.
+	started := time.Now().Add(-48 * time.Hour)
+	if err = os.WriteFile(filepath.Join(dir, startName), []byte(started.Format(time.RFC3339Nano)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err = openRotatingFile(dir, Retention{MaxAge: time.Hour})
+	if err != nil {
+		t.Fatalf("openRotatingFile: %v", err)
+	}
+	defer r.Close()
+	_, _ = r.Write([]byte("today\n"))
+
+	if got := readGeneration(t, dir, 1); got != "yesterday\n" {
+		t.Errorf("generation 1 = %q, want the old log rotated by age", got)
+	}
+	current, _ := os.ReadFile(filepath.Join(dir, logName))
+	if string(current) != "today\n" {
+		t.Errorf("current log = %q", current)
+	}
+}
@@ -0,0 +1,63 @@
+// Package notify decides what the tray notification after an autorun says
+// and how often it may be shown.
+package notify
+
+import (
+	"time"
+
+	"wintray/internal/config"
+	"wintray/internal/history"
+)
+
+// Entries returns the entries of run that a notification in mode should
+// list, leaving out muted entries. It returns nil when nothing should be
+// shown.
+func Entries(mode config.AutorunNotify, run history.Run, muted func(entryID string) bool) []history.Entry {
+	if mode != config.NotifyFailures && mode != config.NotifySummary {
+		return nil
+	}
+	var out []history.Entry
+	for _, e := range run.Entries {
+		if muted != nil && muted(e.EntryID) {
+			continue
+		}
+		if mode == config.NotifyFailures && e.Managed {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// MutedEntries returns a muted func for Entries backed by settings.
+func MutedEntries(settings config.Settings) func(entryID string) bool {
+	muted := map[string]bool{}
+	for _, entry := range settings.ManagedApps {
+		if entry.MuteNotifications {
+			muted[entry.ID] = true
+		}
+	}
+	return func(entryID string) bool { return muted[entryID] }
+}
+
+// Limiter allows at most Max notifications within Period.
+type Limiter struct {
+	Max    int
+	Period time.Duration
+}
+
+// DefaultLimiter keeps repeated autoruns, such as a crash-looping logon
+// task, from flooding the notification area.
+var DefaultLimiter = Limiter{Max: 3, Period: time.Hour}
+
+// Allow reports whether a notification may be shown at now, given earlier
+// runs; those with Notified set count against the limit.
+func (l Limiter) Allow(runs []history.Run, now time.Time) bool {
+	shown := 0
+	for _, run := range runs {
+		if run.Notified && now.Sub(run.StartedAt) < l.Period && !run.StartedAt.After(now) {
+			shown++
+		}
+	}
+	return shown < l.Max
+}
@@ -0,0 +1,70 @@
+package notify
+
+import (
+	"testing"
+	"time"
+
+	"wintray/internal/config"
+	"wintray/internal/history"
+)
+
+func TestEntries(t *testing.T) {
+	run := history.Run{Entries: []history.Entry{
+		{EntryID: "ok", Managed: true},
+		{EntryID: "failed"},
+		{EntryID: "muted"},
+	}}
+	settings := config.Settings{ManagedApps: []config.ManagedAppEntry{
+		{ID: "ok"}, {ID: "failed"}, {ID: "muted", MuteNotifications: true},
+	}}
+	muted := MutedEntries(settings)
+
+	tests := []struct {
+		mode config.AutorunNotify
+		want []string
+	}{
+		{config.NotifyOff, nil},
+		{config.NotifyFailures, []string{"failed"}},
+		{config.NotifySummary, []string{"ok", "failed"}},
+	}
+	for _, tt := range tests {
+		got := Entries(tt.mode, run, muted)
+		ids := make([]string, 0, len(got))
+		for _, e := range got {
+			ids = append(ids, e.EntryID)
+		}
+		if len(ids) != len(tt.want) {
+			t.Errorf("%s: entries = %v, want %v", tt.mode, ids, tt.want)
+			continue
+		}
+		for i := range ids {
+			if ids[i] != tt.want[i] {
+				t.Errorf("%s: entries = %v, want %v", tt.mode, ids, tt.want)
+				break
+			}
+		}
+	}
+	if got := Entries(config.NotifyFailures, history.Run{Entries: run.Entries[:1]}, muted); got != nil {
+		t.Errorf("failures mode with no failures = %v, want nil", got)
+	}
+}
+
+func TestLimiterAllow(t *testing.T) {
+	now := time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC)
+	l := Limiter{Max: 2, Period: time.Hour}
+	runs := []history.Run{
+		{StartedAt: now.Add(-10 * time.Minute), Notified: true},
+		{StartedAt: now.Add(-20 * time.Minute)},
+		{StartedAt: now.Add(-2 * time.Hour), Notified: true},
+	}
+	if !l.Allow(runs, now) {
+		t.Fatalf("Allow with one recent notification = false, want true")
+	}
+	runs = append(runs, history.Run{StartedAt: now.Add(-50 * time.Minute), Notified: true})
+	if l.Allow(runs, now) {
+		t.Fatalf("Allow with two recent notifications = true, want false")
+	}
+	if !l.Allow(runs, now.Add(15*time.Minute)) {
+		t.Fatalf("Allow after the oldest left the period = false, want true")
+	}
+}
@@ -0,0 +1,133 @@
+package orchestrator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"wintray/internal/config"
+)
+
+// environ is a process environment with Windows semantics: variable names are
+// case-insensitive and keep the spelling they were first defined with.
+type environ struct {
+	vars []string
+}
+
+func newEnviron(base []string) *environ {
+	return &environ{vars: append([]string(nil), base...)}
+}
+
+// index finds name in the environment. Entries such as "=C:=C:\dir" that
+// start with '=' are per-drive state and never match.
+func (e *environ) index(name string) int {
+	for i, kv := range e.vars {
+		if key, _, ok := strings.Cut(kv, "="); ok && key != "" && strings.EqualFold(key, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (e *environ) Lookup(name string) (string, bool) {
+	if i := e.index(name); i >= 0 {
+		_, value, _ := strings.Cut(e.vars[i], "=")
+		return value, true
+	}
+	return "", false
+}
+
+func (e *environ) Set(name, value string) {
+	if i := e.index(name); i >= 0 {
+		key, _, _ := strings.Cut(e.vars[i], "=")
+		e.vars[i] = key + "=" + value
+		return
+	}
+	e.vars = append(e.vars, name+"="+value)
+}
+
+func (e *environ) Unset(name string) {
+	if i := e.index(name); i >= 0 {
+		e.vars = append(e.vars[:i], e.vars[i+1:]...)
+	}
+}
+
+// expandWindowsEnv replaces %NAME% references the way cmd.exe does: unknown
+// variables and lone '%' characters are left untouched.
+func expandWindowsEnv(s string, lookup func(string) (string, bool)) string {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(s, '%')
+		if start < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		end := strings.IndexByte(s[start+1:], '%')
+		if end < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		end += start + 1
+		name := s[start+1 : end]
+		if value, ok := lookup(name); ok && name != "" {
+			b.WriteString(s[:start])
+			b.WriteString(value)
+			s = s[end+1:]
+			continue
+		}
+		// Not a variable: keep the first '%' and rescan from the second, which
+		// may open a valid reference.
+		b.WriteString(s[:end])
+		s = s[end:]
+	}
+}
+
+// buildEnvironment applies overrides to base. Overrides are applied in order
+// and each value is expanded against the environment built so far.
+func buildEnvironment(base []string, overrides []config.EnvOverride) []string {
+	env := newEnviron(base)
+	for _, o := range overrides {
+		value := expandWindowsEnv(o.Value, env.Lookup)
+		switch o.Op {
+		case config.EnvUnset:
+			env.Unset(o.Name)
+		case config.EnvPrependPath:
+			if current, ok := env.Lookup(o.Name); ok && current != "" {
+				value += string(os.PathListSeparator) + current
+			}
+			env.Set(o.Name, value)
+		default:
+			env.Set(o.Name, value)
+		}
+	}
+	return env.vars
+}
+
+// launchEnvironment returns the environment and working directory entry is
+// launched with. A nil environment means WinTray's own is inherited.
+func launchEnvironment(entry config.ManagedAppEntry) ([]string, string, error) {
+	var env []string
+	lookup := os.LookupEnv
+	if len(entry.Env) > 0 {
+		env = buildEnvironment(os.Environ(), entry.Env)
+		lookup = newEnviron(env).Lookup
+	}
+
+	if strings.TrimSpace(entry.WorkingDir) == "" {
+		dir := filepath.Dir(entry.ExePath)
+		if _, err := os.Stat(dir); err != nil {
+			dir = ""
+		}
+		return env, dir, nil
+	}
+	dir := expandWindowsEnv(strings.TrimSpace(entry.WorkingDir), lookup)
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, "", fmt.Errorf("working directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, "", fmt.Errorf("working directory %s is not a directory", dir)
+	}
+	return env, dir, nil
+}
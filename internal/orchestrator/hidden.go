@@ -0,0 +1,254 @@
+package orchestrator
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"wintray/internal/config"
+)
+
+// HiddenWindow is a window WinTray hid with SW_HIDE. Such windows have no way
+// back on their own, so the Service keeps them until they are restored.
+type HiddenWindow struct {
+	Handle           uintptr   `json:"hwnd"`
+	ProcessID        uint32    `json:"pid"`
+	ProcessCreatedAt time.Time `json:"processCreatedAt"`
+	ProcessPath      string    `json:"exePath"`
+	EntryID          string    `json:"entryId"`
+	AppName          string    `json:"appName"`
+	Title            string    `json:"title"`
+	HiddenAt         time.Time `json:"hiddenAt"`
+	// FromPreviousSession marks windows adopted from a persisted registry.
+	FromPreviousSession bool `json:"-"`
+}
+
+// OnHiddenWindowsChanged registers a callback invoked after a window is added
+// to or removed from the hidden-window registry.
+func (s *Service) OnHiddenWindowsChanged(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onHiddenChanged = fn
+}
+
+// HiddenWindows returns the registry ordered by app name, then by hide time.
+func (s *Service) HiddenWindows() []HiddenWindow {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]HiddenWindow, 0, len(s.hidden))
+	for _, h := range s.hidden {
+		list = append(list, h)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].AppName != list[j].AppName {
+			return list[i].AppName < list[j].AppName
+		}
+		return list[i].HiddenAt.Before(list[j].HiddenAt)
+	})
+	return list
+}
+
+// Restore shows a previously hidden window and brings it to the foreground.
+// The handle is dropped from the registry once restored or once it no longer
+// refers to a window.
+func (s *Service) Restore(handle uintptr) error {
+	s.mu.Lock()
+	entry, ok := s.hidden[handle]
+	s.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("window 0x%X is not hidden by WinTray", handle)
+	}
+
+	restored, err := s.manager.RestoreWindow(handle)
+	if err != nil && !errors.Is(err, ErrWindowGone) {
+		s.logger.Warn("restore failed", "entry", entry.EntryID, "app", entry.AppName, hwndAttr("hwnd", handle), "err", err)
+		return err
+	}
+	if restored {
+		s.logger.Info("restored", "entry", entry.EntryID, "app", entry.AppName, hwndAttr("hwnd", handle))
+	} else {
+		s.logger.Info("restore dropped stale window", "entry", entry.EntryID, "app", entry.AppName, hwndAttr("hwnd", handle))
+	}
+	s.forgetHidden(handle)
+	return err
+}
+
+// RestoreAll restores every window in the registry and returns the first error.
+func (s *Service) RestoreAll() error {
+	var firstErr error
+	for _, h := range s.HiddenWindows() {
+		if err := s.Restore(h.Handle); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// AdoptHiddenWindows reconciles a registry persisted by an earlier WinTray
+// session against the live system and adds the windows that are still hidden.
+// Entries whose handle is gone, visible again, or owned by a different process
+// (PID reuse is detected via the process creation time) are pruned. When the
+// creation time is unknown, for example because the process is elevated, only
+// the executable path is compared.
+func (s *Service) AdoptHiddenWindows(persisted []HiddenWindow) []HiddenWindow {
+	adopted := make([]HiddenWindow, 0, len(persisted))
+	for _, h := range persisted {
+		if reason := s.staleReason(h); reason != "" {
+			s.logger.Info("prune persisted hidden window", "entry", h.EntryID, "app", h.AppName, hwndAttr("hwnd", h.Handle), "reason", reason)
+			continue
+		}
+		h.FromPreviousSession = true
+		adopted = append(adopted, h)
+	}
+	if len(adopted) == 0 {
+		return adopted
+	}
+
+	s.mu.Lock()
+	for _, h := range adopted {
+		if _, exists := s.hidden[h.Handle]; !exists {
+			s.hidden[h.Handle] = h
+		}
+	}
+	notify := s.onHiddenChanged
+	s.mu.Unlock()
+
+	if notify != nil {
+		notify()
+	}
+	return adopted
+}
+
+// RestorePreviousSession restores only the windows adopted from a persisted registry.
+func (s *Service) RestorePreviousSession() error {
+	var firstErr error
+	for _, h := range s.HiddenWindows() {
+		if !h.FromPreviousSession {
+			continue
+		}
+		if err := s.Restore(h.Handle); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// PruneHidden drops registry entries whose window no longer exists.
+func (s *Service) PruneHidden() {
+	for _, h := range s.HiddenWindows() {
+		if _, _, ok := s.enumerator.InspectWindow(h.Handle); !ok {
+			s.forgetHidden(h.Handle)
+		}
+	}
+}
+
+func (s *Service) staleReason(h HiddenWindow) string {
+	pid, visible, ok := s.enumerator.InspectWindow(h.Handle)
+	if !ok {
+		return "window gone"
+	}
+	if pid != h.ProcessID {
+		return "handle reused"
+	}
+	if visible {
+		return "visible again"
+	}
+	// The window still belongs to pid, so a failed lookup means the process
+	// cannot be queried rather than that it is gone.
+	identity, _ := s.processes.ProcessIdentity(pid)
+	if !identity.CreatedAt.IsZero() && !h.ProcessCreatedAt.IsZero() && !identity.CreatedAt.Equal(h.ProcessCreatedAt) {
+		return "pid reused"
+	}
+	if h.ProcessPath != "" && identity.Path != "" && !strings.EqualFold(normalizePath(identity.Path), normalizePath(h.ProcessPath)) {
+		return "exe path changed"
+	}
+	return ""
+}
+
+// Rehide hides a window that was previously in the registry again, for example
+// after it was restored from a proxy tray icon. A missing process creation
+// time is looked up so that the next session does not take the entry for a
+// reused PID.
+func (s *Service) Rehide(h HiddenWindow) error {
+	ok, err := s.manager.HideWindow(h.Handle)
+	if !ok {
+		if err == nil {
+			err = fmt.Errorf("hide request for 0x%X was rejected", h.Handle)
+		}
+		return err
+	}
+	if h.ProcessCreatedAt.IsZero() {
+		if identity, ok := s.processes.ProcessIdentity(h.ProcessID); ok {
+			h.ProcessCreatedAt = identity.CreatedAt
+			if h.ProcessPath == "" {
+				h.ProcessPath = identity.Path
+			}
+		}
+	}
+	h.HiddenAt = time.Now()
+	h.FromPreviousSession = false
+	s.mu.Lock()
+	if existing, ok := s.hidden[h.Handle]; ok && existing.ProcessID == h.ProcessID && h.ProcessCreatedAt.IsZero() {
+		h.ProcessCreatedAt = existing.ProcessCreatedAt
+	}
+	s.hidden[h.Handle] = h
+	notify := s.onHiddenChanged
+	s.mu.Unlock()
+
+	if notify != nil {
+		notify()
+	}
+	return nil
+}
+
+// Quit asks a hidden or restored window to close and drops it from the registry.
+func (s *Service) Quit(handle uintptr) error {
+	ok, err := s.manager.CloseWindow(handle)
+	if !ok && err == nil {
+		err = fmt.Errorf("close request for 0x%X was rejected", handle)
+	}
+	s.forgetHidden(handle)
+	return err
+}
+
+func (s *Service) recordHidden(entry config.ManagedAppEntry, window ManagedWindowInfo, handle uintptr) {
+	identity, ok := s.processes.ProcessIdentity(window.ProcessID)
+	if !ok {
+		s.logger.Debug("process identity unavailable", "entry", entry.ID, "app", entry.Name, "pid", window.ProcessID)
+	}
+	path := window.ProcessPath
+	if path == "" {
+		path = identity.Path
+	}
+
+	s.mu.Lock()
+	s.hidden[handle] = HiddenWindow{
+		Handle:           handle,
+		ProcessID:        window.ProcessID,
+		ProcessCreatedAt: identity.CreatedAt,
+		ProcessPath:      path,
+		EntryID:          entry.ID,
+		AppName:          entry.Name,
+		Title:            window.Title,
+		HiddenAt:         time.Now(),
+	}
+	notify := s.onHiddenChanged
+	s.mu.Unlock()
+
+	if notify != nil {
+		notify()
+	}
+}
+
+func (s *Service) forgetHidden(handle uintptr) {
+	s.mu.Lock()
+	delete(s.hidden, handle)
+	notify := s.onHiddenChanged
+	s.mu.Unlock()
+
+	if notify != nil {
+		notify()
+	}
+}
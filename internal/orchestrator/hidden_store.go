@@ -0,0 +1,49 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// HiddenWindowStore persists the hidden-window registry so windows hidden by a
+// session that exited or crashed can be found again on the next start.
+type HiddenWindowStore struct {
+	path string
+}
+
+func NewHiddenWindowStore(path string) *HiddenWindowStore {
+	return &HiddenWindowStore{path: path}
+}
+
+func (s *HiddenWindowStore) Load() ([]HiddenWindow, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var list []HiddenWindow
+	if err = json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *HiddenWindowStore) Save(list []HiddenWindow) error {
+	if len(list) == 0 {
+		if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0o644)
+}
@@ -0,0 +1,46 @@
+package orchestrator
+
+import (
+	"path/filepath"
+	"sort"
+
+	"wintray/internal/config"
+	"wintray/internal/stringutil"
+)
+
+// MinManageScore is the lowest candidate score the Service acts on.
+const MinManageScore = closeAllowedScoreThreshold
+
+// expectedProcess returns the exe path and process name whose windows belong
+// to entry, falling back to ExePath when the launch target is invalid.
+func (s *Service) expectedProcess(entry config.ManagedAppEntry) (string, string) {
+	expectedPath, expectedName := normalizePath(entry.ExePath), stringutil.TrimExt(filepath.Base(entry.ExePath))
+	if target, err := s.launchTarget(entry); err == nil {
+		expectedPath, expectedName = target.ExpectedProcess()
+	}
+	return expectedPath, expectedName
+}
+
+// Windows returns the current visible top-level windows.
+func (s *Service) Windows() []ManagedWindowInfo {
+	return s.enumerator.EnumerateTopLevelWindows()
+}
+
+// ScoreWindows scores windows against entry the way HideExisting would,
+// best first. Windows of other programs and windows that are never managed
+// are left out.
+func (s *Service) ScoreWindows(entry config.ManagedAppEntry, windows []ManagedWindowInfo) []MatchCandidate {
+	expectedPath, expectedName := s.expectedProcess(entry)
+	candidates := make([]MatchCandidate, 0)
+	if expectedName == "" {
+		return candidates
+	}
+	for _, w := range windows {
+		if isUnmanageableWindow(w) || !matchesExecutableWithIdentityFallback(w, expectedPath, expectedName) || !matchStrategy(w, entry.WindowMatch.Strategy) {
+			continue
+		}
+		candidates = append(candidates, MatchCandidate{Window: w, Score: computeCandidateScore(w, expectedPath, expectedName, nil, nil)})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Score > candidates[j].Score })
+	return candidates
+}
@@ -0,0 +1,27 @@
+//go:build !windows
+
+package orchestrator
+
+import "errors"
+
+var errShellUnsupported = errors.New("shell launch requires Windows")
+
+type Win32ShortcutResolver struct{}
+
+func NewWin32ShortcutResolver() *Win32ShortcutResolver { return &Win32ShortcutResolver{} }
+
+func (r *Win32ShortcutResolver) ResolveShortcut(_ string) (Shortcut, error) {
+	return Shortcut{}, errShellUnsupported
+}
+
+type Win32ShellLauncher struct{}
+
+func NewWin32ShellLauncher() *Win32ShellLauncher { return &Win32ShellLauncher{} }
+
+func (l *Win32ShellLauncher) ShellOpen(_ string, _ []string, _ string) (uint32, error) {
+	return 0, errShellUnsupported
+}
+
+func (l *Win32ShellLauncher) ActivateApp(_ string, _ []string) (uint32, error) {
+	return 0, errShellUnsupported
+}
@@ -0,0 +1,198 @@
+package orchestrator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"wintray/internal/cmdline"
+	"wintray/internal/config"
+	"wintray/internal/stringutil"
+)
+
+// LaunchTarget is how one kind of managed entry is started and recognised.
+type LaunchTarget interface {
+	// Start launches the target. It returns a nil process when the launch was
+	// handed to the shell and no process can be tracked.
+	Start(output *os.File) (*os.Process, error)
+	// ExpectedProcess returns the normalized exe path and the process name
+	// used to score candidate windows. Either may be empty.
+	ExpectedProcess() (path, name string)
+	// CanCapture reports whether Start honours hidden launches and output capture.
+	CanCapture() bool
+}
+
+// Shortcut is the launch information stored in a .lnk file.
+type Shortcut struct {
+	Target     string
+	Args       string
+	WorkingDir string
+}
+
+// ShortcutResolver reads the launch information of a .lnk file.
+type ShortcutResolver interface {
+	ResolveShortcut(path string) (Shortcut, error)
+}
+
+// ShellLauncher starts targets that only the Windows shell knows how to open.
+// Both methods return pid 0 when the shell reports no process.
+type ShellLauncher interface {
+	ShellOpen(target string, args []string, dir string) (uint32, error)
+	ActivateApp(appUserModelID string, args []string) (uint32, error)
+}
+
+var errNoShellLauncher = errors.New("shell launch is not available")
+
+// launchTarget builds the LaunchTarget for entry according to its LaunchKind.
+func (s *Service) launchTarget(entry config.ManagedAppEntry) (LaunchTarget, error) {
+	target := strings.Trim(strings.TrimSpace(entry.ExePath), `"`)
+	if target == "" {
+		return nil, errors.New("empty launch target")
+	}
+	kind := entry.LaunchKind
+	if kind == "" {
+		kind = config.InferLaunchKind(target)
+	}
+
+	switch kind {
+	case config.LaunchShortcut:
+		return s.shortcutTarget(entry, target)
+	case config.LaunchURI:
+		return &shellTarget{shell: s.shell, target: target, args: entry.Args, expected: entry.ExpectedProcess}, nil
+	case config.LaunchAppID:
+		aumid := target
+		if len(aumid) > len(config.AppsFolderPrefix) && strings.EqualFold(aumid[:len(config.AppsFolderPrefix)], config.AppsFolderPrefix) {
+			aumid = aumid[len(config.AppsFolderPrefix):]
+		}
+		return &appTarget{shell: s.shell, aumid: aumid, args: entry.Args, expected: entry.ExpectedProcess}, nil
+	default:
+		entry.ExePath = target
+		return newExeTarget(entry)
+	}
+}
+
+// shortcutTarget resolves a .lnk file. Shortcuts to files are started like
+// exe entries so they keep hidden launches, capture and PID tracking;
+// anything else (shell folders, advertised shortcuts) is opened by the shell.
+func (s *Service) shortcutTarget(entry config.ManagedAppEntry, path string) (LaunchTarget, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	if s.shortcuts == nil {
+		return nil, errors.New("shortcut resolution is not available")
+	}
+	link, err := s.shortcuts.ResolveShortcut(path)
+	if err != nil {
+		return nil, fmt.Errorf("resolve shortcut: %w", err)
+	}
+	if info, statErr := os.Stat(link.Target); link.Target == "" || statErr != nil || info.IsDir() {
+		return &shellTarget{shell: s.shell, target: path, args: entry.Args, expected: entry.ExpectedProcess}, nil
+	}
+
+	resolved := entry
+	resolved.ExePath = link.Target
+	resolved.Args = append(cmdline.Split(link.Args), entry.Args...)
+	if strings.TrimSpace(resolved.WorkingDir) == "" {
+		resolved.WorkingDir = link.WorkingDir
+	}
+	return newExeTarget(resolved)
+}
+
+// expectedFromOverride interprets ManagedAppEntry.ExpectedProcess, which may
+// be a full exe path or a bare process name.
+func expectedFromOverride(override string) (string, string) {
+	override = strings.Trim(strings.TrimSpace(override), `"`)
+	if override == "" {
+		return "", ""
+	}
+	name := stringutil.TrimExt(filepath.Base(override))
+	if strings.ContainsAny(override, `\/`) {
+		return normalizePath(override), name
+	}
+	return "", name
+}
+
+type exeTarget struct {
+	entry config.ManagedAppEntry
+}
+
+func newExeTarget(entry config.ManagedAppEntry) (*exeTarget, error) {
+	if _, err := os.Stat(entry.ExePath); err != nil {
+		return nil, err
+	}
+	return &exeTarget{entry: entry}, nil
+}
+
+func (t *exeTarget) Start(output *os.File) (*os.Process, error) {
+	cmd, err := startProcess(t.entry, output)
+	if err != nil {
+		return nil, err
+	}
+	return cmd.Process, nil
+}
+
+func (t *exeTarget) ExpectedProcess() (string, string) {
+	if path, name := expectedFromOverride(t.entry.ExpectedProcess); name != "" {
+		return path, name
+	}
+	return normalizePath(t.entry.ExePath), stringutil.TrimExt(filepath.Base(t.entry.ExePath))
+}
+
+func (t *exeTarget) CanCapture() bool { return true }
+
+// shellTarget opens a URI or shell item with its registered handler.
+type shellTarget struct {
+	shell    ShellLauncher
+	target   string
+	args     []string
+	expected string
+}
+
+func (t *shellTarget) Start(_ *os.File) (*os.Process, error) {
+	if t.shell == nil {
+		return nil, errNoShellLauncher
+	}
+	pid, err := t.shell.ShellOpen(t.target, t.args, "")
+	if err != nil {
+		return nil, err
+	}
+	return findProcess(pid), nil
+}
+
+func (t *shellTarget) ExpectedProcess() (string, string) { return expectedFromOverride(t.expected) }
+func (t *shellTarget) CanCapture() bool                  { return false }
+
+// appTarget activates a packaged app by its AppUserModelID.
+type appTarget struct {
+	shell    ShellLauncher
+	aumid    string
+	args     []string
+	expected string
+}
+
+func (t *appTarget) Start(_ *os.File) (*os.Process, error) {
+	if t.shell == nil {
+		return nil, errNoShellLauncher
+	}
+	pid, err := t.shell.ActivateApp(t.aumid, t.args)
+	if err != nil {
+		return nil, err
+	}
+	return findProcess(pid), nil
+}
+
+func (t *appTarget) ExpectedProcess() (string, string) { return expectedFromOverride(t.expected) }
+func (t *appTarget) CanCapture() bool                  { return false }
+
+func findProcess(pid uint32) *os.Process {
+	if pid == 0 {
+		return nil
+	}
+	p, err := os.FindProcess(int(pid))
+	if err != nil {
+		return nil
+	}
+	return p
+}
@@ -0,0 +1,218 @@
+//go:build windows
+
+package orchestrator
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"wintray/internal/cmdline"
+)
+
+var (
+	ole32                = syscall.NewLazyDLL("ole32.dll")
+	shell32              = syscall.NewLazyDLL("shell32.dll")
+	procCoCreateInstance = ole32.NewProc("CoCreateInstance")
+	procShellExecuteExW  = shell32.NewProc("ShellExecuteExW")
+)
+
+var (
+	clsidShellLink                    = windows.GUID{Data1: 0x00021401, Data4: [8]byte{0xC0, 0, 0, 0, 0, 0, 0, 0x46}}
+	iidShellLinkW                     = windows.GUID{Data1: 0x000214F9, Data4: [8]byte{0xC0, 0, 0, 0, 0, 0, 0, 0x46}}
+	iidPersistFile                    = windows.GUID{Data1: 0x0000010B, Data4: [8]byte{0xC0, 0, 0, 0, 0, 0, 0, 0x46}}
+	clsidApplicationActivationManager = windows.GUID{Data1: 0x45BA127D, Data2: 0x10A8, Data3: 0x46EA, Data4: [8]byte{0x8A, 0xB7, 0x56, 0xEA, 0x90, 0x78, 0x94, 0x3C}}
+	iidApplicationActivationManager   = windows.GUID{Data1: 0x2E941141, Data2: 0x7F97, Data3: 0x4756, Data4: [8]byte{0xBA, 0x1D, 0x9D, 0xEC, 0xDE, 0x89, 0x4A, 0x3D}}
+)
+
+const (
+	clsctxInprocServer = 0x1
+	clsctxLocalServer  = 0x4
+	stgmRead           = 0x0
+	rpcEChangedMode    = 0x80010106
+
+	seeMaskNoCloseProcess = 0x00000040
+	seeMaskNoAsync        = 0x00000100
+	seeMaskFlagNoUI       = 0x00000400
+	swShowNormal          = 1
+
+	// vtable slots after IUnknown's QueryInterface, AddRef and Release.
+	vtblQueryInterface        = 0
+	vtblRelease               = 2
+	shellLinkGetPath          = 3
+	shellLinkGetWorkingDir    = 8
+	shellLinkGetArguments     = 10
+	persistFileLoad           = 5
+	activationManagerActivate = 3
+	shellLinkMaxPath          = 32768
+	win32FindDataWSize        = 592
+)
+
+// shellExecuteInfo mirrors SHELLEXECUTEINFOW.
+type shellExecuteInfo struct {
+	cbSize        uint32
+	fMask         uint32
+	hwnd          uintptr
+	verb          *uint16
+	file          *uint16
+	parameters    *uint16
+	directory     *uint16
+	show          int32
+	instApp       uintptr
+	idList        uintptr
+	class         *uint16
+	keyClass      uintptr
+	hotKey        uint32
+	iconOrMonitor uintptr
+	process       windows.Handle
+}
+
+// Win32ShortcutResolver reads .lnk files through the shell's IShellLinkW.
+type Win32ShortcutResolver struct{}
+
+func NewWin32ShortcutResolver() *Win32ShortcutResolver { return &Win32ShortcutResolver{} }
+
+func (r *Win32ShortcutResolver) ResolveShortcut(path string) (Shortcut, error) {
+	var link Shortcut
+	err := withCOM(func() error {
+		var obj unsafe.Pointer
+		if hr := coCreateInstance(&clsidShellLink, clsctxInprocServer, &iidShellLinkW, &obj); hr != nil {
+			return fmt.Errorf("create ShellLink: %w", hr)
+		}
+		defer comCall(obj, vtblRelease)
+
+		var persist unsafe.Pointer
+		if hr := comCall(obj, vtblQueryInterface, uintptr(unsafe.Pointer(&iidPersistFile)), uintptr(unsafe.Pointer(&persist))); hr != 0 {
+			return fmt.Errorf("query IPersistFile: %w", syscall.Errno(hr))
+		}
+		defer comCall(persist, vtblRelease)
+
+		p, err := windows.UTF16PtrFromString(path)
+		if err != nil {
+			return err
+		}
+		if hr := comCall(persist, persistFileLoad, uintptr(unsafe.Pointer(p)), stgmRead); hr != 0 {
+			return fmt.Errorf("load shortcut: %w", syscall.Errno(hr))
+		}
+
+		buf := make([]uint16, shellLinkMaxPath)
+		var findData [win32FindDataWSize]byte
+		if hr := comCall(obj, shellLinkGetPath, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)), uintptr(unsafe.Pointer(&findData[0])), 0); int32(hr) < 0 {
+			return fmt.Errorf("read shortcut target: %w", syscall.Errno(hr))
+		}
+		link.Target = windows.UTF16ToString(buf)
+		if hr := comCall(obj, shellLinkGetArguments, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf))); int32(hr) >= 0 {
+			link.Args = windows.UTF16ToString(buf)
+		}
+		if hr := comCall(obj, shellLinkGetWorkingDir, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf))); int32(hr) >= 0 {
+			link.WorkingDir = windows.UTF16ToString(buf)
+		}
+		return nil
+	})
+	return link, err
+}
+
+// Win32ShellLauncher starts URIs and shell items with ShellExecuteExW and
+// packaged apps with IApplicationActivationManager.
+type Win32ShellLauncher struct{}
+
+func NewWin32ShellLauncher() *Win32ShellLauncher { return &Win32ShellLauncher{} }
+
+func (l *Win32ShellLauncher) ShellOpen(target string, args []string, dir string) (uint32, error) {
+	var pid uint32
+	err := withCOM(func() error {
+		info := shellExecuteInfo{
+			fMask: seeMaskNoCloseProcess | seeMaskNoAsync | seeMaskFlagNoUI,
+			show:  swShowNormal,
+		}
+		info.cbSize = uint32(unsafe.Sizeof(info))
+		var err error
+		if info.verb, err = windows.UTF16PtrFromString("open"); err != nil {
+			return err
+		}
+		if info.file, err = windows.UTF16PtrFromString(target); err != nil {
+			return err
+		}
+		if len(args) > 0 {
+			if info.parameters, err = windows.UTF16PtrFromString(cmdline.Join(args)); err != nil {
+				return err
+			}
+		}
+		if dir != "" {
+			if info.directory, err = windows.UTF16PtrFromString(dir); err != nil {
+				return err
+			}
+		}
+		ok, _, callErr := procShellExecuteExW.Call(uintptr(unsafe.Pointer(&info)))
+		if ok == 0 {
+			return fmt.Errorf("ShellExecuteEx %s: %w", target, callErr)
+		}
+		if info.process != 0 {
+			pid, _ = windows.GetProcessId(info.process)
+			_ = windows.CloseHandle(info.process)
+		}
+		return nil
+	})
+	return pid, err
+}
+
+func (l *Win32ShellLauncher) ActivateApp(appUserModelID string, args []string) (uint32, error) {
+	var pid uint32
+	err := withCOM(func() error {
+		var obj unsafe.Pointer
+		if hr := coCreateInstance(&clsidApplicationActivationManager, clsctxLocalServer, &iidApplicationActivationManager, &obj); hr != nil {
+			return fmt.Errorf("create ApplicationActivationManager: %w", hr)
+		}
+		defer comCall(obj, vtblRelease)
+
+		id, err := windows.UTF16PtrFromString(appUserModelID)
+		if err != nil {
+			return err
+		}
+		argPtr, err := windows.UTF16PtrFromString(cmdline.Join(args))
+		if err != nil {
+			return err
+		}
+		if hr := comCall(obj, activationManagerActivate, uintptr(unsafe.Pointer(id)), uintptr(unsafe.Pointer(argPtr)), 0, uintptr(unsafe.Pointer(&pid))); int32(hr) < 0 {
+			return fmt.Errorf("activate %s: %w", appUserModelID, syscall.Errno(hr))
+		}
+		return nil
+	})
+	return pid, err
+}
+
+// withCOM runs fn on a locked OS thread with COM initialized as an STA.
+func withCOM(fn func() error) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	err := windows.CoInitializeEx(0, windows.COINIT_APARTMENTTHREADED)
+	switch {
+	case err == nil, errors.Is(err, syscall.Errno(1)): // S_FALSE: already initialized
+		defer windows.CoUninitialize()
+	case errors.Is(err, syscall.Errno(rpcEChangedMode)):
+		// The thread already runs COM in another apartment; use it as is.
+	default:
+		return fmt.Errorf("CoInitializeEx: %w", err)
+	}
+	return fn()
+}
+
+func coCreateInstance(clsid *windows.GUID, context uint32, iid *windows.GUID, obj *unsafe.Pointer) error {
+	hr, _, _ := procCoCreateInstance.Call(uintptr(unsafe.Pointer(clsid)), 0, uintptr(context), uintptr(unsafe.Pointer(iid)), uintptr(unsafe.Pointer(obj)))
+	if int32(hr) < 0 {
+		return syscall.Errno(hr)
+	}
+	return nil
+}
+
+// comCall invokes the method in vtable slot index of the COM object obj.
+func comCall(obj unsafe.Pointer, index int, args ...uintptr) uintptr {
+	vtbl := *(*unsafe.Pointer)(obj)
+	method := *(*uintptr)(unsafe.Add(vtbl, uintptr(index)*unsafe.Sizeof(uintptr(0))))
+	hr, _, _ := syscall.SyscallN(method, append([]uintptr{uintptr(obj)}, args...)...)
+	return hr
+}
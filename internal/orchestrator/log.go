@@ -0,0 +1,80 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"wintray/internal/config"
+)
+
+type logFieldsKey struct{}
+
+// WithLogFields returns a copy of ctx whose Service log records carry args,
+// slog-style key-value pairs, in addition to the fields ctx already carries.
+func WithLogFields(ctx context.Context, args ...any) context.Context {
+	prev, _ := ctx.Value(logFieldsKey{}).([]any)
+	fields := append(append(make([]any, 0, len(prev)+len(args)), prev...), args...)
+	return context.WithValue(ctx, logFieldsKey{}, fields)
+}
+
+// withEntry adds the fields identifying entry to ctx.
+func withEntry(ctx context.Context, entry config.ManagedAppEntry) context.Context {
+	return WithLogFields(ctx, entryAttrs(entry)...)
+}
+
+func entryAttrs(entry config.ManagedAppEntry) []any {
+	return []any{"entry", entry.ID, "app", entry.Name}
+}
+
+// log returns the Service logger with the fields carried by ctx.
+func (s *Service) log(ctx context.Context) Logger {
+	fields, _ := ctx.Value(logFieldsKey{}).([]any)
+	if len(fields) == 0 {
+		return s.logger
+	}
+	return fieldLogger{next: s.logger, fields: fields}
+}
+
+type fieldLogger struct {
+	next   Logger
+	fields []any
+}
+
+func (l fieldLogger) Debug(msg string, args ...any) { l.next.Debug(msg, l.with(args)...) }
+func (l fieldLogger) Info(msg string, args ...any)  { l.next.Info(msg, l.with(args)...) }
+func (l fieldLogger) Warn(msg string, args ...any)  { l.next.Warn(msg, l.with(args)...) }
+func (l fieldLogger) Error(msg string, args ...any) { l.next.Error(msg, l.with(args)...) }
+
+func (l fieldLogger) with(args []any) []any {
+	return append(append(make([]any, 0, len(l.fields)+len(args)), l.fields...), args...)
+}
+
+func hwndAttr(key string, hwnd uintptr) slog.Attr {
+	return slog.String(key, fmt.Sprintf("0x%X", hwnd))
+}
+
+func windowAttr(window ManagedWindowInfo) slog.Attr {
+	return slog.Group("window",
+		hwndAttr("hwnd", window.Handle),
+		slog.Any("pid", window.ProcessID),
+		slog.String("process", window.ProcessName),
+		slog.String("title", window.Title),
+		slog.String("class", window.ClassName),
+		slog.Bool("minimized", window.IsMinimized),
+		slog.Bool("foreground", window.IsForeground),
+		hwndAttr("owner", window.OwnerHandle),
+		slog.Bool("tool", window.IsToolWindow),
+	)
+}
+
+// candidatesAttr groups the best top candidates by rank.
+func candidatesAttr(candidates []MatchCandidate, top int) slog.Attr {
+	top = min(top, len(candidates))
+	attrs := make([]any, 0, top)
+	for i, c := range candidates[:top] {
+		attrs = append(attrs, slog.Group(strconv.Itoa(i+1), slog.Int("score", c.Score), windowAttr(c.Window)))
+	}
+	return slog.Group("top", attrs...)
+}
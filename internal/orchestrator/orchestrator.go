@@ -3,67 +3,85 @@ package orchestrator
 import (
 	"context"
 	"fmt"
-	"os"
-	"path/filepath"
 	"sort"
-	"strings"
 	"time"
 
 	"wintray/internal/config"
-	"wintray/internal/stringutil"
 )
 
 func (s *Service) StartAndManage(ctx context.Context, entry config.ManagedAppEntry, retrySeconds int) Result {
+	ctx = withEntry(ctx, entry)
 	if entry.ExePath == "" {
-		return Result{AppName: entry.Name, Managed: false, Message: "empty exe path"}
+		return Result{AppName: entry.Name, Managed: false, Code: ResultEmptyExePath}
 	}
-	if _, err := os.Stat(entry.ExePath); err != nil {
-		s.logger.Warn(fmt.Sprintf("skip invalid exe path: %s", entry.ExePath))
-		return Result{AppName: entry.Name, Managed: false, Message: "invalid exe path"}
+	target, err := s.launchTarget(entry)
+	if err != nil {
+		s.log(ctx).Warn("skip invalid launch target", "path", entry.ExePath, "kind", entry.LaunchKind, "err", err)
+		return Result{AppName: entry.Name, Managed: false, Code: ResultInvalidLaunchTarget, Err: err}
+	}
+	hidden := entry.LaunchHiddenInBackground
+	if hidden && !target.CanCapture() {
+		s.log(ctx).Warn("hidden launch not supported, launching normally", "kind", entry.LaunchKind)
+		hidden = false
 	}
 
-	expectedName := stringutil.TrimExt(filepath.Base(entry.ExePath))
-	expectedPath := normalizePath(entry.ExePath)
+	expectedPath, expectedName := target.ExpectedProcess()
 	if s.hasExistingManagedWindow(expectedPath, expectedName, entry.WindowMatch.Strategy) {
-		s.logger.Info(fmt.Sprintf("skip start: already running %s", entry.Name))
-		if !entry.LaunchHiddenInBackground && entry.TrayBehavior.AutoMinimizeAndHideOnLaunch {
-			ok := s.manageFirstMatchingWindow(ctx, func(w ManagedWindowInfo) bool {
+		s.log(ctx).Info("skip start: already running")
+		if !hidden && entry.TrayBehavior.AutoMinimizeAndHideOnLaunch {
+			m := s.manageFirstMatchingWindow(ctx, entry, func(w ManagedWindowInfo) bool {
 				return matchesExecutableWithIdentityFallback(w, expectedPath, expectedName) && matchStrategy(w, entry.WindowMatch.Strategy)
 			}, expectedPath, expectedName, nil, nil, retrySeconds, "hide")
-			if ok {
-				return Result{AppName: entry.Name, Managed: true, Action: "hide", Message: "already running managed existing"}
+			if m.ok {
+				return Result{AppName: entry.Name, Managed: true, Action: "hide", Code: ResultAlreadyRunningManaged, Details: m.details}
 			}
 		}
-		return Result{AppName: entry.Name, Managed: true, Message: "already running skipped"}
+		return Result{AppName: entry.Name, Managed: true, Code: ResultAlreadyRunningSkipped}
 	}
 
 	baseline := s.captureBaseline(func(w ManagedWindowInfo) bool {
 		return matchesExecutableWithIdentityFallback(w, expectedPath, expectedName) && matchStrategy(w, entry.WindowMatch.Strategy)
 	})
 
-	cmd, err := startProcess(entry.ExePath, entry.Args, entry.LaunchHiddenInBackground)
+	run, err := s.launch(entry, target)
 	if err != nil {
-		s.logger.Error(fmt.Sprintf("start failed: %s err=%v", entry.Name, err))
-		return Result{AppName: entry.Name, Managed: false, Message: "process start failed"}
+		s.log(ctx).Error("start failed", "err", err)
+		return Result{AppName: entry.Name, Managed: false, Code: ResultStartFailed, Err: err}
 	}
-	pid := uint32(cmd.Process.Pid)
-	s.logger.Info(fmt.Sprintf("started: %s pid=%d hidden=%t", entry.Name, pid, entry.LaunchHiddenInBackground))
+	pid := run.pid()
+	if pid != 0 {
+		run.tracked = s.trackLaunch(entry, pid)
+	}
+	s.log(ctx).Info("started", "kind", entry.LaunchKind, "pid", pid, "hidden", hidden)
 
-	if entry.LaunchHiddenInBackground {
-		return Result{AppName: entry.Name, Managed: true, Message: "started hidden"}
+	if hidden {
+		if entry.Supervise.Enabled {
+			go s.supervise(ctx, entry, target, run)
+		} else {
+			go s.waitLaunched(ctx, entry, run)
+		}
+		return Result{AppName: entry.Name, Managed: true, Code: ResultStartedHidden, OutputPath: run.outputPath, PID: pid}
 	}
 
 	if !entry.TrayBehavior.AutoMinimizeAndHideOnLaunch {
-		return Result{AppName: entry.Name, Managed: true, Message: "started only"}
+		return Result{AppName: entry.Name, Managed: true, Code: ResultStartedOnly}
+	}
+	if pid == 0 && expectedName == "" {
+		s.log(ctx).Warn("cannot manage window without a process id or expected process")
+		return Result{AppName: entry.Name, Managed: false, Code: ResultNoExpectedProcess}
 	}
 
-	ok := s.manageFirstMatchingWindow(ctx, func(w ManagedWindowInfo) bool {
-		return (w.ProcessID == pid || matchesExecutableWithIdentityFallback(w, expectedPath, expectedName)) && matchStrategy(w, entry.WindowMatch.Strategy)
-	}, expectedPath, expectedName, &pid, baseline, retrySeconds, "close")
-	if !ok {
-		return Result{AppName: entry.Name, Managed: false, Message: "no window managed"}
+	var launchedPID *uint32
+	if pid != 0 {
+		launchedPID = &pid
+	}
+	m := s.manageFirstMatchingWindow(ctx, entry, func(w ManagedWindowInfo) bool {
+		return ((pid != 0 && w.ProcessID == pid) || matchesExecutableWithIdentityFallback(w, expectedPath, expectedName)) && matchStrategy(w, entry.WindowMatch.Strategy)
+	}, expectedPath, expectedName, launchedPID, baseline, retrySeconds, "close")
+	if !m.ok {
+		return Result{AppName: entry.Name, Managed: false, Code: ResultNoWindowManaged, Err: m.err, Details: m.details}
 	}
-	return Result{AppName: entry.Name, Managed: true, Action: "close", Message: "managed"}
+	return Result{AppName: entry.Name, Managed: true, Action: "close", Code: ResultManaged, Details: m.details}
 }
 
 func (s *Service) hasExistingManagedWindow(expectedPath, expectedName string, strategy config.MatchStrategy) bool {
@@ -83,31 +101,46 @@ func (s *Service) hasExistingManagedWindow(expectedPath, expectedName string, st
 }
 
 func (s *Service) HideExisting(ctx context.Context, entry config.ManagedAppEntry, retrySeconds int) Result {
-	expectedName := stringutil.TrimExt(filepath.Base(entry.ExePath))
+	ctx = withEntry(ctx, entry)
+	expectedPath, expectedName := s.expectedProcess(entry)
 	if expectedName == "" {
-		return Result{AppName: entry.Name, Managed: false, Message: "invalid process name"}
+		return Result{AppName: entry.Name, Managed: false, Code: ResultInvalidProcessName}
 	}
-	expectedPath := normalizePath(entry.ExePath)
-	ok := s.manageFirstMatchingWindow(ctx, func(w ManagedWindowInfo) bool {
+	m := s.manageFirstMatchingWindow(ctx, entry, func(w ManagedWindowInfo) bool {
 		return matchesExecutableWithIdentityFallback(w, expectedPath, expectedName) && matchStrategy(w, entry.WindowMatch.Strategy)
 	}, expectedPath, expectedName, nil, nil, retrySeconds, "hide")
-	if !ok {
-		return Result{AppName: entry.Name, Managed: false, Message: "no existing window managed"}
+	if !m.ok {
+		return Result{AppName: entry.Name, Managed: false, Code: ResultNoExistingWindowManaged, Err: m.err, Details: m.details}
 	}
-	return Result{AppName: entry.Name, Managed: true, Action: "hide", Message: "managed existing"}
+	return Result{AppName: entry.Name, Managed: true, Action: "hide", Code: ResultManagedExisting, Details: m.details}
+}
+
+// matchOutcome is what manageFirstMatchingWindow reports back for a Result.
+type matchOutcome struct {
+	ok      bool
+	err     error
+	details ResultDetails
 }
 
-func (s *Service) manageFirstMatchingWindow(ctx context.Context, predicate func(ManagedWindowInfo) bool, expectedPath, expectedName string, launchedPID *uint32, baseline map[uintptr]struct{}, retrySeconds int, actionType string) bool {
+func (s *Service) manageFirstMatchingWindow(ctx context.Context, entry config.ManagedAppEntry, predicate func(ManagedWindowInfo) bool, expectedPath, expectedName string, launchedPID *uint32, baseline map[uintptr]struct{}, retrySeconds int, actionType string) matchOutcome {
 	attempts := max(1, max(0, retrySeconds)*2+1)
 	const delay = 500 * time.Millisecond
 	managedAny := false
+	var out matchOutcome
+	start := time.Now()
+	done := func(ok bool) matchOutcome {
+		out.ok = ok
+		out.details.Elapsed = time.Since(start)
+		return out
+	}
 
 	for i := 0; i < attempts; i++ {
 		select {
 		case <-ctx.Done():
-			return false
+			return done(false)
 		default:
 		}
+		out.details.Attempts = i + 1
 
 		windows := s.enumerator.EnumerateTopLevelWindows()
 		bestByRoot := map[uintptr]MatchCandidate{}
@@ -129,15 +162,35 @@ func (s *Service) manageFirstMatchingWindow(ctx context.Context, predicate func(
 			candidates = append(candidates, c)
 		}
 		sort.Slice(candidates, func(i, j int) bool { return candidates[i].Score > candidates[j].Score })
+		out.details.Candidates = max(out.details.Candidates, len(candidates))
+		if len(candidates) > 0 && out.details.FirstWindow == 0 {
+			out.details.FirstWindow = time.Since(start)
+		}
 		if len(candidates) > 0 {
-			s.logger.Info(fmt.Sprintf("match round %d/%d candidates=%d top=%s", i+1, attempts, len(candidates), summarizeCandidates(candidates, 3)))
+			if !managedAny {
+				out.details.Score = max(out.details.Score, candidates[0].Score)
+			}
+			s.log(ctx).Info("match round", "round", i+1, "rounds", attempts, "candidates", len(candidates), candidatesAttr(candidates, 3))
 		}
 
 		managedThisRound := false
 		for _, c := range candidates {
-			if s.tryManageAndVerify(ctx, c.Window, c.Score, actionType) {
+			var attempt actionAttempt
+			ok := s.tryManageAndVerify(ctx, entry, c.Window, c.Score, actionType, &attempt)
+			if attempt.err != nil {
+				out.err = attempt.err
+			}
+			if !managedAny && len(attempt.actions) > 0 {
+				out.details.Actions = attempt.actions
+			}
+			if ok {
+				if !managedAny {
+					out.details.Score = c.Score
+					out.details.Action = time.Since(start)
+					out.details.Window = describeWindow(c.Window)
+				}
 				if actionType != "hide" {
-					return true
+					return done(true)
 				}
 				managedAny = true
 				managedThisRound = true
@@ -148,30 +201,42 @@ func (s *Service) manageFirstMatchingWindow(ctx context.Context, predicate func(
 		if actionType == "hide" {
 			if managedThisRound {
 				if !waitWithContext(ctx, 150*time.Millisecond) {
-					return managedAny
+					return done(managedAny)
 				}
 				continue
 			}
 			if managedAny && len(candidates) == 0 {
-				return true
+				return done(true)
 			}
 		}
 
 		if i < attempts-1 {
 			if !waitWithContext(ctx, delay) {
-				return false
+				return done(false)
 			}
 		}
 	}
-	if actionType == "hide" {
-		return managedAny
+	return done(actionType == "hide" && managedAny)
+}
+
+// actionAttempt records the window requests tried on one candidate.
+type actionAttempt struct {
+	// actions lists the requests sent, in order: "close" or "hide".
+	actions []string
+	// err is the last error a request returned.
+	err error
+}
+
+func (a *actionAttempt) record(action string, err error) {
+	a.actions = append(a.actions, action)
+	if err != nil {
+		a.err = err
 	}
-	return false
 }
 
-func (s *Service) tryManageAndVerify(ctx context.Context, window ManagedWindowInfo, score int, actionType string) bool {
+func (s *Service) tryManageAndVerify(ctx context.Context, entry config.ManagedAppEntry, window ManagedWindowInfo, score int, actionType string, attempt *actionAttempt) bool {
 	if score < closeAllowedScoreThreshold {
-		s.logger.Warn(fmt.Sprintf("skip low confidence candidate score=%d threshold=%d %s", score, closeAllowedScoreThreshold, describeWindow(window)))
+		s.log(ctx).Warn("skip low confidence candidate", "score", score, "threshold", closeAllowedScoreThreshold, windowAttr(window))
 		return false
 	}
 
@@ -191,42 +256,54 @@ func (s *Service) tryManageAndVerify(ctx context.Context, window ManagedWindowIn
 	if actionType == "hide" {
 		// Prefer app-native close-to-tray behavior first. Many apps (Tauri/Electron)
 		// intercept close and move to tray, preserving tray-click restore semantics.
-		if s.applyAndVerify(ctx, window, score, "hide", s.manager.CloseWindow) {
+		ok, err := s.applyAndVerify(ctx, window, score, "hide", s.manager.CloseWindow)
+		attempt.record("close", err)
+		if ok {
 			return true
 		}
-		return s.applyAndVerify(ctx, window, score, "hide", s.manager.HideWindow)
+		return s.hideAndRecord(ctx, entry, window, score, attempt)
 	}
-	if s.applyAndVerify(ctx, window, score, "close", s.manager.CloseWindow) {
+	ok, err := s.applyAndVerify(ctx, window, score, "close", s.manager.CloseWindow)
+	attempt.record("close", err)
+	if ok {
 		return true
 	}
-	s.logger.Info(fmt.Sprintf("close fallback to hide score=%d %s", score, describeWindow(window)))
-	return s.applyAndVerify(ctx, window, score, "hide", s.manager.HideWindow)
+	s.log(ctx).Info("close fallback to hide", "score", score, windowAttr(window))
+	return s.hideAndRecord(ctx, entry, window, score, attempt)
+}
+
+// hideAndRecord applies SW_HIDE and remembers the window so it can be restored;
+// windows that merely closed to their own tray icon are not recorded.
+func (s *Service) hideAndRecord(ctx context.Context, entry config.ManagedAppEntry, window ManagedWindowInfo, score int, attempt *actionAttempt) bool {
+	ok, err := s.applyAndVerify(ctx, window, score, "hide", s.manager.HideWindow)
+	attempt.record("hide", err)
+	if !ok {
+		return false
+	}
+	s.recordHidden(entry, window, resolveActionTargetHandle(window))
+	return true
 }
 
-func (s *Service) applyAndVerify(ctx context.Context, window ManagedWindowInfo, score int, action string, fn func(uintptr) (bool, error)) bool {
+func (s *Service) applyAndVerify(ctx context.Context, window ManagedWindowInfo, score int, action string, fn func(uintptr) (bool, error)) (bool, error) {
 	targetHwnd := resolveActionTargetHandle(window)
 	if targetHwnd != window.Handle {
-		s.logger.Info(fmt.Sprintf("retarget action action=%s score=%d from=0x%X to=0x%X", action, score, window.Handle, targetHwnd))
+		s.log(ctx).Debug("retarget action", "action", action, "score", score, hwndAttr("from", window.Handle), hwndAttr("to", targetHwnd))
 	}
 
 	ok, err := fn(targetHwnd)
 	if !ok {
-		if err != nil {
-			s.logger.Warn(fmt.Sprintf("action request failed action=%s score=%d hwnd=0x%X %s err=%v", action, score, targetHwnd, describeWindow(window), err))
-		} else {
-			s.logger.Warn(fmt.Sprintf("action request failed action=%s score=%d hwnd=0x%X %s", action, score, targetHwnd, describeWindow(window)))
-		}
-		return false
+		s.log(ctx).Warn("action request failed", "action", action, "score", score, hwndAttr("hwnd", targetHwnd), windowAttr(window), "err", err)
+		return false, err
 	}
 
-	s.logger.Info(fmt.Sprintf("action requested action=%s score=%d hwnd=0x%X %s", action, score, targetHwnd, describeWindow(window)))
+	s.log(ctx).Debug("action requested", "action", action, "score", score, hwndAttr("hwnd", targetHwnd), windowAttr(window))
 	if s.verifyActionApplied(ctx, targetHwnd, score, action) {
-		s.logger.Info(fmt.Sprintf("action applied action=%s score=%d hwnd=0x%X", action, score, targetHwnd))
-		return true
+		s.log(ctx).Info("action applied", "action", action, "score", score, hwndAttr("hwnd", targetHwnd))
+		return true, nil
 	}
 
-	s.logger.Warn(fmt.Sprintf("action not applied action=%s score=%d hwnd=0x%X", action, score, targetHwnd))
-	return false
+	s.log(ctx).Warn("action not applied", "action", action, "score", score, hwndAttr("hwnd", targetHwnd))
+	return false, nil
 }
 
 func resolveActionTargetHandle(window ManagedWindowInfo) uintptr {
@@ -285,29 +362,11 @@ func (s *Service) verifyActionApplied(ctx context.Context, hwnd uintptr, score i
 	}
 
 	if score >= closeAllowedScoreThreshold {
-		s.logger.Warn(fmt.Sprintf("verify timeout action=%s score=%d hwnd=0x%X", action, score, hwnd))
+		s.log(ctx).Warn("verify timeout", "action", action, "score", score, hwndAttr("hwnd", hwnd))
 	}
 	return false
 }
 
-func summarizeCandidates(candidates []MatchCandidate, top int) string {
-	if len(candidates) == 0 {
-		return "none"
-	}
-	if top <= 0 {
-		top = 1
-	}
-	if len(candidates) < top {
-		top = len(candidates)
-	}
-	parts := make([]string, 0, top)
-	for i := 0; i < top; i++ {
-		c := candidates[i]
-		parts = append(parts, fmt.Sprintf("score=%d %s", c.Score, describeWindow(c.Window)))
-	}
-	return strings.Join(parts, "; ")
-}
-
 func describeWindow(window ManagedWindowInfo) string {
 	title := window.Title
 	if title == "" {
@@ -1,7 +1,20 @@
 package orchestrator
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"wintray/internal/config"
 )
@@ -230,28 +243,782 @@ func TestMatchStrategy(t *testing.T) {
 	}
 }
 
-func TestParseArgs(t *testing.T) {
+func TestRestartTrackerBackoffAndBudget(t *testing.T) {
+	tracker := newRestartTracker(3, time.Minute)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	wantDelays := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}
+	for i, want := range wantDelays {
+		got, ok := tracker.next(start.Add(time.Duration(i) * time.Second))
+		if !ok {
+			t.Fatalf("restart %d rejected, want allowed", i)
+		}
+		if got != want {
+			t.Fatalf("restart %d delay = %s, want %s", i, got, want)
+		}
+	}
+	if _, ok := tracker.next(start.Add(10 * time.Second)); ok {
+		t.Fatal("restart beyond budget allowed, want crash loop")
+	}
+
+	// Once the window has slid past the earlier restarts the budget recovers
+	// and backoff starts over.
+	got, ok := tracker.next(start.Add(2 * time.Minute))
+	if !ok {
+		t.Fatal("restart after window rejected, want allowed")
+	}
+	if got != time.Second {
+		t.Fatalf("delay after window = %s, want %s", got, time.Second)
+	}
+}
+
+func TestRestartTrackerBackoffCapped(t *testing.T) {
+	tracker := newRestartTracker(20, time.Hour)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var last time.Duration
+	for i := 0; i < 10; i++ {
+		last, _ = tracker.next(now)
+	}
+	if last != superviseMaxBackoff {
+		t.Fatalf("delay = %s, want cap %s", last, superviseMaxBackoff)
+	}
+}
+
+type fakeEnumerator struct {
+	windows   []ManagedWindowInfo
+	inspected map[uintptr]fakeWindowState
+}
+
+type fakeWindowState struct {
+	pid     uint32
+	visible bool
+}
+
+func (e *fakeEnumerator) EnumerateTopLevelWindows() []ManagedWindowInfo { return e.windows }
+
+func (e *fakeEnumerator) InspectWindow(hwnd uintptr) (uint32, bool, bool) {
+	st, ok := e.inspected[hwnd]
+	return st.pid, st.visible, ok
+}
+
+type fakeManager struct {
+	closed   []uintptr
+	hidden   []uintptr
+	restored []uintptr
+	gone     map[uintptr]bool
+	onClose  func(hwnd uintptr)
+	// failErr, when set, fails every close and hide request.
+	failErr error
+}
+
+func (m *fakeManager) CloseWindow(hwnd uintptr) (bool, error) {
+	m.closed = append(m.closed, hwnd)
+	if m.failErr != nil {
+		return false, m.failErr
+	}
+	if m.onClose != nil {
+		m.onClose(hwnd)
+	}
+	return true, nil
+}
+
+func (m *fakeManager) HideWindow(hwnd uintptr) (bool, error) {
+	m.hidden = append(m.hidden, hwnd)
+	if m.failErr != nil {
+		return false, m.failErr
+	}
+	return true, nil
+}
+
+func (m *fakeManager) MinimizeWindow(_ uintptr) (bool, error) { return true, nil }
+
+func (m *fakeManager) RestoreWindow(hwnd uintptr) (bool, error) {
+	if m.gone[hwnd] {
+		return false, ErrWindowGone
+	}
+	m.restored = append(m.restored, hwnd)
+	return true, nil
+}
+
+type fakeProcesses struct {
+	running    map[uint32]bool
+	terminated []uint32
+	identities map[uint32]ProcessIdentity
+}
+
+func (p *fakeProcesses) IsProcessRunning(pid uint32) bool { return p.running[pid] }
+
+func (p *fakeProcesses) ProcessIdentity(pid uint32) (ProcessIdentity, bool) {
+	id, ok := p.identities[pid]
+	return id, ok
+}
+
+func (p *fakeProcesses) TerminateProcessTree(pid uint32) error {
+	p.terminated = append(p.terminated, pid)
+	p.running[pid] = false
+	return nil
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...any) {}
+func (nopLogger) Info(string, ...any)  {}
+func (nopLogger) Warn(string, ...any)  {}
+func (nopLogger) Error(string, ...any) {}
+
+func TestStopManagedAppsAppliesPolicyInReverseOrder(t *testing.T) {
+	procs := &fakeProcesses{running: map[uint32]bool{10: true, 20: true, 30: true}}
+	enum := &fakeEnumerator{windows: []ManagedWindowInfo{
+		{Handle: 0x100, ProcessID: 10},
+		{Handle: 0x200, ProcessID: 20},
+	}}
+	mgr := &fakeManager{onClose: func(hwnd uintptr) {
+		// App 1 honours WM_CLOSE, app 2 ignores it.
+		if hwnd == 0x100 {
+			procs.running[10] = false
+		}
+	}}
+	svc := NewService(enum, mgr, nopLogger{})
+	svc.processes = procs
+
+	entries := []config.ManagedAppEntry{
+		{ID: "a", Name: "A", StopPolicy: config.StopClose},
+		{ID: "b", Name: "B", StopPolicy: config.StopCloseThenKill},
+		{ID: "c", Name: "C", StopPolicy: config.StopNever},
+		{ID: "d", Name: "D", StopPolicy: config.StopClose},
+	}
+	svc.trackLaunch(entries[0], 10)
+	svc.trackLaunch(entries[1], 20)
+	never := svc.trackLaunch(entries[2], 30)
+
+	results := svc.StopManagedApps(context.Background(), entries, 10*time.Millisecond)
+
+	if len(results) != 3 {
+		t.Fatalf("results = %d, want 3 (untracked entry skipped)", len(results))
+	}
+	if results[0].AppName != "C" || !results[0].Skipped {
+		t.Fatalf("results[0] = %+v, want C skipped", results[0])
+	}
+	if results[1].AppName != "B" || !results[1].Killed {
+		t.Fatalf("results[1] = %+v, want B killed", results[1])
+	}
+	if results[2].AppName != "A" || !results[2].Closed || results[2].Killed {
+		t.Fatalf("results[2] = %+v, want A closed", results[2])
+	}
+	if len(procs.terminated) != 1 || procs.terminated[0] != 20 {
+		t.Fatalf("terminated = %v, want [20]", procs.terminated)
+	}
+	if !procs.running[30] {
+		t.Fatal("never-stop entry was stopped")
+	}
+	if never.stopping {
+		t.Fatal("never-stop entry is marked stopping and would not be restarted")
+	}
+}
+
+func TestStopManagedAppsFailedStopKeepsSupervision(t *testing.T) {
+	procs := &fakeProcesses{running: map[uint32]bool{10: true}}
+	svc := NewService(&fakeEnumerator{}, &fakeManager{}, nopLogger{})
+	svc.processes = procs
+	entry := config.ManagedAppEntry{ID: "a", Name: "A", StopPolicy: config.StopClose}
+	tracked := svc.trackLaunch(entry, 10)
+
+	results := svc.StopManagedApps(context.Background(), []config.ManagedAppEntry{entry}, time.Millisecond)
+
+	if len(results) != 1 || results[0].Err == nil {
+		t.Fatalf("results = %+v, want a failed stop", results)
+	}
+	if tracked.stopping {
+		t.Fatal("entry whose stop failed is still marked stopping")
+	}
+}
+
+// TestHelperProcess is not a test: it is the process the supervisor tests
+// launch, which runs until it is killed.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("WINTRAY_TEST_HELPER") != "1" {
+		return
+	}
+	time.Sleep(time.Minute)
+	os.Exit(0)
+}
+
+// helperTarget launches TestHelperProcess.
+type helperTarget struct {
+	mu     sync.Mutex
+	starts []*os.Process
+}
+
+func (h *helperTarget) Start(_ *os.File) (*os.Process, error) {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestHelperProcess$")
+	cmd.Env = append(os.Environ(), "WINTRAY_TEST_HELPER=1")
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.starts = append(h.starts, cmd.Process)
+	return cmd.Process, nil
+}
+
+func (h *helperTarget) ExpectedProcess() (string, string) { return "", "" }
+func (h *helperTarget) CanCapture() bool                  { return false }
+
+// killingProcesses kills the helper processes of a helperTarget.
+type killingProcesses struct {
+	target *helperTarget
+	mu     sync.Mutex
+	killed map[uint32]bool
+}
+
+func (p *killingProcesses) IsProcessRunning(pid uint32) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return !p.killed[pid]
+}
+
+func (p *killingProcesses) ProcessIdentity(uint32) (ProcessIdentity, bool) {
+	return ProcessIdentity{}, false
+}
+
+func (p *killingProcesses) TerminateProcessTree(pid uint32) error {
+	p.target.mu.Lock()
+	defer p.target.mu.Unlock()
+	for _, process := range p.target.starts {
+		if uint32(process.Pid) == pid {
+			p.mu.Lock()
+			p.killed[pid] = true
+			p.mu.Unlock()
+			return process.Kill()
+		}
+	}
+	return fmt.Errorf("pid %d was not started", pid)
+}
+
+func TestStopManagedAppsStopsSupervisedProcessForGood(t *testing.T) {
+	target := &helperTarget{}
+	svc := NewService(&fakeEnumerator{}, &fakeManager{}, nopLogger{})
+	svc.processes = &killingProcesses{target: target, killed: map[uint32]bool{}}
+	entry := config.ManagedAppEntry{
+		ID: "a", Name: "A", StopPolicy: config.StopCloseThenKill, LaunchHiddenInBackground: true,
+		Supervise: config.SupervisePolicy{Enabled: true, MaxRestarts: 3, WindowSeconds: 60},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer func() {
+		for _, process := range target.starts {
+			_ = process.Kill()
+		}
+	}()
+
+	run, err := svc.launch(entry, target)
+	if err != nil {
+		t.Fatalf("launch: %v", err)
+	}
+	run.tracked = svc.trackLaunch(entry, run.pid())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		svc.supervise(ctx, entry, target, run)
+	}()
+
+	results := svc.StopManagedApps(ctx, []config.ManagedAppEntry{entry}, 0)
+	if len(results) != 1 || !results[0].Killed {
+		t.Fatalf("results = %+v, want the supervised process killed", results)
+	}
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("supervisor still running after the process was stopped")
+	}
+	target.mu.Lock()
+	defer target.mu.Unlock()
+	if len(target.starts) != 1 {
+		t.Fatalf("started %d times, want no restart after the stop", len(target.starts))
+	}
+}
+
+func TestLogFieldsFollowContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	procs := &fakeProcesses{running: map[uint32]bool{}}
+	svc := NewService(&fakeEnumerator{}, &fakeManager{}, logger)
+	svc.processes = procs
+
+	entry := config.ManagedAppEntry{ID: "a", Name: "A", StopPolicy: config.StopClose}
+	svc.trackLaunch(entry, 10)
+	ctx := WithLogFields(context.Background(), "run", "r1")
+	svc.StopManagedApps(ctx, []config.ManagedAppEntry{entry}, time.Millisecond)
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("log output %q: %v", buf.String(), err)
+	}
+	want := map[string]any{"msg": "stop: already exited", "run": "r1", "entry": "a", "app": "A", "pid": float64(10)}
+	for k, v := range want {
+		if record[k] != v {
+			t.Errorf("%s = %v, want %v", k, record[k], v)
+		}
+	}
+}
+
+func TestProcessTreeOrderChildrenFirst(t *testing.T) {
+	start := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	at := func(parent uint32, minutes int) processNode {
+		return processNode{parent: parent, created: start.Add(time.Duration(minutes) * time.Minute)}
+	}
+	procs := map[uint32]processNode{
+		0: at(0, 0),
+		1: at(0, 1),
+		2: at(1, 2),
+		3: at(2, 3),
+		4: at(1, 4),
+		5: at(99, 5),
+		// 6 claims 1 as its parent but is older: its real parent exited
+		// and 1 reused that PID.
+		6: at(1, 0),
+		// 7 is a child of 6, so it is not in the tree either.
+		7: at(6, 6),
+	}
+	order := processTreeOrder(1, procs)
+	if len(order) != 4 || order[len(order)-1] != 1 {
+		t.Fatalf("order = %v, want 4 pids ending with root", order)
+	}
+	pos := map[uint32]int{}
+	for i, pid := range order {
+		pos[pid] = i
+	}
+	if pos[3] > pos[2] {
+		t.Fatalf("grandchild after child in %v", order)
+	}
+	for _, pid := range []uint32{5, 6, 7} {
+		if _, ok := pos[pid]; ok {
+			t.Fatalf("unrelated process %d included in %v", pid, order)
+		}
+	}
+}
+
+func TestHiddenWindowRegistryRestore(t *testing.T) {
+	mgr := &fakeManager{gone: map[uintptr]bool{0x300: true}}
+	svc := NewService(&fakeEnumerator{}, mgr, nopLogger{})
+	changes := 0
+	svc.OnHiddenWindowsChanged(func() { changes++ })
+
+	svc.recordHidden(config.ManagedAppEntry{Name: "B"}, ManagedWindowInfo{ProcessID: 2, Title: "b"}, 0x200)
+	svc.recordHidden(config.ManagedAppEntry{Name: "A"}, ManagedWindowInfo{ProcessID: 1, Title: "a"}, 0x100)
+	svc.recordHidden(config.ManagedAppEntry{Name: "A"}, ManagedWindowInfo{ProcessID: 1, Title: "gone"}, 0x300)
+
+	hidden := svc.HiddenWindows()
+	if len(hidden) != 3 || hidden[0].AppName != "A" || hidden[2].AppName != "B" {
+		t.Fatalf("HiddenWindows = %+v, want sorted by app", hidden)
+	}
+
+	if err := svc.Restore(0x100); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if err := svc.Restore(0x100); err == nil {
+		t.Fatal("second Restore of same handle succeeded, want error")
+	}
+
+	err := svc.RestoreAll()
+	if !errors.Is(err, ErrWindowGone) {
+		t.Fatalf("RestoreAll err = %v, want ErrWindowGone for stale handle", err)
+	}
+	if left := svc.HiddenWindows(); len(left) != 0 {
+		t.Fatalf("registry not empty after RestoreAll: %+v", left)
+	}
+	if len(mgr.restored) != 2 {
+		t.Fatalf("restored = %v, want 2 live windows", mgr.restored)
+	}
+	if changes != 6 {
+		t.Fatalf("change notifications = %d, want 6", changes)
+	}
+}
+
+func TestAdoptHiddenWindowsPrunesStaleEntries(t *testing.T) {
+	created := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	enum := &fakeEnumerator{inspected: map[uintptr]fakeWindowState{
+		0x1: {pid: 100},
+		0x2: {pid: 200},
+		0x3: {pid: 300, visible: true},
+		0x4: {pid: 999},
+	}}
+	procs := &fakeProcesses{identities: map[uint32]ProcessIdentity{
+		100: {Path: `C:\Apps\a.exe`, CreatedAt: created},
+		200: {Path: `C:\Apps\b.exe`, CreatedAt: created.Add(time.Hour)},
+		300: {Path: `C:\Apps\c.exe`, CreatedAt: created},
+	}}
+	svc := NewService(enum, &fakeManager{}, nopLogger{})
+	svc.processes = procs
+
+	persisted := []HiddenWindow{
+		{Handle: 0x1, ProcessID: 100, ProcessCreatedAt: created, ProcessPath: `C:\Apps\a.exe`, AppName: "A"},
+		{Handle: 0x2, ProcessID: 200, ProcessCreatedAt: created, AppName: "pid reused"},
+		{Handle: 0x3, ProcessID: 300, ProcessCreatedAt: created, AppName: "visible again"},
+		{Handle: 0x4, ProcessID: 400, ProcessCreatedAt: created, AppName: "handle reused"},
+		{Handle: 0x5, ProcessID: 500, ProcessCreatedAt: created, AppName: "window gone"},
+	}
+	adopted := svc.AdoptHiddenWindows(persisted)
+
+	if len(adopted) != 1 || adopted[0].Handle != 0x1 || !adopted[0].FromPreviousSession {
+		t.Fatalf("adopted = %+v, want only hwnd 0x1 from previous session", adopted)
+	}
+	if hidden := svc.HiddenWindows(); len(hidden) != 1 {
+		t.Fatalf("registry = %+v, want 1 entry", hidden)
+	}
+}
+
+func TestAdoptHiddenWindowsWithoutCreationTimeComparesPaths(t *testing.T) {
+	created := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	enum := &fakeEnumerator{inspected: map[uintptr]fakeWindowState{
+		0x1: {pid: 100},
+		0x2: {pid: 200},
+		0x3: {pid: 300},
+	}}
+	// 100 is elevated: its identity cannot be read in either session.
+	procs := &fakeProcesses{identities: map[uint32]ProcessIdentity{
+		200: {Path: `C:\Apps\b.exe`, CreatedAt: created},
+		300: {Path: `C:\Apps\other.exe`, CreatedAt: created},
+	}}
+	svc := NewService(enum, &fakeManager{}, nopLogger{})
+	svc.processes = procs
+	svc.recordHidden(config.ManagedAppEntry{Name: "Elevated"}, ManagedWindowInfo{ProcessID: 100, ProcessPath: `C:\Apps\a.exe`}, 0x1)
+	recorded := svc.HiddenWindows()
+	if len(recorded) != 1 || !recorded[0].ProcessCreatedAt.IsZero() {
+		t.Fatalf("registry = %+v, want one entry without creation time", recorded)
+	}
+
+	persisted := append(recorded,
+		HiddenWindow{Handle: 0x2, ProcessID: 200, ProcessPath: `C:\Apps\b.exe`, AppName: "same path"},
+		HiddenWindow{Handle: 0x3, ProcessID: 300, ProcessPath: `C:\Apps\c.exe`, AppName: "exe path changed"},
+	)
+	next := NewService(enum, &fakeManager{}, nopLogger{})
+	next.processes = procs
+	adopted := next.AdoptHiddenWindows(persisted)
+
+	if len(adopted) != 2 || adopted[0].Handle != 0x1 || adopted[1].Handle != 0x2 {
+		t.Fatalf("adopted = %+v, want hwnd 0x1 and 0x2", adopted)
+	}
+}
+
+func TestRehideKeepsProcessCreationTime(t *testing.T) {
+	created := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	enum := &fakeEnumerator{inspected: map[uintptr]fakeWindowState{0x1: {pid: 100}}}
+	procs := &fakeProcesses{identities: map[uint32]ProcessIdentity{
+		100: {Path: `C:\Apps\a.exe`, CreatedAt: created},
+	}}
+	svc := NewService(enum, &fakeManager{}, nopLogger{})
+	svc.processes = procs
+
+	// A proxy tray icon only knows the handle, PID and names.
+	if err := svc.Rehide(HiddenWindow{Handle: 0x1, ProcessID: 100, AppName: "A"}); err != nil {
+		t.Fatalf("Rehide: %v", err)
+	}
+	hidden := svc.HiddenWindows()
+	if len(hidden) != 1 || !hidden[0].ProcessCreatedAt.Equal(created) {
+		t.Fatalf("registry = %+v, want creation time %v", hidden, created)
+	}
+
+	next := NewService(enum, &fakeManager{}, nopLogger{})
+	next.processes = procs
+	if adopted := next.AdoptHiddenWindows(hidden); len(adopted) != 1 {
+		t.Fatalf("rehidden window pruned by the next session")
+	}
+}
+
+func TestHiddenWindowStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hidden-windows.json")
+	store := NewHiddenWindowStore(path)
+
+	if list, err := store.Load(); err != nil || len(list) != 0 {
+		t.Fatalf("Load missing file = %v, %v; want empty, nil", list, err)
+	}
+
+	want := []HiddenWindow{{
+		Handle:           0xABC,
+		ProcessID:        42,
+		ProcessCreatedAt: time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC),
+		ProcessPath:      `C:\Apps\a.exe`,
+		AppName:          "A",
+		Title:            "Inbox",
+		HiddenAt:         time.Date(2024, 5, 1, 8, 1, 0, 0, time.UTC),
+	}}
+	if err := store.Save(want); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	got, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(got) != 1 || got[0] != want[0] {
+		t.Fatalf("Load = %+v, want %+v", got, want)
+	}
+
+	if err = store.Save(nil); err != nil {
+		t.Fatalf("Save empty: %v", err)
+	}
+	if _, err = os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("empty registry left file behind: %v", err)
+	}
+}
+
+func TestOutputFilesRotateAndLatest(t *testing.T) {
+	root := t.TempDir()
+	entry := config.ManagedAppEntry{ID: "app:1", Name: "Sync"}
+	if _, err := LatestOutput(root, entry); !errors.Is(err, ErrNoOutput) {
+		t.Fatalf("expected ErrNoOutput, got %v", err)
+	}
+
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	var last string
+	for i := 0; i < MaxOutputFiles+3; i++ {
+		f, err := createOutputFile(OutputDir(root, entry), start.Add(time.Duration(i)*time.Second))
+		if err != nil {
+			t.Fatalf("createOutputFile: %v", err)
+		}
+		last = f.Name()
+		_ = f.Close()
+	}
+
+	if filepath.Base(OutputDir(root, entry)) != "app_1" {
+		t.Fatalf("expected sanitized directory, got %s", OutputDir(root, entry))
+	}
+	files, err := outputFiles(OutputDir(root, entry))
+	if err != nil {
+		t.Fatalf("outputFiles: %v", err)
+	}
+	if len(files) != MaxOutputFiles {
+		t.Fatalf("expected %d files, got %d", MaxOutputFiles, len(files))
+	}
+	latest, err := LatestOutput(root, entry)
+	if err != nil || latest != last {
+		t.Fatalf("expected latest %s, got %s err=%v", last, latest, err)
+	}
+}
+
+func TestOutputCaptureCapsFileSize(t *testing.T) {
+	f, err := createOutputFile(t.TempDir(), time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("createOutputFile: %v", err)
+	}
+	capture, w, err := startOutputCapture(f, 10)
+	if err != nil {
+		t.Fatalf("startOutputCapture: %v", err)
+	}
+	for _, chunk := range []string{"12345", "67890abc", "dropped"} {
+		if _, err = w.WriteString(chunk); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	_ = w.Close()
+	capture.finish("[exit]")
+
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	if !strings.HasPrefix(got, "1234567890\r\n[WinTray] output truncated") || strings.Contains(got, "abc") || strings.Contains(got, "dropped") {
+		t.Fatalf("output = %q, want 10 bytes and a truncation note", got)
+	}
+	if !strings.HasSuffix(got, "[exit]") {
+		t.Fatalf("output = %q, want the exit note last", got)
+	}
+}
+
+func TestExpandWindowsEnv(t *testing.T) {
+	lookup := newEnviron([]string{"USERPROFILE=C:\\Users\\me", "Empty="}).Lookup
 	tests := []struct {
-		input string
-		want  []string
+		in   string
+		want string
 	}{
-		{"", nil},
-		{"--verbose", []string{"--verbose"}},
-		{`--config "C:\My Path\cfg.json" --verbose`, []string{"--config", `C:\My Path\cfg.json`, "--verbose"}},
-		{`  --flag   value  `, []string{"--flag", "value"}},
-		{`"quoted arg"`, []string{"quoted arg"}},
-		{`one two three`, []string{"one", "two", "three"}},
+		{"%USERPROFILE%\\bin", "C:\\Users\\me\\bin"},
+		{"%userprofile%", "C:\\Users\\me"},
+		{"%EMPTY%x", "x"},
+		{"%MISSING%\\bin", "%MISSING%\\bin"},
+		{"100% of %USERPROFILE%", "100% of C:\\Users\\me"},
+		{"%%USERPROFILE%", "%C:\\Users\\me"},
+		{"50%", "50%"},
+		{"no vars", "no vars"},
 	}
-	for _, tc := range tests {
-		got := parseArgs(tc.input)
-		if len(got) != len(tc.want) {
-			t.Errorf("parseArgs(%q) = %v (len %d), want %v (len %d)", tc.input, got, len(got), tc.want, len(tc.want))
-			continue
+	for _, tt := range tests {
+		if got := expandWindowsEnv(tt.in, lookup); got != tt.want {
+			t.Fatalf("expandWindowsEnv(%q) = %q, want %q", tt.in, got, tt.want)
 		}
-		for i := range got {
-			if got[i] != tc.want[i] {
-				t.Errorf("parseArgs(%q)[%d] = %q, want %q", tc.input, i, got[i], tc.want[i])
-			}
+	}
+}
+
+func TestBuildEnvironment(t *testing.T) {
+	sep := string(os.PathListSeparator)
+	base := []string{"=C:=C:\\work", "Path=C:\\Windows", "PROXY=http://proxy", "HOME=C:\\Users\\me"}
+	got := buildEnvironment(base, []config.EnvOverride{
+		{Op: config.EnvSet, Name: "TOOLS", Value: "%HOME%\\tools"},
+		{Op: config.EnvPrependPath, Name: "PATH", Value: "%TOOLS%\\bin"},
+		{Op: config.EnvUnset, Name: "proxy"},
+		{Op: config.EnvPrependPath, Name: "NEWLIST", Value: "a"},
+	})
+	want := []string{"=C:=C:\\work", "Path=C:\\Users\\me\\tools\\bin" + sep + "C:\\Windows", "HOME=C:\\Users\\me", "TOOLS=C:\\Users\\me\\tools", "NEWLIST=a"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
 		}
 	}
+	if base[2] != "PROXY=http://proxy" {
+		t.Fatalf("base environment was modified: %v", base)
+	}
+}
+
+type fakeShortcuts map[string]Shortcut
+
+func (f fakeShortcuts) ResolveShortcut(path string) (Shortcut, error) {
+	link, ok := f[path]
+	if !ok {
+		return Shortcut{}, errors.New("not a shortcut")
+	}
+	return link, nil
+}
+
+type fakeShell struct {
+	opened    []string
+	activated []string
+	pid       uint32
+}
+
+func (f *fakeShell) ShellOpen(target string, _ []string, _ string) (uint32, error) {
+	f.opened = append(f.opened, target)
+	return f.pid, nil
+}
+
+func (f *fakeShell) ActivateApp(appUserModelID string, _ []string) (uint32, error) {
+	f.activated = append(f.activated, appUserModelID)
+	return f.pid, nil
+}
+
+func TestLaunchTargetKinds(t *testing.T) {
+	dir := t.TempDir()
+	exe := filepath.Join(dir, "Game.exe")
+	lnk := filepath.Join(dir, "Game.lnk")
+	brokenLnk := filepath.Join(dir, "Broken.lnk")
+	for _, p := range []string{exe, lnk, brokenLnk} {
+		if err := os.WriteFile(p, nil, 0o644); err != nil {
+			t.Fatalf("write %s: %v", p, err)
+		}
+	}
+
+	shell := &fakeShell{}
+	svc := NewService(&fakeEnumerator{}, &fakeManager{}, nopLogger{})
+	svc.shell = shell
+	svc.shortcuts = fakeShortcuts{
+		lnk:       {Target: exe, Args: `-windowed "C:\Save Games"`, WorkingDir: dir},
+		brokenLnk: {Target: filepath.Join(dir, "missing.exe")},
+	}
+
+	target, err := svc.launchTarget(config.ManagedAppEntry{ExePath: lnk, LaunchKind: config.LaunchShortcut, Args: []string{"-fast"}})
+	if err != nil {
+		t.Fatalf("shortcut target: %v", err)
+	}
+	exeT, ok := target.(*exeTarget)
+	if !ok {
+		t.Fatalf("expected shortcut to resolve to an exe target, got %T", target)
+	}
+	wantArgs := []string{"-windowed", `C:\Save Games`, "-fast"}
+	if len(exeT.entry.Args) != len(wantArgs) {
+		t.Fatalf("expected args %q, got %q", wantArgs, exeT.entry.Args)
+	}
+	for i := range wantArgs {
+		if exeT.entry.Args[i] != wantArgs[i] {
+			t.Fatalf("expected args %q, got %q", wantArgs, exeT.entry.Args)
+		}
+	}
+	if exeT.entry.WorkingDir != dir {
+		t.Fatalf("expected working dir %s, got %s", dir, exeT.entry.WorkingDir)
+	}
+	if path, name := target.ExpectedProcess(); path != normalizePath(exe) || name != "Game" {
+		t.Fatalf("unexpected expected process %q %q", path, name)
+	}
+
+	target, err = svc.launchTarget(config.ManagedAppEntry{ExePath: brokenLnk, LaunchKind: config.LaunchShortcut})
+	if err != nil {
+		t.Fatalf("broken shortcut target: %v", err)
+	}
+	if _, ok := target.(*shellTarget); !ok || target.CanCapture() {
+		t.Fatalf("expected unresolvable shortcut to be opened by the shell, got %T", target)
+	}
+
+	target, err = svc.launchTarget(config.ManagedAppEntry{ExePath: "steam://rungameid/570", LaunchKind: config.LaunchURI, ExpectedProcess: filepath.Join(dir, "dota2.exe")})
+	if err != nil {
+		t.Fatalf("uri target: %v", err)
+	}
+	if path, name := target.ExpectedProcess(); path != normalizePath(filepath.Join(dir, "dota2.exe")) || name != "dota2" {
+		t.Fatalf("unexpected expected process %q %q", path, name)
+	}
+	if p, err := target.Start(nil); err != nil || p != nil {
+		t.Fatalf("expected untracked shell launch, got %v %v", p, err)
+	}
+	if len(shell.opened) != 1 || shell.opened[0] != "steam://rungameid/570" {
+		t.Fatalf("unexpected shell launches: %v", shell.opened)
+	}
+
+	target, err = svc.launchTarget(config.ManagedAppEntry{ExePath: `shell:AppsFolder\Microsoft.WindowsCalculator_8wekyb3d8bbwe!App`, LaunchKind: config.LaunchAppID, ExpectedProcess: "CalculatorApp"})
+	if err != nil {
+		t.Fatalf("aumid target: %v", err)
+	}
+	if _, err := target.Start(nil); err != nil {
+		t.Fatalf("aumid start: %v", err)
+	}
+	if len(shell.activated) != 1 || shell.activated[0] != "Microsoft.WindowsCalculator_8wekyb3d8bbwe!App" {
+		t.Fatalf("unexpected activations: %v", shell.activated)
+	}
+	if path, name := target.ExpectedProcess(); path != "" || name != "CalculatorApp" {
+		t.Fatalf("unexpected expected process %q %q", path, name)
+	}
+
+	if _, err := svc.launchTarget(config.ManagedAppEntry{ExePath: filepath.Join(dir, "missing.exe")}); err == nil {
+		t.Fatalf("expected missing exe to be rejected")
+	}
+}
+
+func TestHideExistingReportsFailureDetails(t *testing.T) {
+	denied := errors.New("access is denied")
+	enum := &fakeEnumerator{windows: []ManagedWindowInfo{
+		{Handle: 0x10, ProcessID: 7, ProcessName: "tool.exe", ProcessPath: "/apps/tool.exe", Title: "Tool", ClassName: "ToolWnd", IsVisible: true},
+		{Handle: 0x20, ProcessID: 8, ProcessName: "other.exe", ProcessPath: "/apps/other.exe", Title: "Other", IsVisible: true},
+	}}
+	svc := NewService(enum, &fakeManager{failErr: denied}, nopLogger{})
+	entry := config.ManagedAppEntry{Name: "Tool", ExePath: "/apps/tool.exe", LaunchKind: config.LaunchExe}
+
+	result := svc.HideExisting(context.Background(), entry, 0)
+	if result.Managed || result.Code != ResultNoExistingWindowManaged {
+		t.Fatalf("result = %+v, want unmanaged %s", result, ResultNoExistingWindowManaged)
+	}
+	if !errors.Is(result.Err, denied) {
+		t.Fatalf("Err = %v, want %v", result.Err, denied)
+	}
+	if d := result.Details; d.Attempts != 1 || d.Candidates != 1 || d.Score < closeAllowedScoreThreshold {
+		t.Fatalf("Details = %+v, want 1 attempt with 1 candidate above threshold", d)
+	}
+	if d := result.Details; !reflect.DeepEqual(d.Actions, []string{"close", "hide"}) || d.Window != "" {
+		t.Fatalf("Details = %+v, want close then hide and no managed window", d)
+	}
+}
+
+func TestScoreWindowsKeepsOnlyTheEntrysWindows(t *testing.T) {
+	windows := []ManagedWindowInfo{
+		{Handle: 0x10, ProcessID: 7, ProcessName: "tool.exe", ProcessPath: "/apps/tool.exe", Title: "Tool", IsVisible: true},
+		{Handle: 0x20, ProcessID: 8, ProcessName: "other.exe", ProcessPath: "/apps/other.exe", Title: "Other", IsVisible: true},
+		{Handle: 0x30, ProcessID: 7, ProcessName: "tool.exe", ProcessPath: "/apps/tool.exe", IsVisible: true},
+	}
+	svc := NewService(&fakeEnumerator{}, &fakeManager{}, nopLogger{})
+	entry := config.ManagedAppEntry{Name: "Tool", ExePath: "/apps/tool.exe", LaunchKind: config.LaunchExe}
+
+	got := svc.ScoreWindows(entry, windows)
+	if len(got) != 2 {
+		t.Fatalf("candidates = %+v, want the two tool.exe windows", got)
+	}
+	if got[0].Window.Handle != 0x10 || got[0].Score < got[1].Score {
+		t.Fatalf("candidates = %+v, want the titled window first", got)
+	}
 }
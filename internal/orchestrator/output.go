@@ -0,0 +1,301 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"wintray/internal/config"
+)
+
+const (
+	// MaxOutputFiles is the number of captured output files kept per entry.
+	MaxOutputFiles = 10
+	// MaxOutputFileSize caps each captured output file. Output beyond it is
+	// dropped.
+	MaxOutputFileSize = 10 << 20
+
+	// outputDrainTimeout is how long an exited launch waits for its output
+	// to be copied. Child processes that inherited the output can keep the
+	// pipe open.
+	outputDrainTimeout = 2 * time.Second
+
+	outputFileLayout = "20060102-150405.000"
+	outputFileExt    = ".log"
+)
+
+// ErrNoOutput is returned by LatestOutput when an entry has no captured output.
+var ErrNoOutput = errors.New("no captured output")
+
+// ExitRecord describes how a background launch ended.
+type ExitRecord struct {
+	EntryID    string
+	AppName    string
+	PID        uint32
+	ExitCode   int
+	StartedAt  time.Time
+	Runtime    time.Duration
+	OutputPath string
+}
+
+// launchedProcess is a process started by the Service together with the file
+// receiving its captured output, if any.
+type launchedProcess struct {
+	// process is nil when the shell started the target without reporting a process.
+	process    *os.Process
+	output     *outputCapture
+	outputPath string
+	startedAt  time.Time
+	// tracked is the record StopManagedApps marks; nil without a pid.
+	tracked *trackedProcess
+}
+
+// outputCapture copies the output of a launch from a pipe into its output
+// file, up to limit bytes.
+type outputCapture struct {
+	mu        sync.Mutex
+	file      *os.File
+	limit     int64
+	written   int64
+	truncated bool
+	done      chan struct{}
+}
+
+// startOutputCapture starts copying into file and returns the pipe end to
+// hand to the launched process. The caller closes it once the process started.
+func startOutputCapture(file *os.File, limit int64) (*outputCapture, *os.File, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	c := &outputCapture{file: file, limit: limit, done: make(chan struct{})}
+	go func() {
+		defer close(c.done)
+		_, _ = io.Copy(c, r)
+		_ = r.Close()
+	}()
+	return c, w, nil
+}
+
+// Write appends p to the file until the limit is reached and drops the rest,
+// so the launched process never blocks on a full pipe.
+func (c *outputCapture) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.file == nil {
+		return 0, os.ErrClosed
+	}
+	if c.truncated {
+		return len(p), nil
+	}
+	if room := c.limit - c.written; int64(len(p)) > room {
+		n, _ := c.file.Write(p[:max(room, 0)])
+		c.written += int64(n)
+		c.truncated = true
+		_, _ = fmt.Fprintf(c.file, "\r\n[WinTray] output truncated at %d bytes\r\n", c.limit)
+		return len(p), nil
+	}
+	n, err := c.file.Write(p)
+	c.written += int64(n)
+	return n, err
+}
+
+// finish waits for the copied output, appends note and closes the file.
+func (c *outputCapture) finish(note string) {
+	select {
+	case <-c.done:
+	case <-time.After(outputDrainTimeout):
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if note != "" {
+		_, _ = io.WriteString(c.file, note)
+	}
+	_ = c.file.Close()
+	c.file = nil
+}
+
+func (r *launchedProcess) pid() uint32 {
+	if r.process == nil {
+		return 0
+	}
+	return uint32(r.process.Pid)
+}
+
+// SetOutputRoot sets the directory under which captured output is written,
+// one subdirectory per entry. Capture is disabled while it is empty.
+func (s *Service) SetOutputRoot(dir string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.outputRoot = dir
+}
+
+// OnProcessExit registers a callback invoked whenever a background launch exits.
+func (s *Service) OnProcessExit(fn func(ExitRecord)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onProcessExit = fn
+}
+
+func (s *Service) launch(entry config.ManagedAppEntry, target LaunchTarget) (*launchedProcess, error) {
+	run := &launchedProcess{startedAt: time.Now()}
+	var pipe *os.File
+	if entry.CaptureOutput && entry.LaunchHiddenInBackground && target.CanCapture() {
+		s.mu.Lock()
+		root := s.outputRoot
+		s.mu.Unlock()
+		if root != "" {
+			capture, w, err := createCapture(OutputDir(root, entry), run.startedAt)
+			if err != nil {
+				s.logger.Warn("output capture unavailable", append(entryAttrs(entry), "err", err)...)
+			} else {
+				run.output = capture
+				run.outputPath = capture.file.Name()
+				pipe = w
+			}
+		}
+	}
+
+	process, err := target.Start(pipe)
+	if pipe != nil {
+		// The launched process holds its own copy.
+		_ = pipe.Close()
+	}
+	if err != nil {
+		if run.output != nil {
+			run.output.finish("")
+		}
+		return nil, err
+	}
+	run.process = process
+	return run, nil
+}
+
+func createCapture(dir string, now time.Time) (*outputCapture, *os.File, error) {
+	file, err := createOutputFile(dir, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	capture, w, err := startOutputCapture(file, MaxOutputFileSize)
+	if err != nil {
+		_ = file.Close()
+		return nil, nil, err
+	}
+	return capture, w, nil
+}
+
+// waitLaunched waits for run to exit, appends the exit status to its output
+// file and reports the exit. It returns false when ctx is cancelled first.
+func (s *Service) waitLaunched(ctx context.Context, entry config.ManagedAppEntry, run *launchedProcess) (int, bool) {
+	exitCode, ok := waitForExit(ctx, run.process)
+	if !ok {
+		return 0, false
+	}
+
+	record := ExitRecord{
+		EntryID:    entry.ID,
+		AppName:    entry.Name,
+		PID:        run.pid(),
+		ExitCode:   exitCode,
+		StartedAt:  run.startedAt,
+		Runtime:    time.Since(run.startedAt).Round(time.Millisecond),
+		OutputPath: run.outputPath,
+	}
+	if run.output != nil {
+		run.output.finish(fmt.Sprintf("\r\n[WinTray] exit code=%d runtime=%s\r\n", record.ExitCode, record.Runtime))
+	}
+	s.log(ctx).Info("process exited", "pid", record.PID, "exit", record.ExitCode, "runtime", record.Runtime)
+
+	s.mu.Lock()
+	notify := s.onProcessExit
+	s.mu.Unlock()
+	if notify != nil {
+		notify(record)
+	}
+	return exitCode, true
+}
+
+// OutputDir returns the directory holding the captured output of entry.
+func OutputDir(root string, entry config.ManagedAppEntry) string {
+	return filepath.Join(root, sanitizeFileName(supervisorKey(entry)))
+}
+
+// LatestOutput returns the most recent captured output file of entry.
+func LatestOutput(root string, entry config.ManagedAppEntry) (string, error) {
+	files, err := outputFiles(OutputDir(root, entry))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", ErrNoOutput
+		}
+		return "", err
+	}
+	if len(files) == 0 {
+		return "", ErrNoOutput
+	}
+	return files[len(files)-1], nil
+}
+
+func createOutputFile(dir string, now time.Time) (*os.File, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	// Leave room for the new file before it is created.
+	_ = pruneOutputFiles(dir, MaxOutputFiles-1)
+	name := filepath.Join(dir, now.Format(outputFileLayout)+outputFileExt)
+	return os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+}
+
+// pruneOutputFiles removes the oldest output files in dir so that at most keep remain.
+func pruneOutputFiles(dir string, keep int) error {
+	files, err := outputFiles(dir)
+	if err != nil {
+		return err
+	}
+	var firstErr error
+	for len(files) > keep {
+		if err := os.Remove(files[0]); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		files = files[1:]
+	}
+	return firstErr
+}
+
+// outputFiles lists the output files in dir, oldest first. The timestamped
+// file names sort chronologically.
+func outputFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || !strings.EqualFold(filepath.Ext(e.Name()), outputFileExt) {
+			continue
+		}
+		files = append(files, filepath.Join(dir, e.Name()))
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func sanitizeFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	name = strings.TrimRight(name, ". ")
+	if name == "" {
+		return "_"
+	}
+	return name
+}
@@ -0,0 +1,199 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"wintray/internal/config"
+)
+
+// ProcessController inspects and terminates processes launched by the Service.
+type ProcessController interface {
+	IsProcessRunning(pid uint32) bool
+	TerminateProcessTree(pid uint32) error
+	ProcessIdentity(pid uint32) (ProcessIdentity, bool)
+}
+
+// ProcessIdentity distinguishes a process from a later one that reuses its PID.
+type ProcessIdentity struct {
+	Path      string
+	CreatedAt time.Time
+}
+
+// trackedProcess is a process WinTray launched for an entry.
+type trackedProcess struct {
+	pid uint32
+	// stopping is set while StopManagedApps stops the process and stays set
+	// once it succeeded, after the entry has left launched. The supervisor
+	// of the launch reads it to tell a requested stop from a crash.
+	stopping bool
+}
+
+// StopResult describes what StopManagedApps did to a single entry.
+type StopResult struct {
+	AppName string
+	PID     uint32
+	Skipped bool
+	Closed  bool
+	Killed  bool
+	Err     error
+}
+
+func (s *Service) trackLaunch(entry config.ManagedAppEntry, pid uint32) *trackedProcess {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tracked := &trackedProcess{pid: pid}
+	s.launched[supervisorKey(entry)] = tracked
+	return tracked
+}
+
+// stopRequested reports whether StopManagedApps is stopping or has stopped
+// run.
+func (s *Service) stopRequested(run *launchedProcess) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return run.tracked != nil && run.tracked.stopping
+}
+
+// StopManagedApps asks every process WinTray launched for the given entries to
+// close, waiting up to grace before applying the entry's StopPolicy. Entries are
+// stopped in reverse list order, the opposite of the autorun launch order, so
+// that apps launched later (which may depend on earlier ones) go first.
+func (s *Service) StopManagedApps(ctx context.Context, entries []config.ManagedAppEntry, grace time.Duration) []StopResult {
+	results := make([]StopResult, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		key := supervisorKey(entry)
+
+		s.mu.Lock()
+		tracked, ok := s.launched[key]
+		var pid uint32
+		if ok {
+			pid = tracked.pid
+		}
+		s.mu.Unlock()
+		if !ok {
+			continue
+		}
+
+		result := StopResult{AppName: entry.Name, PID: pid}
+		if entry.StopPolicy == config.StopNever {
+			result.Skipped = true
+			results = append(results, result)
+			continue
+		}
+		// stopping keeps the supervisor from restarting the process while it
+		// is stopped; a stop that fails leaves it supervised.
+		s.mu.Lock()
+		tracked.stopping = true
+		s.mu.Unlock()
+		s.stopProcess(ctx, entry, &result, grace)
+		s.mu.Lock()
+		if result.Closed || result.Killed {
+			if s.launched[key] == tracked {
+				delete(s.launched, key)
+			}
+		} else {
+			tracked.stopping = false
+		}
+		s.mu.Unlock()
+		results = append(results, result)
+	}
+	return results
+}
+
+func (s *Service) stopProcess(ctx context.Context, entry config.ManagedAppEntry, result *StopResult, grace time.Duration) {
+	pid := result.PID
+	log := s.log(withEntry(ctx, entry))
+	if !s.processes.IsProcessRunning(pid) {
+		log.Info("stop: already exited", "pid", pid)
+		result.Closed = true
+		return
+	}
+
+	for _, w := range s.enumerator.EnumerateTopLevelWindows() {
+		if w.ProcessID != pid {
+			continue
+		}
+		target := resolveActionTargetHandle(w)
+		if _, err := s.manager.CloseWindow(target); err != nil {
+			log.Warn("stop: close request failed", hwndAttr("hwnd", target), "err", err)
+		}
+	}
+
+	deadline := time.Now().Add(grace)
+	for time.Now().Before(deadline) {
+		if !s.processes.IsProcessRunning(pid) {
+			log.Info("stop: closed", "pid", pid)
+			result.Closed = true
+			return
+		}
+		if !waitWithContext(ctx, 250*time.Millisecond) {
+			result.Err = ctx.Err()
+			return
+		}
+	}
+	if !s.processes.IsProcessRunning(pid) {
+		result.Closed = true
+		return
+	}
+
+	if entry.StopPolicy != config.StopCloseThenKill {
+		log.Warn("stop: still running", "pid", pid, "grace", grace)
+		result.Err = fmt.Errorf("still running after %s", grace)
+		return
+	}
+	if err := s.processes.TerminateProcessTree(pid); err != nil {
+		log.Error("stop: terminate failed", "pid", pid, "err", err)
+		result.Err = err
+		return
+	}
+	log.Warn("stop: terminated", "pid", pid)
+	result.Killed = true
+}
+
+// processNode is one process of a process table snapshot.
+type processNode struct {
+	parent uint32
+	// created is zero when the creation time could not be read.
+	created time.Time
+}
+
+// isChildOf reports whether n was started by parent. A parent PID alone is
+// not enough: the parent may have exited and its PID been reused by a
+// process started after n, so n must also have started after parent.
+func (n processNode) isChildOf(parent processNode) bool {
+	if n.created.IsZero() || parent.created.IsZero() {
+		return false
+	}
+	return !n.created.Before(parent.created)
+}
+
+// processTreeOrder returns root and all of its descendants with children
+// listed before their parents, which is the order they should be terminated in.
+func processTreeOrder(root uint32, procs map[uint32]processNode) []uint32 {
+	children := map[uint32][]uint32{}
+	for pid, node := range procs {
+		parent, ok := procs[node.parent]
+		if pid != node.parent && ok && node.isChildOf(parent) {
+			children[node.parent] = append(children[node.parent], pid)
+		}
+	}
+
+	order := make([]uint32, 0, 1)
+	seen := map[uint32]bool{}
+	var visit func(pid uint32)
+	visit = func(pid uint32) {
+		if seen[pid] {
+			return
+		}
+		seen[pid] = true
+		for _, child := range children[pid] {
+			visit(child)
+		}
+		order = append(order, pid)
+	}
+	visit(root)
+	return order
+}
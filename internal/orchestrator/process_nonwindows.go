@@ -0,0 +1,14 @@
+//go:build !windows
+
+package orchestrator
+
+type Win32ProcessController struct{}
+
+func NewWin32ProcessController() *Win32ProcessController { return &Win32ProcessController{} }
+
+func (c *Win32ProcessController) IsProcessRunning(_ uint32) bool      { return false }
+func (c *Win32ProcessController) TerminateProcessTree(_ uint32) error { return nil }
+
+func (c *Win32ProcessController) ProcessIdentity(_ uint32) (ProcessIdentity, bool) {
+	return ProcessIdentity{}, false
+}
@@ -0,0 +1,100 @@
+//go:build windows
+
+package orchestrator
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+type Win32ProcessController struct{}
+
+func NewWin32ProcessController() *Win32ProcessController { return &Win32ProcessController{} }
+
+func (c *Win32ProcessController) IsProcessRunning(pid uint32) bool {
+	if pid == 0 {
+		return false
+	}
+	h, err := windows.OpenProcess(windows.SYNCHRONIZE|windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		// Access denied still means the process exists.
+		return errors.Is(err, windows.ERROR_ACCESS_DENIED)
+	}
+	defer windows.CloseHandle(h)
+	wait, err := windows.WaitForSingleObject(h, 0)
+	return err == nil && wait == uint32(windows.WAIT_TIMEOUT)
+}
+
+func (c *Win32ProcessController) TerminateProcessTree(pid uint32) error {
+	procs, err := snapshotProcesses()
+	if err != nil {
+		return err
+	}
+	var firstErr error
+	for _, target := range processTreeOrder(pid, procs) {
+		if err = terminateProcess(target); err != nil && firstErr == nil && target == pid {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (c *Win32ProcessController) ProcessIdentity(pid uint32) (ProcessIdentity, bool) {
+	if pid == 0 {
+		return ProcessIdentity{}, false
+	}
+	created, ok := processCreatedAt(pid)
+	if !ok {
+		return ProcessIdentity{}, false
+	}
+	_, path := processInfo(pid)
+	return ProcessIdentity{Path: path, CreatedAt: created}, true
+}
+
+func processCreatedAt(pid uint32) (time.Time, bool) {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return time.Time{}, false
+	}
+	defer windows.CloseHandle(h)
+
+	var created, exited, kernel, user windows.Filetime
+	if err = windows.GetProcessTimes(h, &created, &exited, &kernel, &user); err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(0, created.Nanoseconds()), true
+}
+
+// snapshotProcesses returns the parent and creation time of every process.
+func snapshotProcesses() (map[uint32]processNode, error) {
+	snap, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return nil, fmt.Errorf("process snapshot failed: %w", err)
+	}
+	defer windows.CloseHandle(snap)
+
+	procs := map[uint32]processNode{}
+	var entry windows.ProcessEntry32
+	entry.Size = uint32(unsafe.Sizeof(entry))
+	for err = windows.Process32First(snap, &entry); err == nil; err = windows.Process32Next(snap, &entry) {
+		created, _ := processCreatedAt(entry.ProcessID)
+		procs[entry.ProcessID] = processNode{parent: entry.ParentProcessID, created: created}
+	}
+	if !errors.Is(err, windows.ERROR_NO_MORE_FILES) {
+		return nil, fmt.Errorf("process enumeration failed: %w", err)
+	}
+	return procs, nil
+}
+
+func terminateProcess(pid uint32) error {
+	h, err := windows.OpenProcess(windows.PROCESS_TERMINATE, false, pid)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(h)
+	return windows.TerminateProcess(h, 1)
+}
@@ -0,0 +1,97 @@
+package orchestrator
+
+import (
+	"fmt"
+	"time"
+)
+
+// ResultCode identifies the outcome of StartAndManage or HideExisting.
+type ResultCode string
+
+const (
+	ResultEmptyExePath            ResultCode = "emptyExePath"
+	ResultInvalidLaunchTarget     ResultCode = "invalidLaunchTarget"
+	ResultInvalidProcessName      ResultCode = "invalidProcessName"
+	ResultStartFailed             ResultCode = "startFailed"
+	ResultStartedOnly             ResultCode = "startedOnly"
+	ResultStartedHidden           ResultCode = "startedHidden"
+	ResultAlreadyRunningSkipped   ResultCode = "alreadyRunningSkipped"
+	ResultAlreadyRunningManaged   ResultCode = "alreadyRunningManaged"
+	ResultNoExpectedProcess       ResultCode = "noExpectedProcess"
+	ResultNoWindowManaged         ResultCode = "noWindowManaged"
+	ResultNoExistingWindowManaged ResultCode = "noExistingWindowManaged"
+	ResultManaged                 ResultCode = "managed"
+	ResultManagedExisting         ResultCode = "managedExisting"
+	// ResultCrashLoop is recorded when a supervised background launch
+	// exhausted its restarts.
+	ResultCrashLoop ResultCode = "crashLoop"
+)
+
+// ResultCodes lists every code a Result can carry.
+func ResultCodes() []ResultCode {
+	return []ResultCode{
+		ResultEmptyExePath,
+		ResultInvalidLaunchTarget,
+		ResultInvalidProcessName,
+		ResultStartFailed,
+		ResultStartedOnly,
+		ResultStartedHidden,
+		ResultAlreadyRunningSkipped,
+		ResultAlreadyRunningManaged,
+		ResultNoExpectedProcess,
+		ResultNoWindowManaged,
+		ResultNoExistingWindowManaged,
+		ResultManaged,
+		ResultManagedExisting,
+		ResultCrashLoop,
+	}
+}
+
+// ResultDetails describes the window matching behind a Result. It is zero
+// when no matching ran.
+type ResultDetails struct {
+	// Attempts is the number of match rounds that ran.
+	Attempts int
+	// Candidates is the largest number of candidate windows seen in a round.
+	Candidates int
+	// Score is the score of the managed window, or the best score seen when
+	// none was managed.
+	Score int
+	// FirstWindow is the time until a candidate window first appeared.
+	FirstWindow time.Duration
+	// Action is the time until a window action first took effect.
+	Action  time.Duration
+	Elapsed time.Duration
+	// Actions lists the requests sent to the managed window, or to the last
+	// candidate tried when none was managed: "close", "hide".
+	Actions []string
+	// Window describes the managed window.
+	Window string
+}
+
+type Result struct {
+	AppName string
+	Managed bool
+	Action  string
+	Code    ResultCode
+	// Err is the underlying failure, if any: the launch error, or the last
+	// error a window action returned.
+	Err        error
+	Details    ResultDetails
+	OutputPath string
+	// PID is the background process whose exit is reported through
+	// OnProcessExit. It is zero for other results.
+	PID uint32
+}
+
+// String formats r for logs.
+func (r Result) String() string {
+	s := string(r.Code)
+	if r.Details.Attempts > 0 {
+		s += fmt.Sprintf(" attempts=%d candidates=%d score=%d elapsed=%s", r.Details.Attempts, r.Details.Candidates, r.Details.Score, r.Details.Elapsed.Round(time.Millisecond))
+	}
+	if r.Err != nil {
+		s += " err=" + r.Err.Error()
+	}
+	return s
+}
@@ -1,21 +1,27 @@
 package orchestrator
 
 import (
-	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
-	"syscall"
+
+	"wintray/internal/cmdline"
+	"wintray/internal/config"
 )
 
-func startProcess(exePath, args string, hidden bool) (*exec.Cmd, error) {
-	dir := filepath.Dir(exePath)
-	cmd := buildLaunchCommand(exePath, args, hidden)
-	if _, err := os.Stat(dir); err == nil {
-		cmd.Dir = dir
+// startProcess launches entry. When output is non-nil it receives the
+// process's stdout and stderr.
+func startProcess(entry config.ManagedAppEntry, output *os.File) (*exec.Cmd, error) {
+	exePath, hidden := entry.ExePath, entry.LaunchHiddenInBackground
+	env, dir, err := launchEnvironment(entry)
+	if err != nil {
+		return nil, err
 	}
+	args := expandArgs(entry.Args, env)
+	cmd := buildLaunchCommand(exePath, args, hidden)
+	prepareCommand(cmd, dir, env, output)
 
 	startErr := cmd.Start()
 	if startErr == nil {
@@ -25,20 +31,9 @@ func startProcess(exePath, args string, hidden bool) (*exec.Cmd, error) {
 	if runtime.GOOS == "windows" {
 		// Retry with cmd.exe /c start "" as a last resort (handles shell-associated executables)
 		cleanPath := strings.Trim(strings.TrimSpace(exePath), "\"")
-		commandLine := fmt.Sprintf("cmd.exe /c start \"\" \"%s\"", cleanPath)
-		if trimmedArgs := strings.TrimSpace(args); trimmedArgs != "" {
-			commandLine += " " + trimmedArgs
-		}
-		shellCmd := exec.Command("cmd.exe")
-		attr := &syscall.SysProcAttr{CmdLine: commandLine}
-		if hidden {
-			attr.CreationFlags = createNoWindow
-			attr.HideWindow = true
-		}
-		shellCmd.SysProcAttr = attr
-		if _, err := os.Stat(dir); err == nil {
-			shellCmd.Dir = dir
-		}
+		commandLine := shellCommandLine(`start "" ` + cmdline.JoinCmd(append([]string{cleanPath}, args...)))
+		shellCmd := newShellCommand(commandLine, hidden)
+		prepareCommand(shellCmd, dir, env, output)
 		if shellErr := shellCmd.Start(); shellErr == nil {
 			return shellCmd, nil
 		}
@@ -47,63 +42,46 @@ func startProcess(exePath, args string, hidden bool) (*exec.Cmd, error) {
 	return nil, startErr
 }
 
-const createNoWindow = 0x08000000
-
-func buildLaunchCommand(exePath, args string, hidden bool) *exec.Cmd {
-	trimmedArgs := strings.TrimSpace(args)
-	if runtime.GOOS == "windows" {
-		needsShell := isCmdScript(exePath) || trimmedArgs != ""
-		if hidden || needsShell {
-			cleanPath := strings.Trim(strings.TrimSpace(exePath), "\"")
-			commandLine := fmt.Sprintf("cmd.exe /c \"%s\"", cleanPath)
-			if trimmedArgs != "" {
-				commandLine = commandLine + " " + trimmedArgs
-			}
-			cmd := exec.Command("cmd.exe")
-			attr := &syscall.SysProcAttr{CmdLine: commandLine}
-			if hidden {
-				attr.CreationFlags = createNoWindow
-				attr.HideWindow = true
-			}
-			cmd.SysProcAttr = attr
-			return cmd
-		}
+// prepareCommand applies the launch directory, environment and output
+// redirection shared by the direct and cmd.exe launch paths.
+func prepareCommand(cmd *exec.Cmd, dir string, env []string, output *os.File) {
+	cmd.Dir = dir
+	cmd.Env = env
+	if output != nil {
+		cmd.Stdout = output
+		cmd.Stderr = output
 	}
-	if trimmedArgs == "" {
-		return exec.Command(exePath)
+}
+
+func buildLaunchCommand(exePath string, args []string, hidden bool) *exec.Cmd {
+	if runtime.GOOS == "windows" && (hidden || isCmdScript(exePath)) {
+		cleanPath := strings.Trim(strings.TrimSpace(exePath), "\"")
+		return newShellCommand(shellCommandLine(cmdline.JoinCmd(append([]string{cleanPath}, args...))), hidden)
 	}
-	return exec.Command(exePath, parseArgs(trimmedArgs)...)
+	return exec.Command(exePath, args...)
 }
 
-func isCmdScript(exePath string) bool {
-	ext := strings.ToLower(filepath.Ext(exePath))
-	return ext == ".bat" || ext == ".cmd"
+// shellCommandLine wraps an already cmd-escaped command for cmd.exe. With /s,
+// cmd.exe strips exactly the outer pair of quotes and leaves the rest alone.
+func shellCommandLine(command string) string {
+	return `cmd.exe /s /c "` + command + `"`
 }
 
-// parseArgs splits an argument string respecting double-quoted segments.
-// e.g. `--config "C:\My Path\cfg.json" --verbose` → ["--config", "C:\My Path\cfg.json", "--verbose"]
-func parseArgs(s string) []string {
-	var args []string
-	var current strings.Builder
-	inQuote := false
-	for i := 0; i < len(s); i++ {
-		ch := s[i]
-		switch {
-		case ch == '"':
-			inQuote = !inQuote
-		case ch == ' ' || ch == '\t':
-			if inQuote {
-				current.WriteByte(ch)
-			} else if current.Len() > 0 {
-				args = append(args, current.String())
-				current.Reset()
-			}
-		default:
-			current.WriteByte(ch)
-		}
+// expandArgs expands %VAR% references in args against env, or WinTray's own
+// environment when env is nil, as cmd.exe did for the old string form.
+func expandArgs(args []string, env []string) []string {
+	lookup := os.LookupEnv
+	if env != nil {
+		lookup = newEnviron(env).Lookup
 	}
-	if current.Len() > 0 {
-		args = append(args, current.String())
+	expanded := make([]string, len(args))
+	for i, arg := range args {
+		expanded[i] = expandWindowsEnv(arg, lookup)
 	}
-	return args
+	return expanded
+}
+
+func isCmdScript(exePath string) bool {
+	ext := strings.ToLower(filepath.Ext(exePath))
+	return ext == ".bat" || ext == ".cmd"
 }
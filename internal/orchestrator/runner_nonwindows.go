@@ -0,0 +1,9 @@
+//go:build !windows
+
+package orchestrator
+
+import "os/exec"
+
+func newShellCommand(commandLine string, _ bool) *exec.Cmd {
+	return exec.Command("sh", "-c", commandLine)
+}
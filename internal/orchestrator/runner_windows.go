@@ -0,0 +1,23 @@
+//go:build windows
+
+package orchestrator
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+const createNoWindow = 0x08000000
+
+// newShellCommand runs commandLine verbatim through cmd.exe; Go's own argument
+// quoting is bypassed because cmd.exe has its own parsing rules.
+func newShellCommand(commandLine string, hidden bool) *exec.Cmd {
+	cmd := exec.Command("cmd.exe")
+	attr := &syscall.SysProcAttr{CmdLine: commandLine}
+	if hidden {
+		attr.CreationFlags = createNoWindow
+		attr.HideWindow = true
+	}
+	cmd.SysProcAttr = attr
+	return cmd
+}
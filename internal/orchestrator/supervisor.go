@@ -0,0 +1,175 @@
+package orchestrator
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"wintray/internal/config"
+)
+
+const (
+	superviseBaseBackoff = time.Second
+	superviseMaxBackoff  = time.Minute
+)
+
+// SupervisorState is the last known state of a supervised background launch.
+type SupervisorState struct {
+	EntryID      string
+	AppName      string
+	PID          uint32
+	Running      bool
+	Restarts     int
+	LastExitCode int
+	CrashLoop    bool
+	// StartedAt is when supervision of the launch began.
+	StartedAt time.Time
+	UpdatedAt time.Time
+}
+
+// OnSupervisorEvent registers a callback invoked whenever a supervised
+// process exits, restarts or enters the crash-loop state.
+func (s *Service) OnSupervisorEvent(fn func(SupervisorState)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onSupervisorEvent = fn
+}
+
+func (s *Service) supervise(ctx context.Context, entry config.ManagedAppEntry, target LaunchTarget, run *launchedProcess) {
+	key := supervisorKey(entry)
+	s.updateSupervisor(key, func(st *SupervisorState) {
+		*st = SupervisorState{EntryID: entry.ID, AppName: entry.Name, PID: run.pid(), Running: true, StartedAt: run.startedAt}
+	})
+
+	tracker := newRestartTracker(entry.Supervise.MaxRestarts, time.Duration(entry.Supervise.WindowSeconds)*time.Second)
+	for {
+		exitCode, ok := s.waitLaunched(ctx, entry, run)
+		if !ok {
+			return
+		}
+		s.updateSupervisor(key, func(st *SupervisorState) {
+			st.Running = false
+			st.LastExitCode = exitCode
+		})
+		if s.stopRequested(run) {
+			s.log(ctx).Info("supervised process stopped on request")
+			return
+		}
+		if exitCode == 0 {
+			s.log(ctx).Info("supervised process exited cleanly")
+			return
+		}
+
+		delay, allowed := tracker.next(time.Now())
+		if !allowed {
+			s.log(ctx).Error("supervised process crash loop", "exit", exitCode, "restarts", entry.Supervise.MaxRestarts, "window", time.Duration(entry.Supervise.WindowSeconds)*time.Second)
+			s.updateSupervisor(key, func(st *SupervisorState) { st.CrashLoop = true })
+			return
+		}
+		s.log(ctx).Warn("supervised process exited", "exit", exitCode, "restartIn", delay)
+		if !waitWithContext(ctx, delay) || s.stopRequested(run) {
+			return
+		}
+
+		next, err := s.launch(entry, target)
+		if err != nil {
+			s.log(ctx).Error("supervised restart failed", "err", err)
+			s.updateSupervisor(key, func(st *SupervisorState) { st.CrashLoop = true })
+			return
+		}
+		run = next
+		pid := run.pid()
+		run.tracked = s.trackLaunch(entry, pid)
+		s.log(ctx).Info("supervised restart", "pid", pid)
+		s.updateSupervisor(key, func(st *SupervisorState) {
+			st.PID = pid
+			st.Running = true
+			st.Restarts++
+		})
+	}
+}
+
+func (s *Service) updateSupervisor(key string, mutate func(*SupervisorState)) {
+	s.mu.Lock()
+	st, ok := s.supervised[key]
+	if !ok {
+		st = &SupervisorState{}
+		s.supervised[key] = st
+	}
+	mutate(st)
+	st.UpdatedAt = time.Now()
+	snapshot := *st
+	notify := s.onSupervisorEvent
+	s.mu.Unlock()
+
+	if notify != nil {
+		notify(snapshot)
+	}
+}
+
+func supervisorKey(entry config.ManagedAppEntry) string {
+	if entry.ID != "" {
+		return entry.ID
+	}
+	return entry.Name
+}
+
+// waitForExit blocks until process exits and reports its exit code. It
+// returns false when ctx is cancelled first; the process is left running.
+// A nil process cannot be waited for and reports false immediately.
+func waitForExit(ctx context.Context, process *os.Process) (int, bool) {
+	if process == nil {
+		return 0, false
+	}
+	type exit struct {
+		state *os.ProcessState
+		err   error
+	}
+	done := make(chan exit, 1)
+	go func() {
+		state, err := process.Wait()
+		done <- exit{state, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return 0, false
+	case e := <-done:
+		if e.err != nil || e.state == nil {
+			return -1, true
+		}
+		return e.state.ExitCode(), true
+	}
+}
+
+// restartTracker enforces the restart budget of a SupervisePolicy and computes
+// an exponential backoff from the number of restarts inside the current window.
+type restartTracker struct {
+	maxRestarts int
+	window      time.Duration
+	restarts    []time.Time
+}
+
+func newRestartTracker(maxRestarts int, window time.Duration) *restartTracker {
+	return &restartTracker{maxRestarts: maxRestarts, window: window}
+}
+
+func (t *restartTracker) next(now time.Time) (time.Duration, bool) {
+	recent := t.restarts[:0]
+	for _, at := range t.restarts {
+		if now.Sub(at) < t.window {
+			recent = append(recent, at)
+		}
+	}
+	t.restarts = recent
+	if len(t.restarts) >= t.maxRestarts {
+		return 0, false
+	}
+
+	delay := superviseBaseBackoff << len(t.restarts)
+	if delay > superviseMaxBackoff || delay <= 0 {
+		delay = superviseMaxBackoff
+	}
+	t.restarts = append(t.restarts, now)
+	return delay, true
+}
@@ -1,6 +1,11 @@
 package orchestrator
 
-import "wintray/internal/config"
+import (
+	"errors"
+	"sync"
+
+	"wintray/internal/config"
+)
 
 type ManagedWindowInfo struct {
 	Handle       uintptr
@@ -18,35 +23,61 @@ type ManagedWindowInfo struct {
 
 type WindowEnumerator interface {
 	EnumerateTopLevelWindows() []ManagedWindowInfo
+	// InspectWindow reports the owning process and visibility of any window,
+	// including hidden ones that EnumerateTopLevelWindows skips.
+	InspectWindow(hwnd uintptr) (pid uint32, visible bool, ok bool)
 }
 
 type WindowManager interface {
 	CloseWindow(hwnd uintptr) (bool, error)
 	HideWindow(hwnd uintptr) (bool, error)
 	MinimizeWindow(hwnd uintptr) (bool, error)
+	RestoreWindow(hwnd uintptr) (bool, error)
 }
 
+// ErrWindowGone is returned by WindowManager implementations when the target
+// handle no longer refers to a window.
+var ErrWindowGone = errors.New("target window is not valid")
+
+// Logger receives the Service's log records. Args are slog-style key-value
+// pairs or slog.Attr values, so a *slog.Logger satisfies it.
 type Logger interface {
-	Info(msg string)
-	Warn(msg string)
-	Error(msg string)
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
 }
 
 type Service struct {
 	enumerator WindowEnumerator
 	manager    WindowManager
 	logger     Logger
-}
+	processes  ProcessController
+	shortcuts  ShortcutResolver
+	shell      ShellLauncher
 
-func NewService(enumerator WindowEnumerator, manager WindowManager, logger Logger) *Service {
-	return &Service{enumerator: enumerator, manager: manager, logger: logger}
+	mu                sync.Mutex
+	launched          map[string]*trackedProcess
+	supervised        map[string]*SupervisorState
+	onSupervisorEvent func(SupervisorState)
+	hidden            map[uintptr]HiddenWindow
+	onHiddenChanged   func()
+	outputRoot        string
+	onProcessExit     func(ExitRecord)
 }
 
-type Result struct {
-	AppName string
-	Managed bool
-	Action  string
-	Message string
+func NewService(enumerator WindowEnumerator, manager WindowManager, logger Logger) *Service {
+	return &Service{
+		enumerator: enumerator,
+		manager:    manager,
+		logger:     logger,
+		processes:  NewWin32ProcessController(),
+		shortcuts:  NewWin32ShortcutResolver(),
+		shell:      NewWin32ShellLauncher(),
+		launched:   map[string]*trackedProcess{},
+		supervised: map[string]*SupervisorState{},
+		hidden:     map[uintptr]HiddenWindow{},
+	}
 }
 
 type MatchCandidate struct {
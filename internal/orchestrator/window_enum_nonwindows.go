@@ -9,3 +9,7 @@ func NewWin32WindowEnumerator() *Win32WindowEnumerator { return &Win32WindowEnum
 func (e *Win32WindowEnumerator) EnumerateTopLevelWindows() []ManagedWindowInfo {
 	return nil
 }
+
+func (e *Win32WindowEnumerator) InspectWindow(_ uintptr) (uint32, bool, bool) {
+	return 0, false, false
+}
@@ -82,6 +82,16 @@ func (e *Win32WindowEnumerator) EnumerateTopLevelWindows() []ManagedWindowInfo {
 	return result
 }
 
+func (e *Win32WindowEnumerator) InspectWindow(hwnd uintptr) (uint32, bool, bool) {
+	if !isWindow(hwnd) {
+		return 0, false, false
+	}
+	var pid uint32
+	_, _, _ = procGetWindowThreadProcess.Call(hwnd, uintptr(unsafe.Pointer(&pid)))
+	v, _, _ := procIsWindowVisible.Call(hwnd)
+	return pid, v != 0, true
+}
+
 func processInfo(pid uint32) (string, string) {
 	if pid == 0 {
 		return "", ""
@@ -3,7 +3,6 @@
 package orchestrator
 
 import (
-	"errors"
 	"fmt"
 	"syscall"
 )
@@ -13,6 +12,7 @@ var (
 	procPostMessageW    = user32.NewProc("PostMessageW")
 	procSendMessageW    = user32.NewProc("SendMessageW")
 	procShowWindowAsync = user32.NewProc("ShowWindowAsync")
+	procSetForeground   = user32.NewProc("SetForegroundWindow")
 )
 
 const (
@@ -22,6 +22,8 @@ const (
 	scMinimize   = 0xF020
 	swHide       = 0
 	swMinimize   = 6
+	swRestore    = 9
+	swShow       = 5
 )
 
 type Win32WindowManager struct{}
@@ -30,7 +32,7 @@ func NewWin32WindowManager() *Win32WindowManager { return &Win32WindowManager{}
 
 func (m *Win32WindowManager) MinimizeWindow(hwnd uintptr) (bool, error) {
 	if !isWindow(hwnd) {
-		return false, errors.New("target window is not valid")
+		return false, ErrWindowGone
 	}
 	ok, _, _ := procPostMessageW.Call(hwnd, wmSysCommand, scMinimize, 0)
 	if ok != 0 {
@@ -47,7 +49,7 @@ func (m *Win32WindowManager) MinimizeWindow(hwnd uintptr) (bool, error) {
 
 func (m *Win32WindowManager) HideWindow(hwnd uintptr) (bool, error) {
 	if !isWindow(hwnd) {
-		return false, errors.New("target window is not valid")
+		return false, ErrWindowGone
 	}
 	_, _, callErr := procShowWindowAsync.Call(hwnd, swHide)
 	if callErr != nil && callErr != syscall.Errno(0) {
@@ -58,7 +60,7 @@ func (m *Win32WindowManager) HideWindow(hwnd uintptr) (bool, error) {
 
 func (m *Win32WindowManager) CloseWindow(hwnd uintptr) (bool, error) {
 	if !isWindow(hwnd) {
-		return false, errors.New("target window is not valid")
+		return false, ErrWindowGone
 	}
 	ok, _, callErr := procPostMessageW.Call(hwnd, wmSysCommand, scClose, 0)
 	if ok != 0 {
@@ -77,6 +79,26 @@ func (m *Win32WindowManager) CloseWindow(hwnd uintptr) (bool, error) {
 	return true, nil
 }
 
+// RestoreWindow reverses HideWindow: the window is shown, restored if it was
+// minimized and brought to the foreground.
+func (m *Win32WindowManager) RestoreWindow(hwnd uintptr) (bool, error) {
+	if !isWindow(hwnd) {
+		return false, ErrWindowGone
+	}
+	show := uintptr(swShow)
+	if isMin, _, _ := procIsIconic.Call(hwnd); isMin != 0 {
+		show = swRestore
+	}
+	_, _, callErr := procShowWindowAsync.Call(hwnd, show)
+	if callErr != nil && callErr != syscall.Errno(0) {
+		return false, fmt.Errorf("showwindowasync show failed: %w", callErr)
+	}
+	// SetForegroundWindow may be refused by the foreground lock; the window is
+	// visible either way, so this is best effort.
+	_, _, _ = procSetForeground.Call(hwnd)
+	return true, nil
+}
+
 func isWindow(hwnd uintptr) bool {
 	v, _, _ := procIsWindow.Call(hwnd)
 	return v != 0
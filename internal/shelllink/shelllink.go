@@ -0,0 +1,556 @@
+// Package shelllink reads and writes Windows shell link (.lnk) files as
+// described in [MS-SHLLINK]. It does not use COM and works on any platform, so
+// shortcuts can be inspected and tested without Windows.
+//
+// [MS-SHLLINK]: https://learn.microsoft.com/openspecs/windows_protocols/ms-shllink
+package shelllink
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// LinkFlags are the ShellLinkHeader flags that select which structures follow
+// the header.
+type LinkFlags uint32
+
+const (
+	HasLinkTargetIDList   LinkFlags = 1 << 0
+	HasLinkInfo           LinkFlags = 1 << 1
+	HasName               LinkFlags = 1 << 2
+	HasRelativePath       LinkFlags = 1 << 3
+	HasWorkingDir         LinkFlags = 1 << 4
+	HasArguments          LinkFlags = 1 << 5
+	HasIconLocation       LinkFlags = 1 << 6
+	IsUnicode             LinkFlags = 1 << 7
+	ForceNoLinkInfo       LinkFlags = 1 << 8
+	HasExpString          LinkFlags = 1 << 9
+	RunInSeparateProcess  LinkFlags = 1 << 10
+	RunAsUser             LinkFlags = 1 << 13
+	HasExpIcon            LinkFlags = 1 << 14
+	PreferEnvironmentPath LinkFlags = 1 << 25
+)
+
+// Extra data block signatures.
+const (
+	EnvironmentVariableSignature = 0xA0000001
+	IconEnvironmentSignature     = 0xA0000007
+)
+
+const (
+	headerSize = 0x4C
+	// envBlockSize is the size of EnvironmentVariableDataBlock and
+	// IconEnvironmentDataBlock: size, signature, 260 ANSI and 260 UTF-16 chars.
+	envBlockSize = 0x314
+	maxPathChars = 260
+)
+
+// linkCLSID is 00021401-0000-0000-C000-000000000046 in its on-disk byte order.
+var linkCLSID = [16]byte{0x01, 0x14, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0xC0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x46}
+
+var (
+	// ErrNotShellLink is returned for data that does not start with a
+	// ShellLinkHeader.
+	ErrNotShellLink = errors.New("not a shell link")
+	// ErrTruncated is returned when a structure extends past the end of the data.
+	ErrTruncated = errors.New("shell link truncated")
+)
+
+// Header is the fixed-size ShellLinkHeader.
+type Header struct {
+	Flags          LinkFlags
+	FileAttributes uint32
+	CreationTime   time.Time
+	AccessTime     time.Time
+	WriteTime      time.Time
+	FileSize       uint32
+	IconIndex      int32
+	ShowCommand    uint32
+	HotKey         uint16
+}
+
+// LinkInfo locates the link target on a local volume or a network share.
+type LinkInfo struct {
+	DriveType         uint32
+	DriveSerialNumber uint32
+	VolumeLabel       string
+	LocalBasePath     string
+	NetName           string
+	DeviceName        string
+	CommonPathSuffix  string
+}
+
+// Path joins the base path or share name with the common path suffix.
+func (li *LinkInfo) Path() string {
+	base := li.LocalBasePath
+	if base == "" {
+		base = li.NetName
+	}
+	if base == "" || li.CommonPathSuffix == "" {
+		return base
+	}
+	if !strings.HasSuffix(base, `\`) {
+		base += `\`
+	}
+	return base + li.CommonPathSuffix
+}
+
+// ExtraDataBlock is an extra data block this package does not decode.
+type ExtraDataBlock struct {
+	Signature uint32
+	Data      []byte
+}
+
+// Link is a parsed shell link.
+type Link struct {
+	Header Header
+	// IDList holds the raw ItemID entries of the LinkTargetIDList.
+	IDList   [][]byte
+	LinkInfo *LinkInfo
+
+	Name         string
+	RelativePath string
+	WorkingDir   string
+	Arguments    string
+	IconLocation string
+
+	// EnvironmentTarget is the target path from an EnvironmentVariableDataBlock.
+	// It usually contains %VAR% references.
+	EnvironmentTarget string
+	// IconEnvironmentPath is the icon path from an IconEnvironmentDataBlock.
+	IconEnvironmentPath string
+	// ExtraData holds the remaining extra data blocks in file order.
+	ExtraData []ExtraDataBlock
+}
+
+// Target returns the best available path of the link target: the
+// LinkInfo path, then the environment-variable path, then the relative path.
+// Links to shell namespace items such as packaged apps may have none.
+func (l *Link) Target() string {
+	if l.LinkInfo != nil {
+		if p := l.LinkInfo.Path(); p != "" {
+			return p
+		}
+	}
+	if l.EnvironmentTarget != "" {
+		return l.EnvironmentTarget
+	}
+	return l.RelativePath
+}
+
+// ParseFile reads and parses the shell link at path.
+func ParseFile(path string) (*Link, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse parses a shell link.
+func Parse(data []byte) (*Link, error) {
+	r := &reader{data: data}
+	link := &Link{}
+	if err := parseHeader(r, &link.Header); err != nil {
+		return nil, err
+	}
+	flags := link.Header.Flags
+
+	if flags&HasLinkTargetIDList != 0 {
+		items, err := parseIDList(r)
+		if err != nil {
+			return nil, fmt.Errorf("LinkTargetIDList: %w", err)
+		}
+		link.IDList = items
+	}
+	if flags&HasLinkInfo != 0 {
+		info, err := parseLinkInfo(r)
+		if err != nil {
+			return nil, fmt.Errorf("LinkInfo: %w", err)
+		}
+		if flags&ForceNoLinkInfo == 0 {
+			link.LinkInfo = info
+		}
+	}
+
+	strs := []struct {
+		flag LinkFlags
+		dst  *string
+		name string
+	}{
+		{HasName, &link.Name, "NAME_STRING"},
+		{HasRelativePath, &link.RelativePath, "RELATIVE_PATH"},
+		{HasWorkingDir, &link.WorkingDir, "WORKING_DIR"},
+		{HasArguments, &link.Arguments, "COMMAND_LINE_ARGUMENTS"},
+		{HasIconLocation, &link.IconLocation, "ICON_LOCATION"},
+	}
+	for _, s := range strs {
+		if flags&s.flag == 0 {
+			continue
+		}
+		v, err := r.stringData(flags&IsUnicode != 0)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", s.name, err)
+		}
+		*s.dst = v
+	}
+
+	if err := parseExtraData(r, link); err != nil {
+		return nil, fmt.Errorf("ExtraData: %w", err)
+	}
+	return link, nil
+}
+
+func parseHeader(r *reader, h *Header) error {
+	size, err := r.u32()
+	if err != nil || size != headerSize {
+		return ErrNotShellLink
+	}
+	clsid, err := r.bytes(16)
+	if err != nil || !bytes.Equal(clsid, linkCLSID[:]) {
+		return ErrNotShellLink
+	}
+	fixed, err := r.bytes(headerSize - 20)
+	if err != nil {
+		return ErrTruncated
+	}
+	le := binary.LittleEndian
+	h.Flags = LinkFlags(le.Uint32(fixed[0:]))
+	h.FileAttributes = le.Uint32(fixed[4:])
+	h.CreationTime = filetime(le.Uint64(fixed[8:]))
+	h.AccessTime = filetime(le.Uint64(fixed[16:]))
+	h.WriteTime = filetime(le.Uint64(fixed[24:]))
+	h.FileSize = le.Uint32(fixed[32:])
+	h.IconIndex = int32(le.Uint32(fixed[36:]))
+	h.ShowCommand = le.Uint32(fixed[40:])
+	h.HotKey = le.Uint16(fixed[44:])
+	return nil
+}
+
+func parseIDList(r *reader) ([][]byte, error) {
+	size, err := r.u16()
+	if err != nil {
+		return nil, err
+	}
+	list, err := r.bytes(int(size))
+	if err != nil {
+		return nil, err
+	}
+	items := make([][]byte, 0)
+	for len(list) >= 2 {
+		itemSize := int(binary.LittleEndian.Uint16(list))
+		if itemSize == 0 {
+			return items, nil
+		}
+		if itemSize < 2 || itemSize > len(list) {
+			return nil, ErrTruncated
+		}
+		items = append(items, append([]byte(nil), list[2:itemSize]...))
+		list = list[itemSize:]
+	}
+	return nil, errors.New("missing TerminalID")
+}
+
+func parseLinkInfo(r *reader) (*LinkInfo, error) {
+	start := r.off
+	size, err := r.u32()
+	if err != nil {
+		return nil, err
+	}
+	if size < 0x1C {
+		return nil, fmt.Errorf("size %d too small", size)
+	}
+	r.off = start
+	block, err := r.bytes(int(size))
+	if err != nil {
+		return nil, err
+	}
+
+	le := binary.LittleEndian
+	headerLen := le.Uint32(block[4:])
+	flags := le.Uint32(block[8:])
+	volumeIDOffset := le.Uint32(block[12:])
+	localBasePathOffset := le.Uint32(block[16:])
+	networkOffset := le.Uint32(block[20:])
+	suffixOffset := le.Uint32(block[24:])
+	if headerLen < 0x1C || headerLen > size {
+		return nil, fmt.Errorf("header size %d out of range", headerLen)
+	}
+
+	info := &LinkInfo{}
+	const volumeIDAndLocalBasePath, commonNetworkRelativeLink = 1, 2
+	if flags&volumeIDAndLocalBasePath != 0 {
+		if err = parseVolumeID(block, volumeIDOffset, info); err != nil {
+			return nil, fmt.Errorf("VolumeID: %w", err)
+		}
+		if info.LocalBasePath, err = ansiAt(block, localBasePathOffset); err != nil {
+			return nil, fmt.Errorf("LocalBasePath: %w", err)
+		}
+		if headerLen >= 0x24 {
+			if off := le.Uint32(block[28:]); off != 0 {
+				if info.LocalBasePath, err = unicodeAt(block, off); err != nil {
+					return nil, fmt.Errorf("LocalBasePathUnicode: %w", err)
+				}
+			}
+		}
+	}
+	if flags&commonNetworkRelativeLink != 0 {
+		if err = parseNetworkLink(block, networkOffset, info); err != nil {
+			return nil, fmt.Errorf("CommonNetworkRelativeLink: %w", err)
+		}
+	}
+	if info.CommonPathSuffix, err = ansiAt(block, suffixOffset); err != nil {
+		return nil, fmt.Errorf("CommonPathSuffix: %w", err)
+	}
+	if headerLen >= 0x24 {
+		if off := le.Uint32(block[32:]); off != 0 {
+			if info.CommonPathSuffix, err = unicodeAt(block, off); err != nil {
+				return nil, fmt.Errorf("CommonPathSuffixUnicode: %w", err)
+			}
+		}
+	}
+	return info, nil
+}
+
+func parseVolumeID(block []byte, offset uint32, info *LinkInfo) error {
+	vol, err := sub(block, offset, 0x10)
+	if err != nil {
+		return err
+	}
+	le := binary.LittleEndian
+	size := le.Uint32(vol)
+	if size < 0x10 || uint64(offset)+uint64(size) > uint64(len(block)) {
+		return ErrTruncated
+	}
+	vol = block[offset : offset+size]
+	info.DriveType = le.Uint32(vol[4:])
+	info.DriveSerialNumber = le.Uint32(vol[8:])
+	labelOffset := le.Uint32(vol[12:])
+	if labelOffset == 0x14 {
+		if size < 0x14 {
+			return ErrTruncated
+		}
+		info.VolumeLabel, err = unicodeAt(vol, le.Uint32(vol[16:]))
+	} else {
+		info.VolumeLabel, err = ansiAt(vol, labelOffset)
+	}
+	return err
+}
+
+func parseNetworkLink(block []byte, offset uint32, info *LinkInfo) error {
+	link, err := sub(block, offset, 0x14)
+	if err != nil {
+		return err
+	}
+	le := binary.LittleEndian
+	size := le.Uint32(link)
+	if size < 0x14 || uint64(offset)+uint64(size) > uint64(len(block)) {
+		return ErrTruncated
+	}
+	link = block[offset : offset+size]
+	const validDevice = 1
+	flags := le.Uint32(link[4:])
+	netNameOffset := le.Uint32(link[8:])
+	deviceNameOffset := le.Uint32(link[12:])
+
+	if netNameOffset > 0x14 && size >= 0x1C {
+		if info.NetName, err = unicodeAt(link, le.Uint32(link[20:])); err != nil {
+			return err
+		}
+		if flags&validDevice != 0 {
+			info.DeviceName, err = unicodeAt(link, le.Uint32(link[24:]))
+		}
+		return err
+	}
+	if info.NetName, err = ansiAt(link, netNameOffset); err != nil {
+		return err
+	}
+	if flags&validDevice != 0 {
+		info.DeviceName, err = ansiAt(link, deviceNameOffset)
+	}
+	return err
+}
+
+func parseExtraData(r *reader, link *Link) error {
+	for r.off < len(r.data) {
+		size, err := r.u32()
+		if err != nil {
+			return err
+		}
+		if size < 4 {
+			// TerminalBlock.
+			return nil
+		}
+		if size < 8 {
+			return fmt.Errorf("block size %d too small", size)
+		}
+		body, err := r.bytes(int(size) - 4)
+		if err != nil {
+			return err
+		}
+		sig := binary.LittleEndian.Uint32(body)
+		switch {
+		case sig == EnvironmentVariableSignature && size == envBlockSize:
+			link.EnvironmentTarget = envBlockPath(body[4:])
+		case sig == IconEnvironmentSignature && size == envBlockSize:
+			link.IconEnvironmentPath = envBlockPath(body[4:])
+		default:
+			link.ExtraData = append(link.ExtraData, ExtraDataBlock{Signature: sig, Data: append([]byte(nil), body[4:]...)})
+		}
+	}
+	// Some writers omit the TerminalBlock.
+	return nil
+}
+
+// envBlockPath prefers the UTF-16 copy of an environment data block path and
+// falls back to the ANSI one.
+func envBlockPath(data []byte) string {
+	ansi, wide := data[:maxPathChars], data[maxPathChars:maxPathChars*3]
+	if s := decodeUTF16(wide); s != "" {
+		return s
+	}
+	return decodeANSI(ansi)
+}
+
+type reader struct {
+	data []byte
+	off  int
+}
+
+func (r *reader) bytes(n int) ([]byte, error) {
+	if n < 0 || n > len(r.data)-r.off {
+		return nil, ErrTruncated
+	}
+	b := r.data[r.off : r.off+n]
+	r.off += n
+	return b, nil
+}
+
+func (r *reader) u16() (uint16, error) {
+	b, err := r.bytes(2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint16(b), nil
+}
+
+func (r *reader) u32() (uint32, error) {
+	b, err := r.bytes(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+// stringData reads a StringData structure: a character count followed by
+// that many UTF-16 or ANSI characters, without a terminator.
+func (r *reader) stringData(unicode bool) (string, error) {
+	count, err := r.u16()
+	if err != nil {
+		return "", err
+	}
+	if !unicode {
+		b, err := r.bytes(int(count))
+		if err != nil {
+			return "", err
+		}
+		return decodeANSI(b), nil
+	}
+	b, err := r.bytes(int(count) * 2)
+	if err != nil {
+		return "", err
+	}
+	return decodeUTF16(b), nil
+}
+
+// sub returns block[offset:] after checking that at least min bytes remain.
+func sub(block []byte, offset uint32, min int) ([]byte, error) {
+	if uint64(offset)+uint64(min) > uint64(len(block)) {
+		return nil, ErrTruncated
+	}
+	return block[offset:], nil
+}
+
+// ansiAt reads a NUL-terminated ANSI string at offset. Offset 0 means absent.
+func ansiAt(block []byte, offset uint32) (string, error) {
+	if offset == 0 {
+		return "", nil
+	}
+	b, err := sub(block, offset, 1)
+	if err != nil {
+		return "", err
+	}
+	end := bytes.IndexByte(b, 0)
+	if end < 0 {
+		return "", ErrTruncated
+	}
+	return decodeANSI(b[:end]), nil
+}
+
+// unicodeAt reads a NUL-terminated UTF-16 string at offset. Offset 0 means absent.
+func unicodeAt(block []byte, offset uint32) (string, error) {
+	if offset == 0 {
+		return "", nil
+	}
+	b, err := sub(block, offset, 2)
+	if err != nil {
+		return "", err
+	}
+	for i := 0; i+1 < len(b); i += 2 {
+		if b[i] == 0 && b[i+1] == 0 {
+			return decodeUTF16(b[:i]), nil
+		}
+	}
+	return "", ErrTruncated
+}
+
+// decodeUTF16 decodes little-endian UTF-16, stopping at the first NUL.
+func decodeUTF16(b []byte) string {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		u := binary.LittleEndian.Uint16(b[i:])
+		if u == 0 {
+			break
+		}
+		units = append(units, u)
+	}
+	return string(utf16.Decode(units))
+}
+
+// decodeANSI decodes a string in the writer's ANSI code page, stopping at the
+// first NUL. The code page is not recorded in the file; valid UTF-8 is kept
+// and anything else is read as Latin-1, which is exact for ASCII paths.
+func decodeANSI(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	if utf8.Valid(b) {
+		return string(b)
+	}
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+// filetime converts a FILETIME (100ns intervals since 1601) to time.Time. The
+// zero FILETIME maps to the zero time.Time.
+func filetime(ft uint64) time.Time {
+	if ft == 0 {
+		return time.Time{}
+	}
+	const epochDelta = 116444736000000000 // 1601-01-01 to 1970-01-01 in 100ns
+	if ft < epochDelta {
+		return time.Time{}
+	}
+	ticks := ft - epochDelta
+	return time.Unix(int64(ticks/1e7), int64(ticks%1e7)*100).UTC()
+}
@@ -0,0 +1,183 @@
+package shelllink
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseLocalLink(t *testing.T) {
+	link, err := ParseFile(filepath.Join("testdata", "local.lnk"))
+	if err != nil {
+		t.Fatalf("ParseFile: %v", err)
+	}
+
+	wantTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if !link.Header.WriteTime.Equal(wantTime) {
+		t.Fatalf("WriteTime = %v, want %v", link.Header.WriteTime, wantTime)
+	}
+	if link.Header.IconIndex != 2 || link.Header.ShowCommand != 7 || link.Header.HotKey != 0x0641 || link.Header.FileSize != 12345 {
+		t.Fatalf("unexpected header %+v", link.Header)
+	}
+	if link.Header.Flags&IsUnicode == 0 {
+		t.Fatalf("expected IsUnicode flag")
+	}
+	if len(link.IDList) != 2 || link.IDList[0][0] != 0x1F || link.IDList[1][0] != '/' {
+		t.Fatalf("unexpected IDList %q", link.IDList)
+	}
+	if link.LinkInfo == nil {
+		t.Fatalf("expected LinkInfo")
+	}
+	if link.LinkInfo.VolumeLabel != "SYSTEM" || link.LinkInfo.DriveType != 3 || link.LinkInfo.DriveSerialNumber != 0x1234ABCD {
+		t.Fatalf("unexpected volume %+v", link.LinkInfo)
+	}
+
+	checks := []struct{ name, got, want string }{
+		{"Target", link.Target(), `C:\Program Files\Example App\app.exe`},
+		{"Name", link.Name, "Example App – Tray"},
+		{"RelativePath", link.RelativePath, `..\..\..\Program Files\Example App\app.exe`},
+		{"WorkingDir", link.WorkingDir, `C:\Program Files\Example App`},
+		{"Arguments", link.Arguments, `--tray --profile "Work Profile"`},
+		{"IconLocation", link.IconLocation, `%ProgramFiles%\Example App\app.ico`},
+		{"IconEnvironmentPath", link.IconEnvironmentPath, `%ProgramFiles%\Example App\app.ico`},
+		{"EnvironmentTarget", link.EnvironmentTarget, ""},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %q, want %q", c.name, c.got, c.want)
+		}
+	}
+	if len(link.ExtraData) != 1 || link.ExtraData[0].Signature != 0xA0000003 || string(link.ExtraData[0].Data) != "tracker-data-here" {
+		t.Fatalf("unexpected extra data %+v", link.ExtraData)
+	}
+}
+
+func TestParseEnvironmentLink(t *testing.T) {
+	link, err := ParseFile(filepath.Join("testdata", "env.lnk"))
+	if err != nil {
+		t.Fatalf("ParseFile: %v", err)
+	}
+	if link.LinkInfo != nil || link.IDList != nil {
+		t.Fatalf("expected no LinkInfo or IDList, got %+v", link)
+	}
+	if got, want := link.Target(), `%windir%\system32\notepad.exe`; got != want {
+		t.Fatalf("Target = %q, want %q", got, want)
+	}
+	if link.WorkingDir != "%USERPROFILE%" {
+		t.Fatalf("WorkingDir = %q", link.WorkingDir)
+	}
+	if link.Arguments != "/min café.txt" {
+		t.Fatalf("Arguments = %q", link.Arguments)
+	}
+}
+
+func TestParseNetworkLink(t *testing.T) {
+	link, err := ParseFile(filepath.Join("testdata", "network.lnk"))
+	if err != nil {
+		t.Fatalf("ParseFile: %v", err)
+	}
+	if link.LinkInfo == nil || link.LinkInfo.NetName != `\\fileserver\tools` || link.LinkInfo.DeviceName != "Z:" {
+		t.Fatalf("unexpected LinkInfo %+v", link.LinkInfo)
+	}
+	if got, want := link.Target(), `\\fileserver\tools\sync\run.exe`; got != want {
+		t.Fatalf("Target = %q, want %q", got, want)
+	}
+}
+
+func TestParseUnicodeLinkInfo(t *testing.T) {
+	link, err := ParseFile(filepath.Join("testdata", "unicode_base.lnk"))
+	if err != nil {
+		t.Fatalf("ParseFile: %v", err)
+	}
+	if got, want := link.Target(), `C:\Apps\同步工具.exe`; got != want {
+		t.Fatalf("Target = %q, want %q", got, want)
+	}
+}
+
+func TestParseRejectsInvalidData(t *testing.T) {
+	valid, err := os.ReadFile(filepath.Join("testdata", "local.lnk"))
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if _, err := Parse([]byte("MZ not a link")); !errors.Is(err, ErrNotShellLink) {
+		t.Fatalf("expected ErrNotShellLink, got %v", err)
+	}
+	if _, err := Parse(valid[:headerSize+10]); !errors.Is(err, ErrTruncated) {
+		t.Fatalf("expected ErrTruncated, got %v", err)
+	}
+	for n := 0; n < len(valid); n++ {
+		// Must not panic on any truncation.
+		_, _ = Parse(valid[:n])
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	files, _ := filepath.Glob(filepath.Join("testdata", "*.lnk"))
+	for _, name := range files {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			f.Fatalf("read %s: %v", name, err)
+		}
+		f.Add(data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		link, err := Parse(data)
+		if err != nil {
+			return
+		}
+		_ = link.Target()
+	})
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	for _, name := range []string{"local.lnk", "env.lnk", "network.lnk", "unicode_base.lnk"} {
+		t.Run(name, func(t *testing.T) {
+			want, err := ParseFile(filepath.Join("testdata", name))
+			if err != nil {
+				t.Fatalf("ParseFile: %v", err)
+			}
+			data, err := Encode(want)
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			got, err := Parse(data)
+			if err != nil {
+				t.Fatalf("Parse(Encode): %v", err)
+			}
+			if got.Header.Flags&^IsUnicode != want.Header.Flags&^IsUnicode || got.Header.Flags&IsUnicode == 0 {
+				t.Fatalf("flags = %#x, want %#x with IsUnicode", got.Header.Flags, want.Header.Flags)
+			}
+			got.Header.Flags = want.Header.Flags
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("round trip mismatch\n got: %+v\nwant: %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestEncodeMinimalLink(t *testing.T) {
+	want := &Link{
+		Header:       Header{ShowCommand: 7},
+		LinkInfo:     &LinkInfo{DriveType: 3, LocalBasePath: `D:\Portable\WinTray – ß\wintray.exe`},
+		WorkingDir:   `D:\Portable\WinTray – ß`,
+		Arguments:    "--background --autorun",
+		IconLocation: `D:\Portable\WinTray – ß\wintray.exe`,
+	}
+	path := filepath.Join(t.TempDir(), "WinTray.lnk")
+	if err := WriteFile(path, want); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	got, err := ParseFile(path)
+	if err != nil {
+		t.Fatalf("ParseFile: %v", err)
+	}
+	if got.Target() != want.LinkInfo.LocalBasePath || got.Arguments != want.Arguments || got.WorkingDir != want.WorkingDir {
+		t.Fatalf("unexpected link %+v", got)
+	}
+	if got.Header.ShowCommand != 7 || got.IconLocation != want.IconLocation {
+		t.Fatalf("unexpected header or icon %+v", got)
+	}
+}
@@ -0,0 +1,145 @@
+#!/usr/bin/env python3
+"""Generates the sample shell links used by the shelllink tests.
+
+The files follow [MS-SHLLINK] and cover the structures the parser reads:
+LinkTargetIDList, LinkInfo (local and network, ANSI and Unicode offsets),
+StringData in both encodings and the environment extra data blocks.
+
+Run from this directory: python3 gen_links.py
+"""
+import struct
+
+CLSID = bytes.fromhex("0114020000000000c000000000000046")
+
+HAS_ID_LIST = 1 << 0
+HAS_LINK_INFO = 1 << 1
+HAS_NAME = 1 << 2
+HAS_RELATIVE_PATH = 1 << 3
+HAS_WORKING_DIR = 1 << 4
+HAS_ARGUMENTS = 1 << 5
+HAS_ICON_LOCATION = 1 << 6
+IS_UNICODE = 1 << 7
+HAS_EXP_STRING = 1 << 9
+HAS_EXP_ICON = 1 << 14
+
+# 2024-01-02 03:04:05 UTC as a FILETIME.
+FILETIME = (1704164645 * 10_000_000) + 116444736000000000
+
+
+def header(flags, icon_index=0, show=1, hotkey=0, size=12345):
+    return struct.pack(
+        "<I16sIIQQQIiIHHII",
+        0x4C, CLSID, flags, 0x20, FILETIME, FILETIME, FILETIME,
+        size, icon_index, show, hotkey, 0, 0, 0,
+    )
+
+
+def id_list(items):
+    body = b"".join(struct.pack("<H", len(i) + 2) + i for i in items) + b"\0\0"
+    return struct.pack("<H", len(body)) + body
+
+
+def string_data(s, unicode):
+    if unicode:
+        return struct.pack("<H", len(s)) + s.encode("utf-16-le")
+    data = s.encode("cp1252")
+    return struct.pack("<H", len(data)) + data
+
+
+def volume_id(label):
+    data = label.encode("cp1252") + b"\0"
+    return struct.pack("<IIII", 0x10 + len(data), 3, 0x1234ABCD, 0x10) + data
+
+
+def link_info_local(base, suffix, unicode_base=None):
+    vol = volume_id("SYSTEM")
+    header_len = 0x24 if unicode_base is not None else 0x1C
+    vol_off = header_len
+    base_off = vol_off + len(vol)
+    base_bytes = base.encode("cp1252") + b"\0"
+    suffix_off = base_off + len(base_bytes)
+    suffix_bytes = suffix.encode("cp1252") + b"\0"
+    tail = vol + base_bytes + suffix_bytes
+    extra = b""
+    if unicode_base is not None:
+        ubase_off = suffix_off + len(suffix_bytes)
+        ubase = unicode_base.encode("utf-16-le") + b"\0\0"
+        tail += ubase
+        extra = struct.pack("<II", ubase_off, 0)
+    fields = struct.pack("<IIIII", 1, vol_off, base_off, 0, suffix_off) + extra
+    size = 8 + len(fields) + len(tail)
+    return struct.pack("<II", size, header_len) + fields + tail
+
+
+def link_info_network(net_name, device, suffix):
+    net = net_name.encode("cp1252") + b"\0"
+    dev = device.encode("cp1252") + b"\0"
+    cnrl = struct.pack("<IIIII", 0x14 + len(net) + len(dev), 1, 0x14, 0x14 + len(net), 0x20000) + net + dev
+    header_len = 0x1C
+    cnrl_off = header_len
+    suffix_off = cnrl_off + len(cnrl)
+    suffix_bytes = suffix.encode("cp1252") + b"\0"
+    fields = struct.pack("<IIIII", 2, 0, 0, cnrl_off, suffix_off)
+    size = 8 + len(fields) + len(cnrl) + len(suffix_bytes)
+    return struct.pack("<II", size, header_len) + fields + cnrl + suffix_bytes
+
+
+def env_block(signature, path):
+    ansi = path.encode("cp1252").ljust(260, b"\0")
+    wide = path.encode("utf-16-le").ljust(520, b"\0")
+    return struct.pack("<II", 0x314, signature) + ansi + wide
+
+
+def unknown_block(signature, payload):
+    return struct.pack("<II", 8 + len(payload), signature) + payload
+
+
+TERMINAL = b"\0\0\0\0"
+
+# A typical Start menu shortcut to a local program.
+local = (
+    header(HAS_ID_LIST | HAS_LINK_INFO | HAS_NAME | HAS_RELATIVE_PATH | HAS_WORKING_DIR
+           | HAS_ARGUMENTS | HAS_ICON_LOCATION | IS_UNICODE | HAS_EXP_ICON, icon_index=2, show=7, hotkey=0x0641)
+    + id_list([b"\x1fP\xe0O\xd0 \xea:i\x10\xa2\xd8\x08\x00+00\x9d", b"/C:\\" + b"\0" * 19])
+    + link_info_local("C:\\Program Files\\Example App\\app.exe", "")
+    + string_data("Example App – Tray", True)
+    + string_data("..\\..\\..\\Program Files\\Example App\\app.exe", True)
+    + string_data("C:\\Program Files\\Example App", True)
+    + string_data('--tray --profile "Work Profile"', True)
+    + string_data("%ProgramFiles%\\Example App\\app.ico", True)
+    + env_block(0xA0000007, "%ProgramFiles%\\Example App\\app.ico")
+    + unknown_block(0xA0000003, b"tracker-data-here")
+    + TERMINAL
+)
+
+# An ANSI shortcut that only knows its target through environment variables.
+env = (
+    header(HAS_WORKING_DIR | HAS_ARGUMENTS | HAS_EXP_STRING)
+    + string_data("%USERPROFILE%", False)
+    + string_data("/min caf\xe9.txt", False)
+    + env_block(0xA0000001, "%windir%\\system32\\notepad.exe")
+    + TERMINAL
+)
+
+# A shortcut to a program on a network share, with a Unicode-capable LinkInfo.
+network = (
+    header(HAS_LINK_INFO | IS_UNICODE | HAS_RELATIVE_PATH)
+    + link_info_network("\\\\fileserver\\tools", "Z:", "sync\\run.exe")
+    + string_data("Z:\\sync\\run.exe", True)
+    + TERMINAL
+)
+
+# LinkInfo with a Unicode base path that the ANSI code page cannot represent.
+unicode_base = (
+    header(HAS_LINK_INFO | IS_UNICODE)
+    + link_info_local("C:\\Apps\\????.exe", "", unicode_base="C:\\Apps\\同步工具.exe")
+)
+
+for name, data in [
+    ("local.lnk", local),
+    ("env.lnk", env),
+    ("network.lnk", network),
+    ("unicode_base.lnk", unicode_base),
+]:
+    with open(name, "wb") as f:
+        f.write(data)
@@ -0,0 +1,295 @@
+package shelllink
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"time"
+	"unicode/utf16"
+)
+
+// structureFlags are the header flags Encode derives from the Link fields.
+const structureFlags = HasLinkTargetIDList | HasLinkInfo | HasName | HasRelativePath |
+	HasWorkingDir | HasArguments | HasIconLocation | IsUnicode | ForceNoLinkInfo |
+	HasExpString | HasExpIcon
+
+// Encode serializes link. Strings are always written as UTF-16. The header
+// flags that select structures are derived from the fields that are set;
+// other flags in link.Header.Flags are kept.
+func Encode(link *Link) ([]byte, error) {
+	var buf bytes.Buffer
+	flags := link.Header.Flags&^structureFlags | IsUnicode
+	if len(link.IDList) > 0 {
+		flags |= HasLinkTargetIDList
+	}
+	if link.LinkInfo != nil {
+		flags |= HasLinkInfo
+	}
+	strs := []struct {
+		flag  LinkFlags
+		value string
+	}{
+		{HasName, link.Name},
+		{HasRelativePath, link.RelativePath},
+		{HasWorkingDir, link.WorkingDir},
+		{HasArguments, link.Arguments},
+		{HasIconLocation, link.IconLocation},
+	}
+	for _, s := range strs {
+		if s.value != "" {
+			flags |= s.flag
+		}
+	}
+	if link.EnvironmentTarget != "" {
+		flags |= HasExpString
+	}
+	if link.IconEnvironmentPath != "" {
+		flags |= HasExpIcon
+	}
+
+	writeHeader(&buf, link.Header, flags)
+	if flags&HasLinkTargetIDList != 0 {
+		if err := writeIDList(&buf, link.IDList); err != nil {
+			return nil, err
+		}
+	}
+	if flags&HasLinkInfo != 0 {
+		writeLinkInfo(&buf, link.LinkInfo)
+	}
+	for _, s := range strs {
+		if s.value == "" {
+			continue
+		}
+		units := utf16.Encode([]rune(s.value))
+		if len(units) > 0xFFFF {
+			return nil, fmt.Errorf("string of %d characters too long", len(units))
+		}
+		putU16(&buf, uint16(len(units)))
+		for _, u := range units {
+			putU16(&buf, u)
+		}
+	}
+
+	if link.EnvironmentTarget != "" {
+		if err := writeEnvBlock(&buf, EnvironmentVariableSignature, link.EnvironmentTarget); err != nil {
+			return nil, fmt.Errorf("EnvironmentVariableDataBlock: %w", err)
+		}
+	}
+	if link.IconEnvironmentPath != "" {
+		if err := writeEnvBlock(&buf, IconEnvironmentSignature, link.IconEnvironmentPath); err != nil {
+			return nil, fmt.Errorf("IconEnvironmentDataBlock: %w", err)
+		}
+	}
+	for _, block := range link.ExtraData {
+		putU32(&buf, uint32(8+len(block.Data)))
+		putU32(&buf, block.Signature)
+		buf.Write(block.Data)
+	}
+	// TerminalBlock.
+	putU32(&buf, 0)
+	return buf.Bytes(), nil
+}
+
+// WriteFile encodes link and writes it to path.
+func WriteFile(path string, link *Link) error {
+	data, err := Encode(link)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func writeHeader(buf *bytes.Buffer, h Header, flags LinkFlags) {
+	putU32(buf, headerSize)
+	buf.Write(linkCLSID[:])
+	putU32(buf, uint32(flags))
+	putU32(buf, h.FileAttributes)
+	putU64(buf, toFiletime(h.CreationTime))
+	putU64(buf, toFiletime(h.AccessTime))
+	putU64(buf, toFiletime(h.WriteTime))
+	putU32(buf, h.FileSize)
+	putU32(buf, uint32(h.IconIndex))
+	putU32(buf, h.ShowCommand)
+	putU16(buf, h.HotKey)
+	// Reserved1-3.
+	buf.Write(make([]byte, 10))
+}
+
+func writeIDList(buf *bytes.Buffer, items [][]byte) error {
+	size := 2
+	for _, item := range items {
+		size += 2 + len(item)
+		if 2+len(item) > 0xFFFF {
+			return fmt.Errorf("ItemID of %d bytes too long", len(item))
+		}
+	}
+	if size > 0xFFFF {
+		return fmt.Errorf("LinkTargetIDList of %d bytes too long", size)
+	}
+	putU16(buf, uint16(size))
+	for _, item := range items {
+		putU16(buf, uint16(2+len(item)))
+		buf.Write(item)
+	}
+	putU16(buf, 0)
+	return nil
+}
+
+// writeLinkInfo writes a LinkInfo with the optional Unicode offsets, so that
+// paths outside the ANSI code page survive. The ANSI copies replace such
+// characters with '?'.
+func writeLinkInfo(buf *bytes.Buffer, info *LinkInfo) {
+	const headerLen = 0x24
+	const volumeIDAndLocalBasePath, commonNetworkRelativeLink = 1, 2
+
+	var body bytes.Buffer
+	offset := func() uint32 { return uint32(headerLen + body.Len()) }
+
+	var flags, volumeIDOffset, basePathOffset, networkOffset, basePathUnicodeOffset uint32
+	if info.LocalBasePath != "" {
+		flags |= volumeIDAndLocalBasePath
+		volumeIDOffset = offset()
+		writeVolumeID(&body, info)
+		basePathOffset = offset()
+		body.Write(ansiZ(info.LocalBasePath))
+	}
+	if info.NetName != "" {
+		flags |= commonNetworkRelativeLink
+		networkOffset = offset()
+		writeNetworkLink(&body, info)
+	}
+	suffixOffset := offset()
+	body.Write(ansiZ(info.CommonPathSuffix))
+	if info.LocalBasePath != "" {
+		basePathUnicodeOffset = offset()
+		body.Write(unicodeZ(info.LocalBasePath))
+	}
+	suffixUnicodeOffset := offset()
+	body.Write(unicodeZ(info.CommonPathSuffix))
+
+	putU32(buf, uint32(headerLen+body.Len()))
+	putU32(buf, headerLen)
+	putU32(buf, flags)
+	putU32(buf, volumeIDOffset)
+	putU32(buf, basePathOffset)
+	putU32(buf, networkOffset)
+	putU32(buf, suffixOffset)
+	putU32(buf, basePathUnicodeOffset)
+	putU32(buf, suffixUnicodeOffset)
+	buf.Write(body.Bytes())
+}
+
+// writeVolumeID writes a VolumeID whose label uses the Unicode offset.
+func writeVolumeID(buf *bytes.Buffer, info *LinkInfo) {
+	const fixed = 0x14
+	label := unicodeZ(info.VolumeLabel)
+	putU32(buf, uint32(fixed+len(label)))
+	putU32(buf, info.DriveType)
+	putU32(buf, info.DriveSerialNumber)
+	// VolumeLabelOffset 0x14 means the Unicode offset follows.
+	putU32(buf, fixed)
+	putU32(buf, fixed)
+	buf.Write(label)
+}
+
+// writeNetworkLink writes a CommonNetworkRelativeLink with Unicode names.
+func writeNetworkLink(buf *bytes.Buffer, info *LinkInfo) {
+	const fixed = 0x1C
+	const validDevice = 1
+	netAnsi, deviceAnsi := ansiZ(info.NetName), ansiZ(info.DeviceName)
+	netWide, deviceWide := unicodeZ(info.NetName), unicodeZ(info.DeviceName)
+
+	var flags, deviceOffset, deviceUnicodeOffset uint32
+	netOffset := uint32(fixed)
+	netUnicodeOffset := netOffset + uint32(len(netAnsi))
+	next := netUnicodeOffset + uint32(len(netWide))
+	if info.DeviceName != "" {
+		flags |= validDevice
+		deviceOffset = next
+		deviceUnicodeOffset = deviceOffset + uint32(len(deviceAnsi))
+		next = deviceUnicodeOffset + uint32(len(deviceWide))
+	}
+
+	putU32(buf, next)
+	putU32(buf, flags)
+	putU32(buf, netOffset)
+	putU32(buf, deviceOffset)
+	// NetworkProviderType, unused without ValidNetType.
+	putU32(buf, 0)
+	putU32(buf, netUnicodeOffset)
+	putU32(buf, deviceUnicodeOffset)
+	buf.Write(netAnsi)
+	buf.Write(netWide)
+	if info.DeviceName != "" {
+		buf.Write(deviceAnsi)
+		buf.Write(deviceWide)
+	}
+}
+
+func writeEnvBlock(buf *bytes.Buffer, signature uint32, path string) error {
+	wide := utf16.Encode([]rune(path))
+	if len(wide) >= maxPathChars {
+		return fmt.Errorf("path of %d characters too long", len(wide))
+	}
+	putU32(buf, envBlockSize)
+	putU32(buf, signature)
+	ansi := make([]byte, maxPathChars)
+	copy(ansi, ansiZ(path))
+	buf.Write(ansi)
+	units := make([]byte, maxPathChars*2)
+	for i, u := range wide {
+		binary.LittleEndian.PutUint16(units[i*2:], u)
+	}
+	buf.Write(units)
+	return nil
+}
+
+// ansiZ encodes s as NUL-terminated ASCII, replacing other characters with
+// '?'. The Unicode copy written alongside carries the exact string.
+func ansiZ(s string) []byte {
+	b := make([]byte, 0, len(s)+1)
+	for _, r := range s {
+		if r >= 0x80 {
+			r = '?'
+		}
+		b = append(b, byte(r))
+	}
+	return append(b, 0)
+}
+
+func unicodeZ(s string) []byte {
+	units := utf16.Encode([]rune(s))
+	b := make([]byte, 2*len(units)+2)
+	for i, u := range units {
+		binary.LittleEndian.PutUint16(b[i*2:], u)
+	}
+	return b
+}
+
+// toFiletime is the inverse of filetime.
+func toFiletime(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	const epochDelta = 116444736000000000
+	return uint64(t.UnixNano()/100) + epochDelta
+}
+
+func putU16(buf *bytes.Buffer, v uint16) {
+	var b [2]byte
+	binary.LittleEndian.PutUint16(b[:], v)
+	buf.Write(b[:])
+}
+
+func putU32(buf *bytes.Buffer, v uint32) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	buf.Write(b[:])
+}
+
+func putU64(buf *bytes.Buffer, v uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	buf.Write(b[:])
+}
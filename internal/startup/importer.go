@@ -0,0 +1,400 @@
+package startup
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"wintray/internal/cmdline"
+	"wintray/internal/config"
+	"wintray/internal/shelllink"
+	"wintray/internal/stringutil"
+)
+
+// Scope selects the per-user or the machine-wide startup locations.
+type Scope string
+
+const (
+	// ScopeUser is HKCU and the per-user Startup folder.
+	ScopeUser Scope = "user"
+	// ScopeMachine is HKLM and the common Startup folder.
+	ScopeMachine Scope = "machine"
+)
+
+// Origin is where a startup item is registered.
+type Origin string
+
+const (
+	OriginRunKey        Origin = "run"
+	OriginStartupFolder Origin = "startupFolder"
+)
+
+const (
+	runKeyPath = `SOFTWARE\Microsoft\Windows\CurrentVersion\Run`
+
+	// Task Manager's Startup tab records enabled/disabled state under these
+	// keys, one REG_BINARY value per item, without touching the item itself.
+	approvedRunPath    = `SOFTWARE\Microsoft\Windows\CurrentVersion\Explorer\StartupApproved\Run`
+	approvedFolderPath = `SOFTWARE\Microsoft\Windows\CurrentVersion\Explorer\StartupApproved\StartupFolder`
+
+	// approvedDisabled is the first byte Task Manager writes when disabling an
+	// item. Odd first bytes mean disabled.
+	approvedDisabled = 0x03
+)
+
+// ErrNoJournal is returned by LoadJournal when there is no import to undo.
+var ErrNoJournal = errors.New("no startup import to undo")
+
+// Registry is the registry access the importer needs.
+type Registry interface {
+	// StringValues returns the string values of a key; REG_EXPAND_SZ values
+	// are not expanded. A missing key has no values.
+	StringValues(scope Scope, path string) (map[string]string, error)
+	// BinaryValue returns a value's data; ok is false when it does not exist.
+	BinaryValue(scope Scope, path, name string) (data []byte, ok bool, err error)
+	SetBinaryValue(scope Scope, path, name string, data []byte) error
+	SetStringValue(scope Scope, path, name, value string) error
+	DeleteValue(scope Scope, path, name string) error
+}
+
+// FileSystem is the file access the importer needs.
+type FileSystem interface {
+	StartupFolder(scope Scope) (string, error)
+	// ReadDir returns the file names in dir. A missing dir has no files.
+	ReadDir(dir string) ([]string, error)
+	ReadFile(path string) ([]byte, error)
+	// WriteFile creates or replaces path, creating its directory if needed.
+	WriteFile(path string, data []byte) error
+	// Remove deletes path. A missing file is not an error.
+	Remove(path string) error
+	ExpandEnv(s string) string
+}
+
+// Item is an existing startup entry found by Scan.
+type Item struct {
+	Origin Origin
+	Scope  Scope
+	// Name is the Run value name or the file name in the Startup folder.
+	Name string
+	// Command is the Run command line or the path of the Startup folder file.
+	Command    string
+	Target     string
+	Args       []string
+	WorkingDir string
+	// Enabled is false when the item is disabled in Task Manager.
+	Enabled bool
+}
+
+// approvedKey returns the StartupApproved value holding the item's state.
+func (it Item) approvedKey() (string, string) {
+	if it.Origin == OriginStartupFolder {
+		return approvedFolderPath, it.Name
+	}
+	return approvedRunPath, it.Name
+}
+
+// Journal records the StartupApproved values changed by Disable so that Undo
+// can put them back.
+type Journal struct {
+	CreatedAt time.Time       `json:"createdAt"`
+	Changes   []JournalChange `json:"changes"`
+}
+
+type JournalChange struct {
+	Scope Scope  `json:"scope"`
+	Path  string `json:"path"`
+	Name  string `json:"name"`
+	// Existed is false when Disable created the value; Undo then deletes it.
+	Existed  bool   `json:"existed"`
+	Previous []byte `json:"previous,omitempty"`
+}
+
+func (j *Journal) has(scope Scope, path, name string) bool {
+	for _, c := range j.Changes {
+		if c.Scope == scope && strings.EqualFold(c.Path, path) && strings.EqualFold(c.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Importer converts Run key and Startup folder items into managed entries.
+type Importer struct {
+	reg Registry
+	fs  FileSystem
+}
+
+func NewImporter(reg Registry, fs FileSystem) *Importer {
+	return &Importer{reg: reg, fs: fs}
+}
+
+// Scan lists the startup items of both scopes. Locations that cannot be read
+// are reported in the returned error alongside the items that could.
+func (im *Importer) Scan() ([]Item, error) {
+	var items []Item
+	var errs []error
+	for _, scope := range []Scope{ScopeUser, ScopeMachine} {
+		runItems, err := im.scanRunKey(scope)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s run key: %w", scope, err))
+		}
+		items = append(items, runItems...)
+
+		folderItems, err := im.scanStartupFolder(scope)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s startup folder: %w", scope, err))
+		}
+		items = append(items, folderItems...)
+	}
+	return items, errors.Join(errs...)
+}
+
+func (im *Importer) scanRunKey(scope Scope) ([]Item, error) {
+	values, err := im.reg.StringValues(scope, runKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	items := make([]Item, 0, len(names))
+	for _, name := range names {
+		command := values[name]
+		target, args := splitCommand(im.fs.ExpandEnv(command))
+		if target == "" {
+			continue
+		}
+		item := Item{Origin: OriginRunKey, Scope: scope, Name: name, Command: command, Target: target, Args: args}
+		item.Enabled = im.approved(item)
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (im *Importer) scanStartupFolder(scope Scope) ([]Item, error) {
+	dir, err := im.fs.StartupFolder(scope)
+	if err != nil || dir == "" {
+		return nil, err
+	}
+	names, err := im.fs.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	items := make([]Item, 0, len(names))
+	for _, name := range names {
+		if strings.EqualFold(name, "desktop.ini") {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		item := Item{Origin: OriginStartupFolder, Scope: scope, Name: name, Command: path, Target: path}
+		if strings.EqualFold(filepath.Ext(name), ".lnk") {
+			im.readShortcut(&item)
+		}
+		item.Enabled = im.approved(item)
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// readShortcut fills item from the .lnk file it points to. Links without a
+// file target keep the .lnk path and are launched as shortcuts.
+func (im *Importer) readShortcut(item *Item) {
+	data, err := im.fs.ReadFile(item.Command)
+	if err != nil {
+		return
+	}
+	link, err := shelllink.Parse(data)
+	if err != nil {
+		return
+	}
+	target := im.fs.ExpandEnv(link.Target())
+	if target == "" {
+		return
+	}
+	item.Target = target
+	item.Args = cmdline.Split(link.Arguments)
+	item.WorkingDir = im.fs.ExpandEnv(link.WorkingDir)
+}
+
+func (im *Importer) approved(item Item) bool {
+	return approved(im.reg, item)
+}
+
+// approved reports whether item is enabled in Task Manager.
+func approved(reg Registry, item Item) bool {
+	path, name := item.approvedKey()
+	data, ok, err := reg.BinaryValue(item.Scope, path, name)
+	if err != nil || !ok || len(data) == 0 {
+		return true
+	}
+	return data[0]&1 == 0
+}
+
+// Disable marks items as disabled in Task Manager, recording the previous
+// state in journal. Items that are already disabled are left alone, and a
+// value already in journal keeps its earliest recorded state.
+func (im *Importer) Disable(items []Item, journal *Journal, now time.Time) error {
+	if journal.CreatedAt.IsZero() {
+		journal.CreatedAt = now
+	}
+	var errs []error
+	for _, item := range items {
+		if !item.Enabled {
+			continue
+		}
+		path, name := item.approvedKey()
+		previous, existed, err := im.reg.BinaryValue(item.Scope, path, name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", item.Name, err))
+			continue
+		}
+		if err = im.reg.SetBinaryValue(item.Scope, path, name, disabledValue(now)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", item.Name, err))
+			continue
+		}
+		if !journal.has(item.Scope, path, name) {
+			journal.Changes = append(journal.Changes, JournalChange{
+				Scope:    item.Scope,
+				Path:     path,
+				Name:     name,
+				Existed:  existed,
+				Previous: previous,
+			})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Undo restores the values recorded in journal, newest first. Changes that
+// could not be restored remain in journal.
+func (im *Importer) Undo(journal *Journal) error {
+	var errs []error
+	var remaining []JournalChange
+	for i := len(journal.Changes) - 1; i >= 0; i-- {
+		c := journal.Changes[i]
+		var err error
+		if c.Existed {
+			err = im.reg.SetBinaryValue(c.Scope, c.Path, c.Name, c.Previous)
+		} else {
+			err = im.reg.DeleteValue(c.Scope, c.Path, c.Name)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.Name, err))
+			remaining = append([]JournalChange{c}, remaining...)
+		}
+	}
+	journal.Changes = remaining
+	return errors.Join(errs...)
+}
+
+// disabledValue is the StartupApproved data Task Manager writes when an item
+// is disabled: a flag followed by the FILETIME of the change.
+func disabledValue(now time.Time) []byte {
+	data := make([]byte, 12)
+	data[0] = approvedDisabled
+	// FILETIME counts 100ns intervals since 1601-01-01.
+	const epochDelta = 116444736000000000
+	binary.LittleEndian.PutUint64(data[4:], uint64(now.UnixNano()/100+epochDelta))
+	return data
+}
+
+// splitCommand splits a Run command into the program and its arguments.
+// Run values often leave paths with spaces unquoted, which CreateProcess
+// resolves by trying longer and longer prefixes; the first ".exe" followed
+// by a space or the end is taken as the end of the program in that case.
+func splitCommand(command string) (string, []string) {
+	command = strings.TrimSpace(command)
+	if command == "" {
+		return "", nil
+	}
+	if !strings.HasPrefix(command, `"`) {
+		lower := strings.ToLower(command)
+		for start := 0; ; {
+			i := strings.Index(lower[start:], ".exe")
+			if i < 0 {
+				break
+			}
+			end := start + i + len(".exe")
+			if end == len(command) || command[end] == ' ' || command[end] == '\t' {
+				if strings.ContainsAny(command[:end], " \t") {
+					return command[:end], nonNil(cmdline.Split(command[end:]))
+				}
+				break
+			}
+			start = end
+		}
+	}
+	args := cmdline.Split(command)
+	if len(args) == 0 {
+		return "", nil
+	}
+	return args[0], nonNil(args[1:])
+}
+
+func nonNil(args []string) []string {
+	if args == nil {
+		return []string{}
+	}
+	return args
+}
+
+// ToManagedEntry converts item into a managed entry with the defaults of a
+// newly added program. Windows are matched by process name, then title.
+func ToManagedEntry(item Item, id string) config.ManagedAppEntry {
+	name := stringutil.TrimExt(item.Name)
+	if name == "" {
+		name = stringutil.TrimExt(filepath.Base(item.Target))
+	}
+	entry := config.NewManagedAppEntry(id, name, item.Target)
+	entry.Args = append([]string{}, item.Args...)
+	entry.WorkingDir = item.WorkingDir
+	return entry
+}
+
+// LoadJournal reads the undo journal at path.
+func LoadJournal(path string) (Journal, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Journal{}, ErrNoJournal
+		}
+		return Journal{}, err
+	}
+	var journal Journal
+	if err = json.Unmarshal(data, &journal); err != nil {
+		return Journal{}, err
+	}
+	if len(journal.Changes) == 0 {
+		return Journal{}, ErrNoJournal
+	}
+	return journal, nil
+}
+
+// SaveJournal writes journal to path, removing the file once it is empty.
+func SaveJournal(path string, journal Journal) error {
+	if len(journal.Changes) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(journal, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
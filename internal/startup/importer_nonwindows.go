@@ -0,0 +1,27 @@
+//go:build !windows
+
+package startup
+
+// NewSystemImporter returns an Importer that finds no startup items.
+func NewSystemImporter() *Importer {
+	return NewImporter(noRegistry{}, noFiles{})
+}
+
+type noRegistry struct{}
+
+func (noRegistry) StringValues(Scope, string) (map[string]string, error) { return nil, nil }
+func (noRegistry) BinaryValue(Scope, string, string) ([]byte, bool, error) {
+	return nil, false, nil
+}
+func (noRegistry) SetBinaryValue(Scope, string, string, []byte) error { return nil }
+func (noRegistry) SetStringValue(Scope, string, string, string) error { return nil }
+func (noRegistry) DeleteValue(Scope, string, string) error            { return nil }
+
+type noFiles struct{}
+
+func (noFiles) StartupFolder(Scope) (string, error) { return "", nil }
+func (noFiles) ReadDir(string) ([]string, error)    { return nil, nil }
+func (noFiles) ReadFile(string) ([]byte, error)     { return nil, nil }
+func (noFiles) WriteFile(string, []byte) error      { return nil }
+func (noFiles) Remove(string) error                 { return nil }
+func (noFiles) ExpandEnv(s string) string           { return s }
@@ -0,0 +1,241 @@
+package startup
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"wintray/internal/config"
+)
+
+type fakeRegistry struct {
+	strings map[string]map[string]string
+	binary  map[string][]byte
+	failSet bool
+}
+
+func regKey(scope Scope, path string) string { return string(scope) + `\` + path }
+
+func (r *fakeRegistry) StringValues(scope Scope, path string) (map[string]string, error) {
+	return r.strings[regKey(scope, path)], nil
+}
+
+func (r *fakeRegistry) BinaryValue(scope Scope, path, name string) ([]byte, bool, error) {
+	data, ok := r.binary[regKey(scope, path)+`\`+name]
+	return data, ok, nil
+}
+
+func (r *fakeRegistry) SetBinaryValue(scope Scope, path, name string, data []byte) error {
+	if r.failSet && scope == ScopeMachine {
+		return errors.New("access denied")
+	}
+	r.binary[regKey(scope, path)+`\`+name] = append([]byte(nil), data...)
+	return nil
+}
+
+func (r *fakeRegistry) SetStringValue(scope Scope, path, name, value string) error {
+	key := regKey(scope, path)
+	if r.strings[key] == nil {
+		r.strings[key] = map[string]string{}
+	}
+	r.strings[key][name] = value
+	return nil
+}
+
+func (r *fakeRegistry) DeleteValue(scope Scope, path, name string) error {
+	delete(r.binary, regKey(scope, path)+`\`+name)
+	delete(r.strings[regKey(scope, path)], name)
+	return nil
+}
+
+type fakeFiles struct {
+	folders map[Scope]string
+	files   map[string][]byte
+}
+
+func (f *fakeFiles) StartupFolder(scope Scope) (string, error) { return f.folders[scope], nil }
+
+func (f *fakeFiles) ReadDir(dir string) ([]string, error) {
+	var names []string
+	for path := range f.files {
+		if filepath.Dir(path) == dir {
+			names = append(names, filepath.Base(path))
+		}
+	}
+	return names, nil
+}
+
+func (f *fakeFiles) ReadFile(path string) ([]byte, error) {
+	data, ok := f.files[path]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return data, nil
+}
+
+func (f *fakeFiles) WriteFile(path string, data []byte) error {
+	f.files[path] = append([]byte(nil), data...)
+	return nil
+}
+
+func (f *fakeFiles) Remove(path string) error {
+	delete(f.files, path)
+	return nil
+}
+
+func (f *fakeFiles) ExpandEnv(s string) string {
+	return strings.ReplaceAll(s, "%ProgramFiles%", `C:\Program Files`)
+}
+
+func newFakeImporter(t *testing.T) (*Importer, *fakeRegistry) {
+	t.Helper()
+	link, err := os.ReadFile(filepath.Join("..", "shelllink", "testdata", "local.lnk"))
+	if err != nil {
+		t.Fatalf("read link: %v", err)
+	}
+	reg := &fakeRegistry{
+		strings: map[string]map[string]string{
+			regKey(ScopeUser, runKeyPath): {
+				"Sync":  `%ProgramFiles%\Sync\sync.exe /background`,
+				"Agent": `"C:\Tools\agent.exe" --quiet`,
+			},
+			regKey(ScopeMachine, runKeyPath): {
+				"Vendor": `C:\Program Files\Vendor\tray.exe -s`,
+			},
+		},
+		binary: map[string][]byte{
+			regKey(ScopeUser, approvedRunPath) + `\Agent`: {0x03, 0, 0, 0, 1, 2, 3, 4, 5, 6, 7, 8},
+			regKey(ScopeUser, approvedRunPath) + `\Sync`:  {0x02, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+		},
+	}
+	fs := &fakeFiles{
+		folders: map[Scope]string{ScopeUser: "/startup/user", ScopeMachine: "/startup/common"},
+		files: map[string][]byte{
+			"/startup/user/Example.lnk":  link,
+			"/startup/user/desktop.ini":  []byte("[.ShellClassInfo]"),
+			"/startup/common/backup.cmd": []byte("@echo off"),
+			"/startup/common/Broken.lnk": []byte("not a link"),
+		},
+	}
+	return NewImporter(reg, fs), reg
+}
+
+func TestImporterScan(t *testing.T) {
+	im, _ := newFakeImporter(t)
+	items, err := im.Scan()
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+
+	want := []Item{
+		{Origin: OriginRunKey, Scope: ScopeUser, Name: "Agent", Command: `"C:\Tools\agent.exe" --quiet`, Target: `C:\Tools\agent.exe`, Args: []string{"--quiet"}, Enabled: false},
+		{Origin: OriginRunKey, Scope: ScopeUser, Name: "Sync", Command: `%ProgramFiles%\Sync\sync.exe /background`, Target: `C:\Program Files\Sync\sync.exe`, Args: []string{"/background"}, Enabled: true},
+		{Origin: OriginStartupFolder, Scope: ScopeUser, Name: "Example.lnk", Command: "/startup/user/Example.lnk", Target: `C:\Program Files\Example App\app.exe`, Args: []string{"--tray", "--profile", "Work Profile"}, WorkingDir: `C:\Program Files\Example App`, Enabled: true},
+		{Origin: OriginRunKey, Scope: ScopeMachine, Name: "Vendor", Command: `C:\Program Files\Vendor\tray.exe -s`, Target: `C:\Program Files\Vendor\tray.exe`, Args: []string{"-s"}, Enabled: true},
+		{Origin: OriginStartupFolder, Scope: ScopeMachine, Name: "Broken.lnk", Command: "/startup/common/Broken.lnk", Target: "/startup/common/Broken.lnk", Enabled: true},
+		{Origin: OriginStartupFolder, Scope: ScopeMachine, Name: "backup.cmd", Command: "/startup/common/backup.cmd", Target: "/startup/common/backup.cmd", Enabled: true},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Fatalf("Scan mismatch\n got: %+v\nwant: %+v", items, want)
+	}
+
+	entry := ToManagedEntry(items[2], "id-1")
+	if entry.Name != "Example" || entry.LaunchKind != config.LaunchExe || entry.WindowMatch.Strategy != config.MatchProcessNameThenTitle {
+		t.Fatalf("unexpected entry %+v", entry)
+	}
+	if entry.WorkingDir != `C:\Program Files\Example App` || len(entry.Args) != 3 || !entry.RunOnStartup {
+		t.Fatalf("unexpected entry %+v", entry)
+	}
+	if kind := ToManagedEntry(items[4], "id-2").LaunchKind; kind != config.LaunchShortcut {
+		t.Fatalf("unresolved link kind = %q, want shortcut", kind)
+	}
+}
+
+func TestImporterDisableAndUndo(t *testing.T) {
+	im, reg := newFakeImporter(t)
+	reg.failSet = true
+	items, err := im.Scan()
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	before := make(map[string][]byte, len(reg.binary))
+	for k, v := range reg.binary {
+		before[k] = v
+	}
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	var journal Journal
+	if err = im.Disable(items, &journal, now); err == nil {
+		t.Fatalf("expected error for machine-wide items")
+	}
+	// Agent was already disabled; machine items failed.
+	if len(journal.Changes) != 2 || !journal.CreatedAt.Equal(now) {
+		t.Fatalf("unexpected journal %+v", journal)
+	}
+	for _, item := range items[:3] {
+		path, name := item.approvedKey()
+		data, ok, _ := reg.BinaryValue(item.Scope, path, name)
+		if !ok || data[0] != approvedDisabled {
+			t.Fatalf("%s not disabled: %v", item.Name, data)
+		}
+	}
+	if rescanned, _ := im.Scan(); rescanned[1].Enabled || rescanned[2].Enabled {
+		t.Fatalf("expected items disabled after Disable")
+	}
+
+	// Disabling again keeps the original state in the journal.
+	items[1].Enabled = true
+	if err = im.Disable(items[1:2], &journal, now.Add(time.Hour)); err != nil {
+		t.Fatalf("Disable: %v", err)
+	}
+	if len(journal.Changes) != 2 {
+		t.Fatalf("journal grew to %d changes", len(journal.Changes))
+	}
+
+	path := filepath.Join(t.TempDir(), "undo.json")
+	if err = SaveJournal(path, journal); err != nil {
+		t.Fatalf("SaveJournal: %v", err)
+	}
+	loaded, err := LoadJournal(path)
+	if err != nil {
+		t.Fatalf("LoadJournal: %v", err)
+	}
+	if err = im.Undo(&loaded); err != nil {
+		t.Fatalf("Undo: %v", err)
+	}
+	if !reflect.DeepEqual(reg.binary, before) {
+		t.Fatalf("Undo did not restore values\n got: %v\nwant: %v", reg.binary, before)
+	}
+	if err = SaveJournal(path, loaded); err != nil {
+		t.Fatalf("SaveJournal: %v", err)
+	}
+	if _, err = LoadJournal(path); !errors.Is(err, ErrNoJournal) {
+		t.Fatalf("expected ErrNoJournal after undo, got %v", err)
+	}
+}
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		target  string
+		args    []string
+	}{
+		{`"C:\Program Files\App\app.exe" --min`, `C:\Program Files\App\app.exe`, []string{"--min"}},
+		{`C:\Program Files\App\app.exe --min "a b"`, `C:\Program Files\App\app.exe`, []string{"--min", "a b"}},
+		{`C:\Program Files\App\app.exe`, `C:\Program Files\App\app.exe`, []string{}},
+		{`C:\Windows\system32\rundll32.exe shell32.dll,Control_RunDLL`, `C:\Windows\system32\rundll32.exe`, []string{"shell32.dll,Control_RunDLL"}},
+		{`C:\Apps\tool.exe.config.exe -x`, `C:\Apps\tool.exe.config.exe`, []string{"-x"}},
+		{`C:\My Scripts\run.bat now`, `C:\My`, []string{`Scripts\run.bat`, "now"}},
+		{"   ", "", nil},
+	}
+	for _, tt := range tests {
+		target, args := splitCommand(tt.command)
+		if target != tt.target || !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("splitCommand(%q) = %q %q, want %q %q", tt.command, target, args, tt.target, tt.args)
+		}
+	}
+}
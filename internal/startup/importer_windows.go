@@ -0,0 +1,156 @@
+//go:build windows
+
+package startup
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// NewSystemImporter returns an Importer reading this machine's registry and
+// Startup folders.
+func NewSystemImporter() *Importer {
+	return NewImporter(systemRegistry{}, systemFiles{})
+}
+
+type systemRegistry struct{}
+
+func rootKey(scope Scope) registry.Key {
+	if scope == ScopeMachine {
+		return registry.LOCAL_MACHINE
+	}
+	return registry.CURRENT_USER
+}
+
+func (systemRegistry) StringValues(scope Scope, path string) (map[string]string, error) {
+	key, err := registry.OpenKey(rootKey(scope), path, registry.QUERY_VALUE)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer key.Close()
+
+	names, err := key.ReadValueNames(0)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(names))
+	for _, name := range names {
+		value, _, valueErr := key.GetStringValue(name)
+		if valueErr != nil {
+			// Not a string value.
+			continue
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+func (systemRegistry) BinaryValue(scope Scope, path, name string) ([]byte, bool, error) {
+	key, err := registry.OpenKey(rootKey(scope), path, registry.QUERY_VALUE)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	defer key.Close()
+
+	data, _, err := key.GetBinaryValue(name)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+func (systemRegistry) SetBinaryValue(scope Scope, path, name string, data []byte) error {
+	key, _, err := registry.CreateKey(rootKey(scope), path, registry.SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer key.Close()
+	return key.SetBinaryValue(name, data)
+}
+
+func (systemRegistry) SetStringValue(scope Scope, path, name, value string) error {
+	key, _, err := registry.CreateKey(rootKey(scope), path, registry.SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer key.Close()
+	return key.SetStringValue(name, value)
+}
+
+func (systemRegistry) DeleteValue(scope Scope, path, name string) error {
+	key, err := registry.OpenKey(rootKey(scope), path, registry.SET_VALUE)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer key.Close()
+	if err = key.DeleteValue(name); err != nil && !errors.Is(err, registry.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+type systemFiles struct{}
+
+func (systemFiles) StartupFolder(scope Scope) (string, error) {
+	if scope == ScopeMachine {
+		return windows.KnownFolderPath(windows.FOLDERID_CommonStartup, 0)
+	}
+	return windows.KnownFolderPath(windows.FOLDERID_Startup, 0)
+}
+
+func (systemFiles) ReadDir(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	return names, nil
+}
+
+func (systemFiles) ReadFile(path string) ([]byte, error) { return os.ReadFile(path) }
+
+func (systemFiles) WriteFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func (systemFiles) Remove(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (systemFiles) ExpandEnv(s string) string {
+	expanded, err := registry.ExpandString(s)
+	if err != nil {
+		return s
+	}
+	return expanded
+}
@@ -0,0 +1,89 @@
+package startup
+
+import (
+	"errors"
+	"fmt"
+
+	"wintray/internal/config"
+)
+
+// Registrar registers a command to run when the user logs on.
+type Registrar interface {
+	SetEnabled(appName, command string, enabled bool) error
+	// Status reports the current registration of appName; exePath is the
+	// executable it is expected to start.
+	Status(appName, exePath string) (Status, error)
+}
+
+// Backends holds the Registrar of each config.StartupBackend.
+type Backends map[config.StartupBackend]Registrar
+
+// Applied is the outcome of Backends.Apply.
+type Applied struct {
+	// Backend is the backend holding the registration. It differs from the
+	// selected one after a fallback.
+	Backend  config.StartupBackend
+	Status   Status
+	Repaired bool
+	// CleanupErr reports registrations of other backends that could not be
+	// removed. The registration itself succeeded.
+	CleanupErr error
+}
+
+// FallbackOrder lists the backends to try for selected: selected first, then
+// the Run key, the Startup folder and Task Scheduler.
+func FallbackOrder(selected config.StartupBackend) []config.StartupBackend {
+	order := []config.StartupBackend{selected}
+	for _, backend := range []config.StartupBackend{config.StartupRunKey, config.StartupFolder, config.StartupTaskScheduler} {
+		if backend != selected {
+			order = append(order, backend)
+		}
+	}
+	return order
+}
+
+// Apply repairs the registration of the selected backend (see Repair) and
+// removes what the other backends registered, so switching backends moves
+// an existing Run value to a scheduled task and back. When registering with
+// the selected backend fails, the next backend in FallbackOrder is tried.
+func (b Backends) Apply(selected config.StartupBackend, appName, command, exePath string, enabled bool) (Applied, error) {
+	order := []config.StartupBackend{selected}
+	if enabled {
+		order = FallbackOrder(selected)
+	}
+	var errs []error
+	for _, backend := range order {
+		registrar, ok := b[backend]
+		if !ok {
+			if backend == selected {
+				errs = append(errs, fmt.Errorf("unknown startup backend %q", backend))
+			}
+			continue
+		}
+		st, repaired, err := Repair(registrar, appName, command, exePath, enabled)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", backend, err))
+			continue
+		}
+		return Applied{
+			Backend:    backend,
+			Status:     st,
+			Repaired:   repaired,
+			CleanupErr: b.removeExcept(backend, appName, command),
+		}, nil
+	}
+	return Applied{Backend: selected}, errors.Join(errs...)
+}
+
+func (b Backends) removeExcept(keep config.StartupBackend, appName, command string) error {
+	var errs []error
+	for backend, registrar := range b {
+		if backend == keep {
+			continue
+		}
+		if err := registrar.SetEnabled(appName, command, false); err != nil {
+			errs = append(errs, fmt.Errorf("remove %s registration: %w", backend, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,125 @@
+package startup
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"wintray/internal/config"
+)
+
+type fakeRegistrar struct {
+	registered bool
+	failSet    bool
+	calls      []bool
+}
+
+func (r *fakeRegistrar) SetEnabled(_, _ string, enabled bool) error {
+	r.calls = append(r.calls, enabled)
+	if r.failSet && enabled {
+		return errors.New("access denied")
+	}
+	r.registered = enabled
+	return nil
+}
+
+func (r *fakeRegistrar) Status(_, _ string) (Status, error) {
+	if !r.registered {
+		return Status{}, nil
+	}
+	return Status{Present: true, Command: "wintray.exe", MatchesExecutable: true}, nil
+}
+
+func TestBackendsApply(t *testing.T) {
+	run, folder, task := &fakeRegistrar{registered: true}, &fakeRegistrar{}, &fakeRegistrar{}
+	backends := Backends{config.StartupRunKey: run, config.StartupFolder: folder, config.StartupTaskScheduler: task}
+
+	applied, err := backends.Apply(config.StartupTaskScheduler, "WinTray", "wintray.exe", "wintray.exe", true)
+	if err != nil || applied.CleanupErr != nil {
+		t.Fatalf("Apply: %v %v", err, applied.CleanupErr)
+	}
+	if applied.Backend != config.StartupTaskScheduler || !applied.Repaired || !task.registered || run.registered {
+		t.Fatalf("applied=%+v run=%v task=%v, want Run value moved to the task", applied, run.registered, task.registered)
+	}
+
+	if _, err = backends.Apply("bogus", "WinTray", "wintray.exe", "wintray.exe", false); err == nil {
+		t.Fatalf("expected error for unknown backend")
+	}
+}
+
+func TestBackendsApplyFallsBack(t *testing.T) {
+	run, folder, task := &fakeRegistrar{failSet: true}, &fakeRegistrar{}, &fakeRegistrar{}
+	backends := Backends{config.StartupRunKey: run, config.StartupFolder: folder, config.StartupTaskScheduler: task}
+
+	applied, err := backends.Apply(config.StartupRunKey, "WinTray", "wintray.exe", "wintray.exe", true)
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if applied.Backend != config.StartupFolder || !folder.registered || task.registered {
+		t.Fatalf("applied=%+v folder=%v task=%v, want fallback to the Startup folder", applied, folder.registered, task.registered)
+	}
+
+	folder.failSet, task.failSet = true, true
+	folder.registered = false
+	if _, err = backends.Apply(config.StartupRunKey, "WinTray", "wintray.exe", "wintray.exe", true); err == nil {
+		t.Fatalf("expected error when every backend fails")
+	}
+
+	// Disabling never falls back.
+	run.calls, folder.calls = nil, nil
+	run.registered = true
+	if _, err = backends.Apply(config.StartupFolder, "WinTray", "wintray.exe", "wintray.exe", false); err != nil {
+		t.Fatalf("Apply(disable): %v", err)
+	}
+	if run.registered || !reflect.DeepEqual(run.calls, []bool{false}) {
+		t.Fatalf("run calls = %v, want one removal", run.calls)
+	}
+}
+
+func TestFallbackOrder(t *testing.T) {
+	want := []config.StartupBackend{config.StartupTaskScheduler, config.StartupRunKey, config.StartupFolder}
+	if got := FallbackOrder(config.StartupTaskScheduler); !reflect.DeepEqual(got, want) {
+		t.Fatalf("FallbackOrder = %v, want %v", got, want)
+	}
+}
+
+func TestStartupFolderRegistrar(t *testing.T) {
+	const exe = `D:\Portable\WinTray\wintray.exe`
+	command := `"` + exe + `" --background --autorun`
+	reg := &fakeRegistry{strings: map[string]map[string]string{}, binary: map[string][]byte{}}
+	files := &fakeFiles{folders: map[Scope]string{ScopeUser: "/startup/user"}, files: map[string][]byte{}}
+	r := &StartupFolderRegistrar{reg: reg, files: files}
+
+	st, repaired, err := Repair(r, "WinTray", command, exe, true)
+	if err != nil {
+		t.Fatalf("Repair: %v", err)
+	}
+	if !repaired || !st.Present || !st.MatchesExecutable || st.DisabledByUser || !sameCommand(st.Command, command) {
+		t.Fatalf("unexpected status %+v repaired=%v", st, repaired)
+	}
+	if _, ok := files.files["/startup/user/WinTray.lnk"]; !ok {
+		t.Fatalf("shortcut not written: %v", files.files)
+	}
+
+	reg.binary[regKey(ScopeUser, approvedFolderPath)+`\WinTray.lnk`] = []byte{0x03, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	if st, _ = r.Status("WinTray", `C:\Elsewhere\wintray.exe`); !st.DisabledByUser || st.MatchesExecutable {
+		t.Fatalf("unexpected status %+v", st)
+	}
+
+	if err = r.SetEnabled("WinTray", command, false); err != nil {
+		t.Fatalf("SetEnabled(false): %v", err)
+	}
+	if st, _ = r.Status("WinTray", exe); st.Present {
+		t.Fatalf("shortcut still present after removal")
+	}
+}
+
+func TestSplitUNC(t *testing.T) {
+	share, rest, ok := splitUNC(`\\server\tools\WinTray\wintray.exe`)
+	if !ok || share != `\\server\tools` || rest != `WinTray\wintray.exe` {
+		t.Fatalf("splitUNC = %q %q %v", share, rest, ok)
+	}
+	if _, _, ok = splitUNC(`C:\WinTray\wintray.exe`); ok {
+		t.Fatalf("expected local path to be rejected")
+	}
+}
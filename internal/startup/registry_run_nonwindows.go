@@ -2,8 +2,18 @@
 
 package startup
 
-type Registrar struct{}
+func NewRunKeyRegistrar() *RunKeyRegistrar { return &RunKeyRegistrar{reg: noRegistry{}} }
 
-func NewRegistrar() *Registrar { return &Registrar{} }
+func NewStartupFolderRegistrar() *StartupFolderRegistrar {
+	return &StartupFolderRegistrar{reg: noRegistry{}, files: noFiles{}}
+}
 
-func (r *Registrar) SetEnabled(_, _ string, _ bool) error { return nil }
+type TaskRegistrar struct {
+	Options TaskOptions
+}
+
+func NewTaskRegistrar() *TaskRegistrar { return &TaskRegistrar{} }
+
+func (r *TaskRegistrar) SetEnabled(_, _ string, _ bool) error { return nil }
+
+func (r *TaskRegistrar) Status(_, _ string) (Status, error) { return Status{}, nil }
@@ -2,30 +2,8 @@
 
 package startup
 
-import (
-	"errors"
+func NewRunKeyRegistrar() *RunKeyRegistrar { return &RunKeyRegistrar{reg: systemRegistry{}} }
 
-	"golang.org/x/sys/windows/registry"
-)
-
-const runKeyPath = `SOFTWARE\Microsoft\Windows\CurrentVersion\Run`
-
-type Registrar struct{}
-
-func NewRegistrar() *Registrar { return &Registrar{} }
-
-func (r *Registrar) SetEnabled(appName, command string, enabled bool) error {
-	key, _, err := registry.CreateKey(registry.CURRENT_USER, runKeyPath, registry.SET_VALUE)
-	if err != nil {
-		return err
-	}
-	defer key.Close()
-
-	if enabled {
-		return key.SetStringValue(appName, command)
-	}
-	if err = key.DeleteValue(appName); err != nil && !errors.Is(err, registry.ErrNotExist) {
-		return err
-	}
-	return nil
+func NewStartupFolderRegistrar() *StartupFolderRegistrar {
+	return &StartupFolderRegistrar{reg: systemRegistry{}, files: systemFiles{}}
 }
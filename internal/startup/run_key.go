@@ -0,0 +1,33 @@
+package startup
+
+import "strings"
+
+// RunKeyRegistrar registers run-at-logon as a value under the HKCU Run key.
+type RunKeyRegistrar struct {
+	reg Registry
+}
+
+func (r *RunKeyRegistrar) SetEnabled(appName, command string, enabled bool) error {
+	if enabled {
+		return r.reg.SetStringValue(ScopeUser, runKeyPath, appName, command)
+	}
+	return r.reg.DeleteValue(ScopeUser, runKeyPath, appName)
+}
+
+// Status reads the Run value of appName and its Task Manager state.
+func (r *RunKeyRegistrar) Status(appName, exePath string) (Status, error) {
+	values, err := r.reg.StringValues(ScopeUser, runKeyPath)
+	if err != nil {
+		return Status{}, err
+	}
+	for name, command := range values {
+		if !strings.EqualFold(name, appName) {
+			continue
+		}
+		item := Item{Origin: OriginRunKey, Scope: ScopeUser, Name: name}
+		st := Status{Present: true, Command: command, DisabledByUser: !approved(r.reg, item)}
+		st.MatchesExecutable = commandRuns(command, exePath)
+		return st, nil
+	}
+	return Status{}, nil
+}
@@ -0,0 +1,106 @@
+package startup
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"wintray/internal/cmdline"
+	"wintray/internal/shelllink"
+)
+
+// StartupFolderRegistrar registers run-at-logon as a shortcut in the user's
+// Startup folder, for machines where policy blocks the Run key.
+type StartupFolderRegistrar struct {
+	reg   Registry
+	files FileSystem
+}
+
+func (r *StartupFolderRegistrar) shortcutPath(appName string) (string, error) {
+	dir, err := r.files.StartupFolder(ScopeUser)
+	if err != nil {
+		return "", err
+	}
+	if dir == "" {
+		return "", errors.New("startup folder is not available")
+	}
+	return filepath.Join(dir, appName+".lnk"), nil
+}
+
+func (r *StartupFolderRegistrar) SetEnabled(appName, command string, enabled bool) error {
+	path, err := r.shortcutPath(appName)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return r.files.Remove(path)
+	}
+	args := cmdline.Split(command)
+	if len(args) == 0 {
+		return errors.New("empty startup command")
+	}
+	data, err := shelllink.Encode(startupShortcut(args[0], args[1:]))
+	if err != nil {
+		return err
+	}
+	return r.files.WriteFile(path, data)
+}
+
+// Status reads the shortcut back together with its Task Manager state.
+func (r *StartupFolderRegistrar) Status(appName, exePath string) (Status, error) {
+	path, err := r.shortcutPath(appName)
+	if err != nil {
+		return Status{}, err
+	}
+	data, err := r.files.ReadFile(path)
+	if err != nil {
+		// Missing or unreadable; either way nothing usable is registered.
+		return Status{}, nil
+	}
+	link, err := shelllink.Parse(data)
+	if err != nil {
+		return Status{}, err
+	}
+	target := link.Target()
+	command := cmdline.Quote(target)
+	if args := strings.TrimSpace(link.Arguments); args != "" {
+		command += " " + args
+	}
+	item := Item{Origin: OriginStartupFolder, Scope: ScopeUser, Name: filepath.Base(path)}
+	return Status{
+		Present:           true,
+		Command:           command,
+		MatchesExecutable: samePath(target, exePath),
+		DisabledByUser:    !approved(r.reg, item),
+	}, nil
+}
+
+// startupShortcut builds a shortcut to exePath. It carries only LinkInfo and
+// strings; the shell resolves it without an item ID list.
+func startupShortcut(exePath string, args []string) *shelllink.Link {
+	const driveFixed = 3
+	const showNormal = 1
+	info := &shelllink.LinkInfo{DriveType: driveFixed, LocalBasePath: exePath}
+	if share, rest, ok := splitUNC(exePath); ok {
+		info = &shelllink.LinkInfo{NetName: share, CommonPathSuffix: rest}
+	}
+	return &shelllink.Link{
+		Header:       shelllink.Header{ShowCommand: showNormal},
+		LinkInfo:     info,
+		WorkingDir:   windowsDir(exePath),
+		Arguments:    cmdline.Join(args),
+		IconLocation: exePath,
+	}
+}
+
+// splitUNC splits \\server\share\dir\app.exe into the share and the rest.
+func splitUNC(path string) (string, string, bool) {
+	if !strings.HasPrefix(path, `\\`) {
+		return "", "", false
+	}
+	parts := strings.SplitN(path[2:], `\`, 3)
+	if len(parts) < 3 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return `\\` + parts[0] + `\` + parts[1], parts[2], true
+}
@@ -0,0 +1,71 @@
+package startup
+
+import (
+	"path/filepath"
+	"strings"
+
+	"wintray/internal/cmdline"
+)
+
+// Status describes what a Registrar has registered for an app.
+type Status struct {
+	Present bool
+	Command string
+	// MatchesExecutable reports whether Command starts the given executable.
+	// It is false for stale paths left behind when a portable copy moves.
+	MatchesExecutable bool
+	// DisabledByUser is set when the user turned the entry off in Task
+	// Manager or Task Scheduler. WinTray leaves that choice alone.
+	DisabledByUser bool
+}
+
+// Repair reads the registration of appName and rewrites it when it is
+// missing or differs from command, or removes it when enabled is false. It
+// returns the status after any repair and whether anything was changed.
+func Repair(r Registrar, appName, command, exePath string, enabled bool) (Status, bool, error) {
+	st, err := r.Status(appName, exePath)
+	if err != nil {
+		return Status{}, false, err
+	}
+	switch {
+	case enabled && (!st.Present || !sameCommand(st.Command, command)):
+		err = r.SetEnabled(appName, command, true)
+	case !enabled && st.Present:
+		err = r.SetEnabled(appName, command, false)
+	default:
+		return st, false, nil
+	}
+	if err != nil {
+		return st, false, err
+	}
+	st, err = r.Status(appName, exePath)
+	return st, true, err
+}
+
+// sameCommand compares command lines by their parsed arguments, so quoting
+// differences do not count. The program path is compared case-insensitively.
+func sameCommand(a, b string) bool {
+	argsA, argsB := cmdline.Split(a), cmdline.Split(b)
+	if len(argsA) != len(argsB) || len(argsA) == 0 {
+		return false
+	}
+	if !samePath(argsA[0], argsB[0]) {
+		return false
+	}
+	for i := 1; i < len(argsA); i++ {
+		if argsA[i] != argsB[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// commandRuns reports whether command starts exePath.
+func commandRuns(command, exePath string) bool {
+	target, _ := splitCommand(command)
+	return target != "" && samePath(target, exePath)
+}
+
+func samePath(a, b string) bool {
+	return strings.EqualFold(filepath.Clean(a), filepath.Clean(b))
+}
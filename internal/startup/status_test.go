@@ -0,0 +1,120 @@
+package startup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunKeyRepair(t *testing.T) {
+	const exe = `D:\Portable\WinTray\wintray.exe`
+	command := `"` + exe + `" --autorun`
+	approvedKey := regKey(ScopeUser, approvedRunPath) + `\WinTray`
+
+	tests := []struct {
+		name         string
+		runValue     string
+		approved     []byte
+		enabled      bool
+		wantRepaired bool
+		want         Status
+	}{
+		{
+			name:         "missing value is created",
+			enabled:      true,
+			wantRepaired: true,
+			want:         Status{Present: true, Command: command, MatchesExecutable: true},
+		},
+		{
+			name:         "stale path is rewritten",
+			runValue:     `"C:\Users\me\Downloads\WinTray\wintray.exe" --autorun`,
+			enabled:      true,
+			wantRepaired: true,
+			want:         Status{Present: true, Command: command, MatchesExecutable: true},
+		},
+		{
+			name:     "equivalent quoting is left alone",
+			runValue: `D:\Portable\WinTray\WINTRAY.EXE "--autorun"`,
+			enabled:  true,
+			want:     Status{Present: true, Command: `D:\Portable\WinTray\WINTRAY.EXE "--autorun"`, MatchesExecutable: true},
+		},
+		{
+			name:     "user disabled entry stays disabled",
+			runValue: command,
+			approved: []byte{0x03, 0, 0, 0, 1, 2, 3, 4, 5, 6, 7, 8},
+			enabled:  true,
+			want:     Status{Present: true, Command: command, MatchesExecutable: true, DisabledByUser: true},
+		},
+		{
+			name:         "value removed when run at logon is off",
+			runValue:     command,
+			enabled:      false,
+			wantRepaired: true,
+			want:         Status{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := &fakeRegistry{strings: map[string]map[string]string{}, binary: map[string][]byte{}}
+			if tt.runValue != "" {
+				_ = reg.SetStringValue(ScopeUser, runKeyPath, "WinTray", tt.runValue)
+			}
+			if tt.approved != nil {
+				reg.binary[approvedKey] = tt.approved
+			}
+			st, repaired, err := Repair(&RunKeyRegistrar{reg: reg}, "WinTray", command, exe, tt.enabled)
+			if err != nil {
+				t.Fatalf("Repair: %v", err)
+			}
+			if repaired != tt.wantRepaired || st != tt.want {
+				t.Fatalf("Repair = %+v repaired=%v, want %+v repaired=%v", st, repaired, tt.want, tt.wantRepaired)
+			}
+		})
+	}
+}
+
+func TestRunKeyStatusStalePath(t *testing.T) {
+	reg := &fakeRegistry{strings: map[string]map[string]string{
+		regKey(ScopeUser, runKeyPath): {"wintray": `"C:\Old\wintray.exe" --autorun`},
+	}, binary: map[string][]byte{}}
+	st, err := (&RunKeyRegistrar{reg: reg}).Status("WinTray", `D:\New\wintray.exe`)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if !st.Present || st.MatchesExecutable || st.DisabledByUser {
+		t.Fatalf("unexpected status %+v", st)
+	}
+}
+
+func TestParseTaskStatus(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "task_delayed_elevated.xml"))
+	if err != nil {
+		t.Fatalf("read golden: %v", err)
+	}
+	const exe = `C:\Program Files\WinTray & Co\wintray.exe`
+	for _, encoded := range [][]byte{data, EncodeTaskXML(data)} {
+		st, err := parseTaskStatus(encoded, exe)
+		if err != nil {
+			t.Fatalf("parseTaskStatus: %v", err)
+		}
+		if !st.Present || !st.MatchesExecutable || st.DisabledByUser {
+			t.Fatalf("unexpected status %+v", st)
+		}
+		if !sameCommand(st.Command, `"`+exe+`" --background --autorun`) {
+			t.Fatalf("command = %q", st.Command)
+		}
+	}
+
+	disabled := []byte(`<?xml version="1.0" encoding="UTF-16"?>
+<Task xmlns="http://schemas.microsoft.com/windows/2004/02/mit/task">
+  <Settings><Enabled>false</Enabled></Settings>
+  <Actions><Exec><Command>C:\Other\wintray.exe</Command></Exec></Actions>
+</Task>`)
+	st, err := parseTaskStatus(disabled, exe)
+	if err != nil {
+		t.Fatalf("parseTaskStatus: %v", err)
+	}
+	if !st.DisabledByUser || st.MatchesExecutable || st.Command != `C:\Other\wintray.exe` {
+		t.Fatalf("unexpected status %+v", st)
+	}
+}
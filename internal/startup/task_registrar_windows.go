@@ -0,0 +1,96 @@
+//go:build windows
+
+package startup
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+// TaskRegistrar registers run-at-logon as a scheduled task with a logon
+// trigger, which unlike the Run key supports a start delay.
+type TaskRegistrar struct {
+	Options TaskOptions
+}
+
+func NewTaskRegistrar() *TaskRegistrar { return &TaskRegistrar{} }
+
+func (r *TaskRegistrar) options() (TaskOptions, error) {
+	opts := r.Options
+	if opts.UserID == "" {
+		current, err := user.Current()
+		if err != nil {
+			return opts, err
+		}
+		opts.UserID = current.Username
+	}
+	return opts, nil
+}
+
+func (r *TaskRegistrar) SetEnabled(appName, command string, enabled bool) error {
+	opts, err := r.options()
+	if err != nil {
+		return err
+	}
+	name := TaskName(appName, opts.UserID)
+
+	if !enabled {
+		if _, queryErr := schtasks("/Query", "/TN", name); queryErr != nil {
+			// Nothing registered.
+			return nil
+		}
+		_, err = schtasks("/Delete", "/TN", name, "/F")
+		return err
+	}
+
+	data, err := TaskXML(command, opts)
+	if err != nil {
+		return err
+	}
+	file, err := os.CreateTemp("", "wintray-task-*.xml")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err = file.Write(EncodeTaskXML(data)); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	_, err = schtasks("/Create", "/TN", name, "/XML", file.Name(), "/F")
+	return err
+}
+
+// Status reads the task back from Task Scheduler. A task that cannot be
+// queried is reported as not present.
+func (r *TaskRegistrar) Status(appName, exePath string) (Status, error) {
+	opts, err := r.options()
+	if err != nil {
+		return Status{}, err
+	}
+	out, err := schtasks("/Query", "/TN", TaskName(appName, opts.UserID), "/XML")
+	if err != nil {
+		return Status{}, nil
+	}
+	return parseTaskStatus(out, exePath)
+}
+
+func schtasks(args ...string) ([]byte, error) {
+	cmd := exec.Command("schtasks.exe", args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true, CreationFlags: windows.CREATE_NO_WINDOW}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("schtasks %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
@@ -0,0 +1,267 @@
+package startup
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"wintray/internal/cmdline"
+)
+
+const taskNamespace = "http://schemas.microsoft.com/windows/2004/02/mit/task"
+
+// TaskOptions configures the scheduled task registered by TaskRegistrar.
+type TaskOptions struct {
+	// UserID is the account, as DOMAIN\user, whose logon starts the task.
+	UserID            string
+	Delay             time.Duration
+	HighestPrivileges bool
+	DisallowOnBattery bool
+}
+
+type taskDefinition struct {
+	XMLName          xml.Name         `xml:"Task"`
+	Version          string           `xml:"version,attr"`
+	Namespace        string           `xml:"xmlns,attr"`
+	RegistrationInfo taskRegistration `xml:"RegistrationInfo"`
+	Triggers         taskTriggers     `xml:"Triggers"`
+	Principals       taskPrincipals   `xml:"Principals"`
+	Settings         taskSettings     `xml:"Settings"`
+	Actions          taskActions      `xml:"Actions"`
+}
+
+type taskRegistration struct {
+	Description string `xml:"Description"`
+}
+
+type taskTriggers struct {
+	Logon taskLogonTrigger `xml:"LogonTrigger"`
+}
+
+type taskLogonTrigger struct {
+	Enabled bool   `xml:"Enabled"`
+	UserID  string `xml:"UserId"`
+	Delay   string `xml:"Delay,omitempty"`
+}
+
+type taskPrincipals struct {
+	Principal taskPrincipal `xml:"Principal"`
+}
+
+type taskPrincipal struct {
+	ID        string `xml:"id,attr"`
+	UserID    string `xml:"UserId"`
+	LogonType string `xml:"LogonType"`
+	RunLevel  string `xml:"RunLevel"`
+}
+
+type taskSettings struct {
+	MultipleInstancesPolicy    string `xml:"MultipleInstancesPolicy"`
+	DisallowStartIfOnBatteries bool   `xml:"DisallowStartIfOnBatteries"`
+	StopIfGoingOnBatteries     bool   `xml:"StopIfGoingOnBatteries"`
+	AllowHardTerminate         bool   `xml:"AllowHardTerminate"`
+	StartWhenAvailable         bool   `xml:"StartWhenAvailable"`
+	AllowStartOnDemand         bool   `xml:"AllowStartOnDemand"`
+	Enabled                    bool   `xml:"Enabled"`
+	Hidden                     bool   `xml:"Hidden"`
+	// ExecutionTimeLimit defaults to three days; PT0S lets WinTray run forever.
+	ExecutionTimeLimit string `xml:"ExecutionTimeLimit"`
+	// Priority defaults to 7, which is below normal.
+	Priority int `xml:"Priority"`
+}
+
+type taskActions struct {
+	Context string   `xml:"Context,attr"`
+	Exec    taskExec `xml:"Exec"`
+}
+
+type taskExec struct {
+	Command          string `xml:"Command"`
+	Arguments        string `xml:"Arguments,omitempty"`
+	WorkingDirectory string `xml:"WorkingDirectory,omitempty"`
+}
+
+// TaskXML returns the Task Scheduler definition that runs command when the
+// user in opts logs on. The document declares UTF-16; pass it through
+// EncodeTaskXML before handing it to schtasks.
+func TaskXML(command string, opts TaskOptions) ([]byte, error) {
+	args := cmdline.Split(command)
+	if len(args) == 0 {
+		return nil, errors.New("empty task command")
+	}
+	if strings.TrimSpace(opts.UserID) == "" {
+		return nil, errors.New("task user is required")
+	}
+
+	runLevel := "LeastPrivilege"
+	if opts.HighestPrivileges {
+		runLevel = "HighestAvailable"
+	}
+	def := taskDefinition{
+		Version:          "1.2",
+		Namespace:        taskNamespace,
+		RegistrationInfo: taskRegistration{Description: "Starts WinTray when you sign in."},
+		Triggers: taskTriggers{Logon: taskLogonTrigger{
+			Enabled: true,
+			UserID:  opts.UserID,
+			Delay:   taskDuration(opts.Delay),
+		}},
+		Principals: taskPrincipals{Principal: taskPrincipal{
+			ID:        "Author",
+			UserID:    opts.UserID,
+			LogonType: "InteractiveToken",
+			RunLevel:  runLevel,
+		}},
+		Settings: taskSettings{
+			MultipleInstancesPolicy:    "IgnoreNew",
+			DisallowStartIfOnBatteries: opts.DisallowOnBattery,
+			StopIfGoingOnBatteries:     false,
+			AllowHardTerminate:         true,
+			StartWhenAvailable:         false,
+			AllowStartOnDemand:         true,
+			Enabled:                    true,
+			Hidden:                     false,
+			ExecutionTimeLimit:         "PT0S",
+			Priority:                   4,
+		},
+		Actions: taskActions{Context: "Author", Exec: taskExec{
+			Command:          args[0],
+			Arguments:        cmdline.Join(args[1:]),
+			WorkingDirectory: windowsDir(args[0]),
+		}},
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-16"?>` + "\n")
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(def); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+// EncodeTaskXML converts the UTF-8 output of TaskXML to the UTF-16LE with
+// byte order mark that schtasks /XML expects.
+func EncodeTaskXML(data []byte) []byte {
+	units := utf16.Encode([]rune(string(data)))
+	out := make([]byte, 2, 2+2*len(units))
+	out[0], out[1] = 0xFF, 0xFE
+	for _, u := range units {
+		out = append(out, byte(u), byte(u>>8))
+	}
+	return out
+}
+
+// taskQuery is the part of a registered task definition read back by
+// parseTaskStatus.
+type taskQuery struct {
+	Settings struct {
+		Enabled string `xml:"Enabled"`
+	} `xml:"Settings"`
+	Actions struct {
+		Exec []taskExec `xml:"Exec"`
+	} `xml:"Actions"`
+}
+
+// parseTaskStatus reads a task definition as printed by schtasks /Query /XML,
+// in UTF-8 or UTF-16LE, into a Status for exePath.
+func parseTaskStatus(data []byte, exePath string) (Status, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		units := make([]uint16, 0, len(data)/2)
+		for i := 2; i+1 < len(data); i += 2 {
+			units = append(units, uint16(data[i])|uint16(data[i+1])<<8)
+		}
+		data = []byte(string(utf16.Decode(units)))
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		data = data[3:]
+	}
+
+	var def taskQuery
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	// The declaration names UTF-16 even when schtasks prints UTF-8; the data
+	// is UTF-8 by now either way.
+	dec.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) { return input, nil }
+	if err := dec.Decode(&def); err != nil {
+		return Status{}, err
+	}
+	if len(def.Actions.Exec) == 0 {
+		return Status{}, errors.New("task has no exec action")
+	}
+
+	exec := def.Actions.Exec[0]
+	command := cmdline.Quote(exec.Command)
+	if strings.HasPrefix(exec.Command, `"`) {
+		command = exec.Command
+	}
+	if args := strings.TrimSpace(exec.Arguments); args != "" {
+		command += " " + args
+	}
+	return Status{
+		Present:           true,
+		Command:           command,
+		MatchesExecutable: samePath(strings.Trim(exec.Command, `"`), exePath),
+		DisabledByUser:    strings.EqualFold(strings.TrimSpace(def.Settings.Enabled), "false"),
+	}, nil
+}
+
+// TaskName returns the per-user task path, so that users sharing a machine
+// do not replace each other's task.
+func TaskName(appName, userID string) string {
+	user := userID
+	if i := strings.LastIndexAny(user, `\/`); i >= 0 {
+		user = user[i+1:]
+	}
+	user = strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+		return r
+	}, user)
+	if user == "" {
+		return appName + `\Logon`
+	}
+	return appName + `\Logon-` + user
+}
+
+// taskDuration formats d as an ISO 8601 duration such as PT1M30S. Zero
+// yields "" so the element is omitted.
+func taskDuration(d time.Duration) string {
+	secs := int(d / time.Second)
+	if secs <= 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("PT")
+	if h := secs / 3600; h > 0 {
+		fmt.Fprintf(&b, "%dH", h)
+	}
+	if m := secs % 3600 / 60; m > 0 {
+		fmt.Fprintf(&b, "%dM", m)
+	}
+	if s := secs % 60; s > 0 {
+		fmt.Fprintf(&b, "%dS", s)
+	}
+	return b.String()
+}
+
+// windowsDir returns the directory part of a Windows path, regardless of
+// the platform this runs on.
+func windowsDir(path string) string {
+	i := strings.LastIndexAny(path, `\/`)
+	if i < 0 {
+		return ""
+	}
+	if i == 2 && path[1] == ':' {
+		// Keep the root of "C:\app.exe".
+		return path[:3]
+	}
+	return path[:i]
+}
@@ -0,0 +1,100 @@
+package startup
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files")
+
+func TestTaskXMLGolden(t *testing.T) {
+	tests := []struct {
+		golden  string
+		command string
+		opts    TaskOptions
+	}{
+		{
+			golden:  "task_default.xml",
+			command: `"C:\Tools\WinTray\wintray.exe" --autorun`,
+			opts:    TaskOptions{UserID: `DESKTOP\alice`},
+		},
+		{
+			golden:  "task_delayed_elevated.xml",
+			command: `"C:\Program Files\WinTray & Co\wintray.exe" --background --autorun`,
+			opts: TaskOptions{
+				UserID:            `CORP\bob`,
+				Delay:             90 * time.Second,
+				HighestPrivileges: true,
+				DisallowOnBattery: true,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			got, err := TaskXML(tt.command, tt.opts)
+			if err != nil {
+				t.Fatalf("TaskXML: %v", err)
+			}
+			path := filepath.Join("testdata", tt.golden)
+			if *update {
+				if err = os.WriteFile(path, got, 0o644); err != nil {
+					t.Fatalf("write golden: %v", err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("read golden: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("TaskXML mismatch (run with -update to accept)\n got:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+
+	if _, err := TaskXML("  ", TaskOptions{UserID: "u"}); err == nil {
+		t.Fatalf("expected error for empty command")
+	}
+	if _, err := TaskXML("app.exe", TaskOptions{}); err == nil {
+		t.Fatalf("expected error for missing user")
+	}
+}
+
+func TestEncodeTaskXML(t *testing.T) {
+	got := EncodeTaskXML([]byte("<a>é</a>"))
+	if got[0] != 0xFF || got[1] != 0xFE || len(got)%2 != 0 {
+		t.Fatalf("missing UTF-16LE BOM: % x", got[:2])
+	}
+	units := make([]uint16, 0, len(got)/2-1)
+	for i := 2; i < len(got); i += 2 {
+		units = append(units, uint16(got[i])|uint16(got[i+1])<<8)
+	}
+	if s := string(utf16.Decode(units)); s != "<a>é</a>" {
+		t.Fatalf("decoded %q", s)
+	}
+}
+
+func TestTaskNameAndDuration(t *testing.T) {
+	if got := TaskName("WinTray", `CORP\bob`); got != `WinTray\Logon-bob` {
+		t.Errorf("TaskName = %q", got)
+	}
+	if got := TaskName("WinTray", ""); got != `WinTray\Logon` {
+		t.Errorf("TaskName(empty) = %q", got)
+	}
+	durations := map[time.Duration]string{
+		0:                         "",
+		30 * time.Second:          "PT30S",
+		90 * time.Second:          "PT1M30S",
+		time.Hour + 5*time.Second: "PT1H5S",
+		10 * time.Minute:          "PT10M",
+	}
+	for d, want := range durations {
+		if got := taskDuration(d); got != want {
+			t.Errorf("taskDuration(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package tray
+
+type Callbacks struct {
+	OnOpen          func()
+	OnRestoreWindow func(handle uintptr)
+	OnRestoreAll    func()
+	// OnRestorePreviousSession restores windows hidden by an earlier session.
+	OnRestorePreviousSession func()
+	// OnOpenOutput opens the latest captured output of a managed entry.
+	OnOpenOutput func(entryID string)
+	// OnShowHistory opens the run history.
+	OnShowHistory func()
+	// OnCollectDiagnostics writes a diagnostics bundle.
+	OnCollectDiagnostics func()
+	OnStopManaged        func()
+	OnExit               func()
+	// Proxy handles clicks on per-app proxy icons.
+	Proxy ProxyActions
+}
+
+// OutputItem is a managed entry whose captured output can be opened from the tray.
+type OutputItem struct {
+	EntryID string
+	AppName string
+}
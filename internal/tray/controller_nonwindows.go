@@ -2,11 +2,23 @@
 
 package tray
 
+import "wintray/internal/history"
+
 type Controller struct{}
 
-func New(_ any, _ func(), _ func(), _ string) (*Controller, error) {
+func New(_ any, _ Callbacks, _ string) (*Controller, error) {
 	return &Controller{}, nil
 }
 
-func (c *Controller) SetLanguage(_ string) {}
-func (c *Controller) Dispose()             {}
+func (c *Controller) SetLanguage(_ string)                                   {}
+func (c *Controller) SetHiddenWindows(_ []HiddenWindowItem)                  {}
+func (c *Controller) SetOutputEntries(_ []OutputItem)                        {}
+func (c *Controller) SetLastRun(_ history.Run)                               {}
+func (c *Controller) ShowInfo(_, _ string)                                   {}
+func (c *Controller) ShowWarning(_, _ string)                                {}
+func (c *Controller) ShowNotification(_, _ string, _ bool, _ func())         {}
+func (c *Controller) EnsureProxyIcon(_ ProxyTarget) error                    { return nil }
+func (c *Controller) RemoveProxyIcon(_ uintptr)                              {}
+func (c *Controller) PruneProxyIcons(_ func(ProxyTarget) bool) []ProxyTarget { return nil }
+func (c *Controller) ProxyTargets() []ProxyTarget                            { return nil }
+func (c *Controller) Dispose()                                               {}
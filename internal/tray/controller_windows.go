@@ -3,21 +3,38 @@
 package tray
 
 import (
+	"fmt"
+
 	"github.com/lxn/walk"
+	"wintray/internal/history"
 	"wintray/internal/i18n"
 )
 
 type Controller struct {
-	notifyIcon *walk.NotifyIcon
-	openAction *walk.Action
-	exitAction *walk.Action
-	language   string
+	notifyIcon    *walk.NotifyIcon
+	callbacks     Callbacks
+	openAction    *walk.Action
+	hiddenMenu    *walk.Menu
+	hiddenAction  *walk.Action
+	outputMenu    *walk.Menu
+	outputAction  *walk.Action
+	historyAction *walk.Action
+	diagAction    *walk.Action
+	stopAction    *walk.Action
+	exitAction    *walk.Action
+	hidden        []HiddenWindowItem
+	outputs       []OutputItem
+	lastRun       *history.Run
+	// onBalloonClick handles clicks on the balloon shown last.
+	onBalloonClick func()
+	proxyFactory   *NotifyIconFactory
+	proxies        *ProxyIcons
+	language       string
 }
 
 func New(
 	window *walk.MainWindow,
-	showMainWindow func(),
-	exitApp func(),
+	callbacks Callbacks,
 	language string,
 ) (*Controller, error) {
 	ni, err := walk.NewNotifyIcon(window)
@@ -27,26 +44,54 @@ func New(
 
 	c := &Controller{
 		notifyIcon: ni,
+		callbacks:  callbacks,
 		language:   language,
 	}
+	c.proxyFactory = NewNotifyIconFactory(window, language)
+	c.proxies = NewProxyIcons(c.proxyFactory, callbacks.Proxy)
 
-	openAction := walk.NewAction()
-	openAction.Triggered().Attach(func() {
-		showMainWindow()
-	})
-	c.openAction = openAction
-	ni.ContextMenu().Actions().Add(openAction)
+	c.openAction = addAction(ni, callbacks.OnOpen)
+	hiddenMenu, err := walk.NewMenu()
+	if err != nil {
+		ni.Dispose()
+		return nil, err
+	}
+	c.hiddenMenu = hiddenMenu
+	c.hiddenAction = walk.NewMenuAction(hiddenMenu)
+	_ = c.hiddenAction.SetVisible(false)
+	if err = ni.ContextMenu().Actions().Add(c.hiddenAction); err != nil {
+		ni.Dispose()
+		return nil, err
+	}
+	outputMenu, err := walk.NewMenu()
+	if err != nil {
+		ni.Dispose()
+		return nil, err
+	}
+	c.outputMenu = outputMenu
+	c.outputAction = walk.NewMenuAction(outputMenu)
+	_ = c.outputAction.SetVisible(false)
+	if err = ni.ContextMenu().Actions().Add(c.outputAction); err != nil {
+		ni.Dispose()
+		return nil, err
+	}
+	c.historyAction = addAction(ni, callbacks.OnShowHistory)
+	c.diagAction = addAction(ni, callbacks.OnCollectDiagnostics)
+	c.stopAction = addAction(ni, callbacks.OnStopManaged)
+	if err = ni.ContextMenu().Actions().Add(walk.NewSeparatorAction()); err != nil {
+		ni.Dispose()
+		return nil, err
+	}
+	c.exitAction = addAction(ni, callbacks.OnExit)
 
-	exitAction := walk.NewAction()
-	exitAction.Triggered().Attach(func() {
-		exitApp()
+	ni.MessageClicked().Attach(func() {
+		if c.onBalloonClick != nil {
+			c.onBalloonClick()
+		}
 	})
-	c.exitAction = exitAction
-	ni.ContextMenu().Actions().Add(exitAction)
-
 	ni.MouseDown().Attach(func(x, y int, button walk.MouseButton) {
-		if button == walk.LeftButton {
-			showMainWindow()
+		if button == walk.LeftButton && callbacks.OnOpen != nil {
+			callbacks.OnOpen()
 		}
 	})
 
@@ -59,25 +104,233 @@ func New(
 	return c, nil
 }
 
+func addAction(ni *walk.NotifyIcon, onTriggered func()) *walk.Action {
+	action := walk.NewAction()
+	action.Triggered().Attach(func() {
+		if onTriggered != nil {
+			onTriggered()
+		}
+	})
+	ni.ContextMenu().Actions().Add(action)
+	return action
+}
+
 func (c *Controller) SetLanguage(language string) {
 	if c == nil || c.notifyIcon == nil {
 		return
 	}
 	c.language = language
+	c.proxyFactory.SetLanguage(language)
 	msg := i18n.For(language)
 	_ = c.notifyIcon.SetToolTip(msg.TrayToolTip)
 	if c.openAction != nil {
 		c.openAction.SetText(msg.TrayOpenSettings)
 	}
+	if c.hiddenAction != nil {
+		c.hiddenAction.SetText(msg.TrayHiddenWindows)
+	}
+	if c.outputAction != nil {
+		c.outputAction.SetText(msg.TrayOpenOutput)
+	}
+	c.updateHistoryAction()
+	if c.diagAction != nil {
+		c.diagAction.SetText(msg.TrayCollectDiagnostics)
+	}
+	if c.stopAction != nil {
+		c.stopAction.SetText(msg.TrayStopManaged)
+	}
 	if c.exitAction != nil {
 		c.exitAction.SetText(msg.TrayExit)
 	}
+	c.rebuildHiddenMenu()
+	c.rebuildOutputMenu()
+}
+
+// SetLastRun shows a summary of run on the run history item. It must be
+// called on the UI thread.
+func (c *Controller) SetLastRun(run history.Run) {
+	if c == nil || c.notifyIcon == nil {
+		return
+	}
+	c.lastRun = &run
+	c.updateHistoryAction()
+}
+
+func (c *Controller) updateHistoryAction() {
+	if c.historyAction == nil {
+		return
+	}
+	msg := i18n.For(c.language)
+	if c.lastRun == nil {
+		_ = c.historyAction.SetText(msg.RunHistory)
+		return
+	}
+	at := c.lastRun.StartedAt.Local().Format("01-02 15:04")
+	_ = c.historyAction.SetText(fmt.Sprintf(msg.TrayLastRun, c.lastRun.Managed(), len(c.lastRun.Entries), at))
+}
+
+// SetOutputEntries replaces the "open latest output" submenu. It must be called
+// on the UI thread.
+func (c *Controller) SetOutputEntries(items []OutputItem) {
+	if c == nil || c.notifyIcon == nil {
+		return
+	}
+	c.outputs = items
+	c.rebuildOutputMenu()
+}
+
+func (c *Controller) rebuildOutputMenu() {
+	if c.outputMenu == nil {
+		return
+	}
+	msg := i18n.For(c.language)
+	actions := c.outputMenu.Actions()
+	_ = actions.Clear()
+	for _, item := range c.outputs {
+		entryID := item.EntryID
+		action := walk.NewAction()
+		_ = action.SetText(menuTitle(item.AppName, msg.TrayUntitledWindow))
+		action.Triggered().Attach(func() {
+			if c.callbacks.OnOpenOutput != nil {
+				c.callbacks.OnOpenOutput(entryID)
+			}
+		})
+		_ = actions.Add(action)
+	}
+	_ = c.outputAction.SetVisible(len(c.outputs) > 0)
+}
+
+// SetHiddenWindows replaces the "hidden windows" submenu. It must be called on
+// the UI thread.
+func (c *Controller) SetHiddenWindows(items []HiddenWindowItem) {
+	if c == nil || c.notifyIcon == nil {
+		return
+	}
+	c.hidden = items
+	c.rebuildHiddenMenu()
+}
+
+func (c *Controller) rebuildHiddenMenu() {
+	if c.hiddenMenu == nil {
+		return
+	}
+	msg := i18n.For(c.language)
+	actions := c.hiddenMenu.Actions()
+	_ = actions.Clear()
+	for _, group := range groupHiddenByApp(c.hidden) {
+		appMenu, err := walk.NewMenu()
+		if err != nil {
+			continue
+		}
+		for _, item := range group.Items {
+			handle := item.Handle
+			action := walk.NewAction()
+			_ = action.SetText(menuTitle(item.Title, msg.TrayUntitledWindow))
+			action.Triggered().Attach(func() {
+				if c.callbacks.OnRestoreWindow != nil {
+					c.callbacks.OnRestoreWindow(handle)
+				}
+			})
+			_ = appMenu.Actions().Add(action)
+		}
+		appAction := walk.NewMenuAction(appMenu)
+		_ = appAction.SetText(menuTitle(group.AppName, msg.TrayUntitledWindow))
+		_ = actions.Add(appAction)
+	}
+	if len(c.hidden) > 0 {
+		_ = actions.Add(walk.NewSeparatorAction())
+		if hasPreviousSession(c.hidden) {
+			restorePrevious := walk.NewAction()
+			_ = restorePrevious.SetText(msg.TrayRestorePreviousSession)
+			restorePrevious.Triggered().Attach(func() {
+				if c.callbacks.OnRestorePreviousSession != nil {
+					c.callbacks.OnRestorePreviousSession()
+				}
+			})
+			_ = actions.Add(restorePrevious)
+		}
+		restoreAll := walk.NewAction()
+		_ = restoreAll.SetText(msg.TrayRestoreAll)
+		restoreAll.Triggered().Attach(func() {
+			if c.callbacks.OnRestoreAll != nil {
+				c.callbacks.OnRestoreAll()
+			}
+		})
+		_ = actions.Add(restoreAll)
+	}
+	_ = c.hiddenAction.SetVisible(len(c.hidden) > 0)
+}
+
+// ShowInfo displays an informational balloon notification from the tray icon.
+func (c *Controller) ShowInfo(title, body string) {
+	if c == nil || c.notifyIcon == nil {
+		return
+	}
+	c.onBalloonClick = nil
+	_ = c.notifyIcon.ShowInfo(title, body)
+}
+
+// ShowWarning displays a balloon notification from the tray icon.
+func (c *Controller) ShowWarning(title, body string) {
+	if c == nil || c.notifyIcon == nil {
+		return
+	}
+	c.onBalloonClick = nil
+	_ = c.notifyIcon.ShowWarning(title, body)
+}
+
+// ShowNotification displays a balloon that calls onClick when clicked. It
+// must be called on the UI thread.
+func (c *Controller) ShowNotification(title, body string, warning bool, onClick func()) {
+	if c == nil || c.notifyIcon == nil {
+		return
+	}
+	c.onBalloonClick = onClick
+	if warning {
+		_ = c.notifyIcon.ShowWarning(title, body)
+		return
+	}
+	_ = c.notifyIcon.ShowInfo(title, body)
+}
+
+// EnsureProxyIcon shows a proxy icon for target. It must be called on the UI thread.
+func (c *Controller) EnsureProxyIcon(target ProxyTarget) error {
+	if c == nil || c.notifyIcon == nil {
+		return nil
+	}
+	return c.proxies.Ensure(target)
+}
+
+// RemoveProxyIcon removes the proxy icon of a window. It must be called on the UI thread.
+func (c *Controller) RemoveProxyIcon(handle uintptr) {
+	if c == nil || c.notifyIcon == nil {
+		return
+	}
+	c.proxies.Remove(handle)
+}
+
+// PruneProxyIcons removes proxy icons whose app has exited. It must be called
+// on the UI thread.
+func (c *Controller) PruneProxyIcons(alive func(ProxyTarget) bool) []ProxyTarget {
+	if c == nil || c.notifyIcon == nil {
+		return nil
+	}
+	return c.proxies.Prune(alive)
+}
+
+// ProxyTargets lists the windows that currently have a proxy icon.
+func (c *Controller) ProxyTargets() []ProxyTarget {
+	if c == nil || c.notifyIcon == nil {
+		return nil
+	}
+	return c.proxies.Targets()
 }
 
 func (c *Controller) Dispose() {
 	if c == nil || c.notifyIcon == nil {
 		return
 	}
+	c.proxies.Dispose()
 	_ = c.notifyIcon.SetVisible(false)
 	c.notifyIcon.Dispose()
 	c.notifyIcon = nil
@@ -0,0 +1,57 @@
+package tray
+
+import "strings"
+
+// HiddenWindowItem is a window WinTray hid that can be restored from the tray.
+type HiddenWindowItem struct {
+	Handle              uintptr
+	AppName             string
+	Title               string
+	FromPreviousSession bool
+}
+
+type hiddenGroup struct {
+	AppName string
+	Items   []HiddenWindowItem
+}
+
+// groupHiddenByApp groups items by app name, keeping first-seen order.
+func groupHiddenByApp(items []HiddenWindowItem) []hiddenGroup {
+	groups := make([]hiddenGroup, 0)
+	index := map[string]int{}
+	for _, item := range items {
+		i, ok := index[item.AppName]
+		if !ok {
+			i = len(groups)
+			index[item.AppName] = i
+			groups = append(groups, hiddenGroup{AppName: item.AppName})
+		}
+		groups[i].Items = append(groups[i].Items, item)
+	}
+	return groups
+}
+
+func hasPreviousSession(items []HiddenWindowItem) bool {
+	for _, item := range items {
+		if item.FromPreviousSession {
+			return true
+		}
+	}
+	return false
+}
+
+const maxMenuTitleRunes = 60
+
+// menuTitle trims long window titles and escapes '&' so it is not taken as a
+// menu mnemonic.
+func menuTitle(title, fallback string) string {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return fallback
+	}
+	runes := []rune(title)
+	if len(runes) > maxMenuTitleRunes {
+		title = string(runes[:maxMenuTitleRunes]) + "…"
+	}
+	return strings.ReplaceAll(title, "&", "&&")
+}
@@ -0,0 +1,108 @@
+package tray
+
+import "sort"
+
+// ProxyTarget is an app window represented by a WinTray-owned proxy icon.
+type ProxyTarget struct {
+	Handle    uintptr
+	ProcessID uint32
+	EntryID   string
+	AppName   string
+	Title     string
+	ExePath   string
+}
+
+// ProxyActions are invoked from a proxy icon's click and context menu.
+type ProxyActions struct {
+	OnToggle func(ProxyTarget)
+	OnShow   func(ProxyTarget)
+	OnHide   func(ProxyTarget)
+	OnQuit   func(ProxyTarget)
+}
+
+// ProxyIcon is a single dynamically created notify icon.
+type ProxyIcon interface {
+	Dispose()
+}
+
+// ProxyIconFactory creates the platform notify icon for a target.
+type ProxyIconFactory interface {
+	NewProxyIcon(target ProxyTarget, actions ProxyActions) (ProxyIcon, error)
+}
+
+// ProxyIcons keeps one proxy icon per window handle. It is not safe for
+// concurrent use; on Windows all calls happen on the UI thread.
+type ProxyIcons struct {
+	factory ProxyIconFactory
+	actions ProxyActions
+	icons   map[uintptr]proxyIconEntry
+}
+
+type proxyIconEntry struct {
+	target ProxyTarget
+	icon   ProxyIcon
+}
+
+func NewProxyIcons(factory ProxyIconFactory, actions ProxyActions) *ProxyIcons {
+	return &ProxyIcons{factory: factory, actions: actions, icons: map[uintptr]proxyIconEntry{}}
+}
+
+// Ensure creates an icon for target unless its window already has one.
+func (p *ProxyIcons) Ensure(target ProxyTarget) error {
+	if _, ok := p.icons[target.Handle]; ok {
+		return nil
+	}
+	icon, err := p.factory.NewProxyIcon(target, p.actions)
+	if err != nil {
+		return err
+	}
+	p.icons[target.Handle] = proxyIconEntry{target: target, icon: icon}
+	return nil
+}
+
+// Remove disposes the icon of a window, if any.
+func (p *ProxyIcons) Remove(handle uintptr) {
+	entry, ok := p.icons[handle]
+	if !ok {
+		return
+	}
+	entry.icon.Dispose()
+	delete(p.icons, handle)
+}
+
+// Prune removes the icons whose target is no longer alive and returns them.
+func (p *ProxyIcons) Prune(alive func(ProxyTarget) bool) []ProxyTarget {
+	removed := make([]ProxyTarget, 0)
+	for handle, entry := range p.icons {
+		if alive(entry.target) {
+			continue
+		}
+		entry.icon.Dispose()
+		delete(p.icons, handle)
+		removed = append(removed, entry.target)
+	}
+	return removed
+}
+
+// Targets returns the current targets ordered by app name.
+func (p *ProxyIcons) Targets() []ProxyTarget {
+	targets := make([]ProxyTarget, 0, len(p.icons))
+	for _, entry := range p.icons {
+		targets = append(targets, entry.target)
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i].AppName != targets[j].AppName {
+			return targets[i].AppName < targets[j].AppName
+		}
+		return targets[i].Handle < targets[j].Handle
+	})
+	return targets
+}
+
+// Dispose removes every proxy icon.
+func (p *ProxyIcons) Dispose() {
+	for handle, entry := range p.icons {
+		entry.icon.Dispose()
+		delete(p.icons, handle)
+	}
+}
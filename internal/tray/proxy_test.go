@@ -0,0 +1,122 @@
+package tray
+
+import (
+	"errors"
+	"testing"
+)
+
+type fakeProxyIcon struct {
+	disposed bool
+}
+
+func (i *fakeProxyIcon) Dispose() { i.disposed = true }
+
+type fakeProxyFactory struct {
+	created map[uintptr]*fakeProxyIcon
+	fail    map[uintptr]bool
+}
+
+func (f *fakeProxyFactory) NewProxyIcon(target ProxyTarget, _ ProxyActions) (ProxyIcon, error) {
+	if f.fail[target.Handle] {
+		return nil, errors.New("notify icon unavailable")
+	}
+	icon := &fakeProxyIcon{}
+	f.created[target.Handle] = icon
+	return icon, nil
+}
+
+func TestProxyIconsEnsureIsIdempotent(t *testing.T) {
+	factory := &fakeProxyFactory{created: map[uintptr]*fakeProxyIcon{}}
+	proxies := NewProxyIcons(factory, ProxyActions{})
+
+	target := ProxyTarget{Handle: 0x10, ProcessID: 1, AppName: "A"}
+	if err := proxies.Ensure(target); err != nil {
+		t.Fatalf("Ensure: %v", err)
+	}
+	first := factory.created[0x10]
+	if err := proxies.Ensure(target); err != nil {
+		t.Fatalf("Ensure again: %v", err)
+	}
+	if factory.created[0x10] != first {
+		t.Fatal("second Ensure created a new icon for the same window")
+	}
+	if got := proxies.Targets(); len(got) != 1 {
+		t.Fatalf("Targets = %+v, want 1", got)
+	}
+}
+
+func TestProxyIconsFactoryErrorIsNotTracked(t *testing.T) {
+	factory := &fakeProxyFactory{created: map[uintptr]*fakeProxyIcon{}, fail: map[uintptr]bool{0x20: true}}
+	proxies := NewProxyIcons(factory, ProxyActions{})
+
+	if err := proxies.Ensure(ProxyTarget{Handle: 0x20}); err == nil {
+		t.Fatal("Ensure succeeded, want factory error")
+	}
+	if got := proxies.Targets(); len(got) != 0 {
+		t.Fatalf("Targets = %+v, want none after failure", got)
+	}
+}
+
+func TestProxyIconsPruneDisposesExitedApps(t *testing.T) {
+	factory := &fakeProxyFactory{created: map[uintptr]*fakeProxyIcon{}}
+	proxies := NewProxyIcons(factory, ProxyActions{})
+	for _, target := range []ProxyTarget{
+		{Handle: 0x1, ProcessID: 100, AppName: "B"},
+		{Handle: 0x2, ProcessID: 200, AppName: "A"},
+		{Handle: 0x3, ProcessID: 300, AppName: "C"},
+	} {
+		if err := proxies.Ensure(target); err != nil {
+			t.Fatalf("Ensure: %v", err)
+		}
+	}
+
+	removed := proxies.Prune(func(target ProxyTarget) bool { return target.ProcessID != 200 })
+	if len(removed) != 1 || removed[0].Handle != 0x2 {
+		t.Fatalf("Prune removed %+v, want hwnd 0x2", removed)
+	}
+	if !factory.created[0x2].disposed {
+		t.Fatal("pruned icon not disposed")
+	}
+
+	targets := proxies.Targets()
+	if len(targets) != 2 || targets[0].AppName != "B" || targets[1].AppName != "C" {
+		t.Fatalf("Targets = %+v, want B then C", targets)
+	}
+
+	proxies.Remove(0x1)
+	if !factory.created[0x1].disposed {
+		t.Fatal("removed icon not disposed")
+	}
+
+	proxies.Dispose()
+	if !factory.created[0x3].disposed {
+		t.Fatal("Dispose left an icon behind")
+	}
+	if got := proxies.Targets(); len(got) != 0 {
+		t.Fatalf("Targets after Dispose = %+v, want none", got)
+	}
+}
+
+func TestGroupHiddenByAppKeepsFirstSeenOrder(t *testing.T) {
+	groups := groupHiddenByApp([]HiddenWindowItem{
+		{Handle: 1, AppName: "Mail"},
+		{Handle: 2, AppName: "Chat"},
+		{Handle: 3, AppName: "Mail"},
+	})
+	if len(groups) != 2 || groups[0].AppName != "Mail" || len(groups[0].Items) != 2 || groups[1].AppName != "Chat" {
+		t.Fatalf("groups = %+v", groups)
+	}
+}
+
+func TestMenuTitle(t *testing.T) {
+	if got := menuTitle("  ", "(untitled)"); got != "(untitled)" {
+		t.Fatalf("menuTitle blank = %q", got)
+	}
+	if got := menuTitle("Tom & Jerry", ""); got != "Tom && Jerry" {
+		t.Fatalf("menuTitle ampersand = %q", got)
+	}
+	long := string(make([]rune, maxMenuTitleRunes+10))
+	if got := []rune(menuTitle(long+"x", "")); len(got) != maxMenuTitleRunes+1 {
+		t.Fatalf("menuTitle length = %d, want %d", len(got), maxMenuTitleRunes+1)
+	}
+}
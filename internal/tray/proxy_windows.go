@@ -0,0 +1,84 @@
+//go:build windows
+
+package tray
+
+import (
+	"github.com/lxn/walk"
+	"wintray/internal/i18n"
+)
+
+// NotifyIconFactory creates proxy icons as walk notify icons showing the
+// target executable's own icon.
+type NotifyIconFactory struct {
+	form     walk.Form
+	language string
+}
+
+func NewNotifyIconFactory(form walk.Form, language string) *NotifyIconFactory {
+	return &NotifyIconFactory{form: form, language: language}
+}
+
+func (f *NotifyIconFactory) SetLanguage(language string) {
+	f.language = language
+}
+
+type notifyProxyIcon struct {
+	notifyIcon *walk.NotifyIcon
+	icon       *walk.Icon
+}
+
+func (f *NotifyIconFactory) NewProxyIcon(target ProxyTarget, actions ProxyActions) (ProxyIcon, error) {
+	ni, err := walk.NewNotifyIcon(f.form)
+	if err != nil {
+		return nil, err
+	}
+	proxy := &notifyProxyIcon{notifyIcon: ni}
+	if target.ExePath != "" {
+		if icon, iconErr := walk.NewIconExtractedFromFile(target.ExePath, 0, 16); iconErr == nil {
+			proxy.icon = icon
+			_ = ni.SetIcon(icon)
+		}
+	}
+	_ = ni.SetToolTip(target.AppName)
+
+	msg := i18n.For(f.language)
+	addProxyAction(ni, msg.ProxyShow, target, actions.OnShow)
+	addProxyAction(ni, msg.ProxyHide, target, actions.OnHide)
+	_ = ni.ContextMenu().Actions().Add(walk.NewSeparatorAction())
+	addProxyAction(ni, msg.ProxyQuit, target, actions.OnQuit)
+
+	ni.MouseDown().Attach(func(x, y int, button walk.MouseButton) {
+		if button == walk.LeftButton && actions.OnToggle != nil {
+			actions.OnToggle(target)
+		}
+	})
+
+	if err = ni.SetVisible(true); err != nil {
+		proxy.Dispose()
+		return nil, err
+	}
+	return proxy, nil
+}
+
+func addProxyAction(ni *walk.NotifyIcon, text string, target ProxyTarget, fn func(ProxyTarget)) {
+	action := walk.NewAction()
+	_ = action.SetText(text)
+	action.Triggered().Attach(func() {
+		if fn != nil {
+			fn(target)
+		}
+	})
+	_ = ni.ContextMenu().Actions().Add(action)
+}
+
+func (p *notifyProxyIcon) Dispose() {
+	if p.notifyIcon != nil {
+		_ = p.notifyIcon.SetVisible(false)
+		_ = p.notifyIcon.Dispose()
+		p.notifyIcon = nil
+	}
+	if p.icon != nil {
+		p.icon.Dispose()
+		p.icon = nil
+	}
+}
@@ -0,0 +1,81 @@
+//go:build windows
+
+package ui
+
+import (
+	"github.com/lxn/walk"
+	"github.com/lxn/win"
+	"wintray/internal/history"
+	"wintray/internal/i18n"
+)
+
+// showHistoryDialog lists runs, newest first, with the details of the
+// selected run below.
+func showHistoryDialog(owner walk.Form, language string, runs []history.Run) error {
+	msg := i18n.For(language)
+	dlg, err := walk.NewDialog(owner)
+	if err != nil {
+		return err
+	}
+	defer dlg.Dispose()
+
+	_ = dlg.SetTitle(msg.RunHistory)
+	if err = dlg.SetLayout(walk.NewVBoxLayout()); err != nil {
+		return err
+	}
+	_ = dlg.SetMinMaxSize(walk.Size{Width: 560, Height: 420}, walk.Size{})
+	_ = dlg.SetSize(walk.Size{Width: 760, Height: 560})
+
+	list, err := walk.NewListBox(dlg)
+	if err != nil {
+		return err
+	}
+	list.SetMinMaxSize(walk.Size{Height: 140}, walk.Size{Height: 200})
+	summaries := make([]string, len(runs))
+	for i, run := range runs {
+		summaries[i] = i18n.FormatRunSummary(language, run)
+	}
+	if err = list.SetModel(summaries); err != nil {
+		return err
+	}
+
+	details, err := walk.NewTextEditWithStyle(dlg, win.WS_VSCROLL)
+	if err != nil {
+		return err
+	}
+	_ = details.SetReadOnly(true)
+	list.CurrentIndexChanged().Attach(func() {
+		idx := list.CurrentIndex()
+		if idx < 0 || idx >= len(runs) {
+			details.SetText("")
+			return
+		}
+		details.SetText(i18n.FormatRun(language, runs[idx]))
+	})
+	if len(runs) == 0 {
+		details.SetText(msg.RunHistoryNone)
+	} else {
+		_ = list.SetCurrentIndex(0)
+	}
+
+	row, err := walk.NewComposite(dlg)
+	if err != nil {
+		return err
+	}
+	if err = row.SetLayout(walk.NewHBoxLayout()); err != nil {
+		return err
+	}
+	if _, err = walk.NewHSpacer(row); err != nil {
+		return err
+	}
+	closeBtn, err := walk.NewPushButton(row)
+	if err != nil {
+		return err
+	}
+	_ = closeBtn.SetText(msg.DialogClose)
+	closeBtn.Clicked().Attach(dlg.Cancel)
+	_ = dlg.SetCancelButton(closeBtn)
+
+	dlg.Run()
+	return nil
+}
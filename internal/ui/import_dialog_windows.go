@@ -0,0 +1,108 @@
+//go:build windows
+
+package ui
+
+import (
+	"fmt"
+
+	"github.com/lxn/walk"
+	"wintray/internal/i18n"
+	"wintray/internal/startup"
+)
+
+// showImportDialog previews items and lets the user pick which to import.
+// It returns the chosen items and whether their originals should be disabled;
+// ok is false when the dialog was cancelled.
+func showImportDialog(owner walk.Form, msg i18n.Messages, items []startup.Item) (selected []startup.Item, disable bool, ok bool, err error) {
+	dlg, err := walk.NewDialog(owner)
+	if err != nil {
+		return nil, false, false, err
+	}
+	defer dlg.Dispose()
+
+	_ = dlg.SetTitle(msg.ImportStartupTitle)
+	if err = dlg.SetLayout(walk.NewVBoxLayout()); err != nil {
+		return nil, false, false, err
+	}
+	_ = dlg.SetMinMaxSize(walk.Size{Width: 520, Height: 360}, walk.Size{})
+	_ = dlg.SetSize(walk.Size{Width: 640, Height: 480})
+
+	hint, err := walk.NewLabel(dlg)
+	if err != nil {
+		return nil, false, false, err
+	}
+	_ = hint.SetText(msg.ImportStartupHint)
+
+	scroll, err := walk.NewScrollView(dlg)
+	if err != nil {
+		return nil, false, false, err
+	}
+	if err = scroll.SetLayout(walk.NewVBoxLayout()); err != nil {
+		return nil, false, false, err
+	}
+	checks := make([]*walk.CheckBox, len(items))
+	for i, item := range items {
+		cb, cbErr := walk.NewCheckBox(scroll)
+		if cbErr != nil {
+			return nil, false, false, cbErr
+		}
+		_ = cb.SetText(importItemText(msg, item))
+		_ = cb.SetToolTipText(item.Command)
+		// Items disabled in Task Manager are offered but not preselected.
+		cb.SetChecked(item.Enabled)
+		checks[i] = cb
+	}
+	if _, err = walk.NewVSpacer(scroll); err != nil {
+		return nil, false, false, err
+	}
+
+	disableCheck, err := walk.NewCheckBox(dlg)
+	if err != nil {
+		return nil, false, false, err
+	}
+	_ = disableCheck.SetText(msg.ImportStartupDisableOriginals)
+	disableCheck.SetChecked(true)
+
+	row, err := walk.NewComposite(dlg)
+	if err != nil {
+		return nil, false, false, err
+	}
+	if err = row.SetLayout(walk.NewHBoxLayout()); err != nil {
+		return nil, false, false, err
+	}
+	if _, err = walk.NewHSpacer(row); err != nil {
+		return nil, false, false, err
+	}
+	importBtn, err := walk.NewPushButton(row)
+	if err != nil {
+		return nil, false, false, err
+	}
+	_ = importBtn.SetText(msg.ImportStartupConfirm)
+	importBtn.Clicked().Attach(dlg.Accept)
+	cancelBtn, err := walk.NewPushButton(row)
+	if err != nil {
+		return nil, false, false, err
+	}
+	_ = cancelBtn.SetText(msg.DialogCancel)
+	cancelBtn.Clicked().Attach(dlg.Cancel)
+	_ = dlg.SetDefaultButton(importBtn)
+	_ = dlg.SetCancelButton(cancelBtn)
+
+	if dlg.Run() != walk.DlgCmdOK {
+		return nil, false, false, nil
+	}
+	for i, cb := range checks {
+		if cb.Checked() {
+			selected = append(selected, items[i])
+		}
+	}
+	return selected, disableCheck.Checked(), true, nil
+}
+
+func importItemText(msg i18n.Messages, item startup.Item) string {
+	text := fmt.Sprintf("%s — %s", item.Name, item.Target)
+	if !item.Enabled {
+		text += msg.ImportStartupDisabled
+	}
+	return text
+}
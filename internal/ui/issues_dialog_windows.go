@@ -0,0 +1,55 @@
+//go:build windows
+
+package ui
+
+import (
+	"github.com/lxn/walk"
+	"github.com/lxn/win"
+	"wintray/internal/config"
+	"wintray/internal/i18n"
+)
+
+// showIssuesDialog lists the settings issues, one per line.
+func showIssuesDialog(owner walk.Form, language string, issues config.Issues) error {
+	msg := i18n.For(language)
+	dlg, err := walk.NewDialog(owner)
+	if err != nil {
+		return err
+	}
+	defer dlg.Dispose()
+
+	_ = dlg.SetTitle(msg.ValidationTitle)
+	if err = dlg.SetLayout(walk.NewVBoxLayout()); err != nil {
+		return err
+	}
+	_ = dlg.SetMinMaxSize(walk.Size{Width: 480, Height: 260}, walk.Size{})
+	_ = dlg.SetSize(walk.Size{Width: 720, Height: 380})
+
+	text, err := walk.NewTextEditWithStyle(dlg, win.WS_VSCROLL)
+	if err != nil {
+		return err
+	}
+	_ = text.SetReadOnly(true)
+	text.SetText(i18n.FormatIssues(language, issues))
+
+	row, err := walk.NewComposite(dlg)
+	if err != nil {
+		return err
+	}
+	if err = row.SetLayout(walk.NewHBoxLayout()); err != nil {
+		return err
+	}
+	if _, err = walk.NewHSpacer(row); err != nil {
+		return err
+	}
+	closeBtn, err := walk.NewPushButton(row)
+	if err != nil {
+		return err
+	}
+	_ = closeBtn.SetText(msg.DialogClose)
+	closeBtn.Clicked().Attach(dlg.Cancel)
+	_ = dlg.SetCancelButton(closeBtn)
+
+	dlg.Run()
+	return nil
+}
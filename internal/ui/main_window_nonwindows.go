@@ -2,13 +2,21 @@
 
 package ui
 
-import "wintray/internal/config"
+import (
+	"wintray/internal/config"
+	"wintray/internal/history"
+	"wintray/internal/startup"
+)
 
 type Callbacks struct {
-	OnSave           func(config.Settings)
-	OnOpenLogs       func()
-	OnCleanupRestore func()
-	OnExit           func()
+	OnSave              func(config.Settings)
+	OnOpenLogs          func()
+	OnCleanupRestore    func()
+	OnExit              func()
+	OnScanStartup       func() ([]startup.Item, error)
+	OnDisableStartup    func([]startup.Item) error
+	OnUndoStartupImport func() (int, error)
+	OnLoadHistory       func() ([]history.Run, error)
 }
 
 type MainWindow struct{}
@@ -16,6 +24,13 @@ type MainWindow struct{}
 func NewMainWindow(_ config.Settings, _ Callbacks) (*MainWindow, error) { return &MainWindow{}, nil }
 func (w *MainWindow) ShowMainWindow()                                   {}
 func (w *MainWindow) HideMainWindow()                                   {}
+func (w *MainWindow) SetLogonStatus(_ string)                           {}
+func (w *MainWindow) ShowHistory()                                      {}
+func (w *MainWindow) SetValidationIssues(_ config.Issues)               {}
+func (w *MainWindow) ShowValidationIssues()                             {}
+func (w *MainWindow) ConfirmExternalChange() bool                       { return true }
+func (w *MainWindow) ConfirmOverwrite() bool                            { return true }
+func (w *MainWindow) ReloadSettings(_ config.Settings)                  {}
 func (w *MainWindow) Run() int                                          { return 0 }
 func (w *MainWindow) RequestExplicitClose()                             {}
 func (w *MainWindow) Native() any                                       { return nil }
@@ -3,16 +3,21 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 	"unsafe"
 
 	"github.com/lxn/walk"
 	"github.com/lxn/win"
+	"wintray/internal/cmdline"
 	"wintray/internal/config"
+	"wintray/internal/history"
 	"wintray/internal/i18n"
+	"wintray/internal/startup"
 	"wintray/internal/stringutil"
 )
 
@@ -21,6 +26,16 @@ type Callbacks struct {
 	OnOpenLogs       func()
 	OnCleanupRestore func()
 	OnExit           func()
+	// OnScanStartup lists the existing startup items offered for import.
+	OnScanStartup func() ([]startup.Item, error)
+	// OnDisableStartup disables the originals of imported items, keeping
+	// what is needed to undo it.
+	OnDisableStartup func([]startup.Item) error
+	// OnUndoStartupImport restores the originals disabled by imports and
+	// returns how many were restored.
+	OnUndoStartupImport func() (int, error)
+	// OnLoadHistory returns the recorded runs, newest first.
+	OnLoadHistory func() ([]history.Run, error)
 }
 
 type MainWindow struct {
@@ -30,29 +45,62 @@ type MainWindow struct {
 	callbacks      Callbacks
 	applyingLocale bool
 	updatingEditor bool
-
-	managedList     *walk.ListBox
-	editorTitle     *walk.Label
-	noSelectLabel   *walk.Label
-	pathLabel       *walk.Label
-	pathEdit        *walk.LineEdit
-	argsLabel       *walk.Label
-	argsEdit        *walk.LineEdit
-	browseBtn       *walk.PushButton
-	appAutoHide     *walk.CheckBox
-	appLaunchHidden *walk.CheckBox
-	retryEdit       *walk.LineEdit
-	runAtLogon      *walk.CheckBox
-	startHidden     *walk.CheckBox
-	exitOnDone      *walk.CheckBox
-	retryLabel      *walk.Label
-	managedTitle    *walk.Label
-	languageLabel   *walk.Label
-	languageCombo   *walk.ComboBox
-	removeBtn       *walk.PushButton
-	openLogsBtn     *walk.PushButton
-	cleanupBtn      *walk.PushButton
-	exitBtn         *walk.PushButton
+	// reloading is set while settings from outside the window are shown;
+	// the control events it causes are not saved back.
+	reloading bool
+	// pendingEdit is set while typed text has not been saved yet.
+	pendingEdit bool
+	// issues are the latest settings validation results.
+	issues config.Issues
+
+	managedList       *walk.ListBox
+	editorTitle       *walk.Label
+	noSelectLabel     *walk.Label
+	pathLabel         *walk.Label
+	pathEdit          *walk.LineEdit
+	argsLabel         *walk.Label
+	argsEdit          *walk.LineEdit
+	workDirLabel      *walk.Label
+	workDirEdit       *walk.LineEdit
+	expectedLabel     *walk.Label
+	expectedEdit      *walk.LineEdit
+	envLabel          *walk.Label
+	envEdit           *walk.TextEdit
+	browseBtn         *walk.PushButton
+	appAutoHide       *walk.CheckBox
+	appLaunchHidden   *walk.CheckBox
+	appSupervise      *walk.CheckBox
+	appProxyIcon      *walk.CheckBox
+	appCapture        *walk.CheckBox
+	appMuteNotify     *walk.CheckBox
+	stopPolicyLabel   *walk.Label
+	stopPolicyCombo   *walk.ComboBox
+	retryEdit         *walk.LineEdit
+	runAtLogon        *walk.CheckBox
+	startHidden       *walk.CheckBox
+	exitOnDone        *walk.CheckBox
+	stopOnExit        *walk.CheckBox
+	retryLabel        *walk.Label
+	notifyLabel       *walk.Label
+	notifyCombo       *walk.ComboBox
+	logonBackendLabel *walk.Label
+	logonBackendCombo *walk.ComboBox
+	logonDelayLabel   *walk.Label
+	logonDelayEdit    *walk.LineEdit
+	logonHighest      *walk.CheckBox
+	logonBattery      *walk.CheckBox
+	logonStatus       *walk.Label
+	managedTitle      *walk.Label
+	languageLabel     *walk.Label
+	languageCombo     *walk.ComboBox
+	removeBtn         *walk.PushButton
+	importBtn         *walk.PushButton
+	undoImportBtn     *walk.PushButton
+	historyBtn        *walk.PushButton
+	issuesBtn         *walk.PushButton
+	openLogsBtn       *walk.PushButton
+	cleanupBtn        *walk.PushButton
+	exitBtn           *walk.PushButton
 }
 
 func NewMainWindow(initial config.Settings, callbacks Callbacks) (*MainWindow, error) {
@@ -85,6 +133,7 @@ func NewMainWindow(initial config.Settings, callbacks Callbacks) (*MainWindow, e
 
 	w.applyLanguage(w.settings.Language)
 	w.refreshManagedList()
+	w.trackPendingEdits(w.retryEdit, w.logonDelayEdit, w.pathEdit, w.argsEdit, w.workDirEdit, w.expectedEdit, w.envEdit)
 
 	mw.Closing().Attach(func(canceled *bool, reason walk.CloseReason) {
 		if !w.allowClose {
@@ -159,6 +208,17 @@ func (w *MainWindow) buildTopOptions() error {
 	})
 	w.exitOnDone = exitOnDone
 
+	stopOnExit, err := walk.NewCheckBox(optionsRow)
+	if err != nil {
+		return err
+	}
+	stopOnExit.SetChecked(w.settings.StopManagedAppsOnExit)
+	stopOnExit.CheckedChanged().Attach(func() {
+		w.settings.StopManagedAppsOnExit = stopOnExit.Checked()
+		w.save()
+	})
+	w.stopOnExit = stopOnExit
+
 	if _, err = walk.NewHSpacer(optionsRow); err != nil {
 		return err
 	}
@@ -207,6 +267,28 @@ func (w *MainWindow) buildTopOptions() error {
 	})
 	w.retryEdit = retryEdit
 
+	notifyLabel, err := walk.NewLabel(settingsRow)
+	if err != nil {
+		return err
+	}
+	w.notifyLabel = notifyLabel
+
+	notifyCombo, err := walk.NewComboBox(settingsRow)
+	if err != nil {
+		return err
+	}
+	notifyCombo.SetMinMaxSize(walk.Size{Width: 140, Height: 0}, walk.Size{Width: 140, Height: 0})
+	notifyCombo.CurrentIndexChanged().Attach(func() {
+		if w.applyingLocale {
+			return
+		}
+		if idx := notifyCombo.CurrentIndex(); idx >= 0 && idx < len(autorunNotifyModes) {
+			w.settings.AutorunNotify = autorunNotifyModes[idx]
+		}
+		w.save()
+	})
+	w.notifyCombo = notifyCombo
+
 	if _, err = walk.NewHSpacer(settingsRow); err != nil {
 		return err
 	}
@@ -239,9 +321,128 @@ func (w *MainWindow) buildTopOptions() error {
 	})
 	w.languageCombo = languageCombo
 
+	return w.buildLogonOptions()
+}
+
+// autorunNotifyModes is the order of the autorun notification combo box.
+var autorunNotifyModes = []config.AutorunNotify{config.NotifyOff, config.NotifyFailures, config.NotifySummary}
+
+// startupBackends is the order of the run-at-logon backend combo box.
+var startupBackends = []config.StartupBackend{config.StartupRunKey, config.StartupFolder, config.StartupTaskScheduler}
+
+func (w *MainWindow) buildLogonOptions() error {
+	logonRow, err := walk.NewComposite(w.mw)
+	if err != nil {
+		return err
+	}
+	logonLayout := walk.NewHBoxLayout()
+	logonLayout.SetSpacing(8)
+	if err = logonRow.SetLayout(logonLayout); err != nil {
+		return err
+	}
+
+	backendLabel, err := walk.NewLabel(logonRow)
+	if err != nil {
+		return err
+	}
+	w.logonBackendLabel = backendLabel
+
+	backendCombo, err := walk.NewComboBox(logonRow)
+	if err != nil {
+		return err
+	}
+	backendCombo.SetMinMaxSize(walk.Size{Width: 170, Height: 0}, walk.Size{Width: 170, Height: 0})
+	backendCombo.CurrentIndexChanged().Attach(func() {
+		if w.applyingLocale {
+			return
+		}
+		if idx := backendCombo.CurrentIndex(); idx >= 0 && idx < len(startupBackends) {
+			w.settings.StartupBackend = startupBackends[idx]
+		}
+		w.syncLogonOptions()
+		w.save()
+	})
+	w.logonBackendCombo = backendCombo
+
+	delayLabel, err := walk.NewLabel(logonRow)
+	if err != nil {
+		return err
+	}
+	w.logonDelayLabel = delayLabel
+
+	delayEdit, err := walk.NewLineEdit(logonRow)
+	if err != nil {
+		return err
+	}
+	delayEdit.SetMinMaxSize(walk.Size{Width: 64, Height: 0}, walk.Size{Width: 64, Height: 0})
+	delayEdit.SetText(strconv.Itoa(w.settings.LogonTask.DelaySeconds))
+	delayEdit.EditingFinished().Attach(func() {
+		v, convErr := strconv.Atoi(strings.TrimSpace(delayEdit.Text()))
+		if convErr != nil {
+			walk.MsgBox(w.mw, w.mw.Title(), i18n.For(w.settings.Language).LogonDelayInvalid, walk.MsgBoxIconWarning)
+			v = w.settings.LogonTask.DelaySeconds
+		}
+		if v < 0 {
+			v = 0
+		}
+		if v > config.MaxLogonDelaySeconds {
+			v = config.MaxLogonDelaySeconds
+		}
+		w.settings.LogonTask.DelaySeconds = v
+		delayEdit.SetText(strconv.Itoa(v))
+		w.save()
+	})
+	w.logonDelayEdit = delayEdit
+
+	highest, err := walk.NewCheckBox(logonRow)
+	if err != nil {
+		return err
+	}
+	highest.SetChecked(w.settings.LogonTask.HighestPrivileges)
+	highest.CheckedChanged().Attach(func() {
+		w.settings.LogonTask.HighestPrivileges = highest.Checked()
+		w.save()
+	})
+	w.logonHighest = highest
+
+	battery, err := walk.NewCheckBox(logonRow)
+	if err != nil {
+		return err
+	}
+	battery.SetChecked(w.settings.LogonTask.DisallowOnBattery)
+	battery.CheckedChanged().Attach(func() {
+		w.settings.LogonTask.DisallowOnBattery = battery.Checked()
+		w.save()
+	})
+	w.logonBattery = battery
+
+	if _, err = walk.NewHSpacer(logonRow); err != nil {
+		return err
+	}
+
+	status, err := walk.NewLabel(logonRow)
+	if err != nil {
+		return err
+	}
+	w.logonStatus = status
 	return nil
 }
 
+// SetLogonStatus shows the state of the run-at-logon registration.
+func (w *MainWindow) SetLogonStatus(text string) {
+	w.mw.Synchronize(func() {
+		w.logonStatus.SetText(text)
+	})
+}
+
+// syncLogonOptions enables the task options only for the Task Scheduler backend.
+func (w *MainWindow) syncLogonOptions() {
+	task := w.settings.StartupBackend == config.StartupTaskScheduler
+	w.logonDelayEdit.SetEnabled(task)
+	w.logonHighest.SetEnabled(task)
+	w.logonBattery.SetEnabled(task)
+}
+
 func (w *MainWindow) buildManagedList() error {
 	title, err := walk.NewLabel(w.mw)
 	if err != nil {
@@ -335,8 +536,24 @@ func (w *MainWindow) buildManagedEditor() error {
 	if err != nil {
 		return err
 	}
-	pathEdit.SetReadOnly(true)
 	pathEdit.SetMinMaxSize(walk.Size{Width: 620, Height: 0}, walk.Size{Width: 620, Height: 0})
+	pathEdit.EditingFinished().Attach(func() {
+		if w.updatingEditor {
+			return
+		}
+		app, _, ok := w.selectedManagedApp()
+		if !ok {
+			return
+		}
+		target := strings.TrimSpace(pathEdit.Text())
+		if target == app.ExePath {
+			return
+		}
+		setLaunchTarget(app, target)
+		w.refreshManagedList()
+		w.syncManagedEditor()
+		w.save()
+	})
 	w.pathEdit = pathEdit
 
 	browseBtn, err := walk.NewPushButton(pathRow)
@@ -375,11 +592,119 @@ func (w *MainWindow) buildManagedEditor() error {
 		if !ok {
 			return
 		}
-		app.Args = argsEdit.Text()
+		app.Args = cmdline.Split(argsEdit.Text())
 		w.save()
 	})
 	w.argsEdit = argsEdit
 
+	workDirRow, err := walk.NewComposite(editor)
+	if err != nil {
+		return err
+	}
+	hWorkDir := walk.NewHBoxLayout()
+	hWorkDir.SetSpacing(8)
+	if err = workDirRow.SetLayout(hWorkDir); err != nil {
+		return err
+	}
+
+	workDirLabel, err := walk.NewLabel(workDirRow)
+	if err != nil {
+		return err
+	}
+	w.workDirLabel = workDirLabel
+
+	workDirEdit, err := walk.NewLineEdit(workDirRow)
+	if err != nil {
+		return err
+	}
+	workDirEdit.SetMinMaxSize(walk.Size{Width: 740, Height: 0}, walk.Size{Width: 740, Height: 0})
+	workDirEdit.EditingFinished().Attach(func() {
+		if w.updatingEditor {
+			return
+		}
+		app, _, ok := w.selectedManagedApp()
+		if !ok {
+			return
+		}
+		app.WorkingDir = strings.TrimSpace(workDirEdit.Text())
+		w.save()
+	})
+	w.workDirEdit = workDirEdit
+
+	expectedRow, err := walk.NewComposite(editor)
+	if err != nil {
+		return err
+	}
+	hExpected := walk.NewHBoxLayout()
+	hExpected.SetSpacing(8)
+	if err = expectedRow.SetLayout(hExpected); err != nil {
+		return err
+	}
+
+	expectedLabel, err := walk.NewLabel(expectedRow)
+	if err != nil {
+		return err
+	}
+	w.expectedLabel = expectedLabel
+
+	expectedEdit, err := walk.NewLineEdit(expectedRow)
+	if err != nil {
+		return err
+	}
+	expectedEdit.SetMinMaxSize(walk.Size{Width: 740, Height: 0}, walk.Size{Width: 740, Height: 0})
+	expectedEdit.EditingFinished().Attach(func() {
+		if w.updatingEditor {
+			return
+		}
+		app, _, ok := w.selectedManagedApp()
+		if !ok {
+			return
+		}
+		app.ExpectedProcess = strings.TrimSpace(expectedEdit.Text())
+		w.save()
+	})
+	w.expectedEdit = expectedEdit
+
+	envRow, err := walk.NewComposite(editor)
+	if err != nil {
+		return err
+	}
+	hEnv := walk.NewHBoxLayout()
+	hEnv.SetSpacing(8)
+	if err = envRow.SetLayout(hEnv); err != nil {
+		return err
+	}
+
+	envLabel, err := walk.NewLabel(envRow)
+	if err != nil {
+		return err
+	}
+	w.envLabel = envLabel
+
+	envEdit, err := walk.NewTextEditWithStyle(envRow, win.WS_VSCROLL)
+	if err != nil {
+		return err
+	}
+	envEdit.SetMinMaxSize(walk.Size{Width: 740, Height: 60}, walk.Size{Width: 740, Height: 60})
+	envEdit.FocusedChanged().Attach(func() {
+		if w.updatingEditor || envEdit.Focused() {
+			return
+		}
+		app, _, ok := w.selectedManagedApp()
+		if !ok {
+			return
+		}
+		overrides, parseErr := parseEnvText(envEdit.Text())
+		if parseErr != nil {
+			msg := i18n.For(w.settings.Language)
+			w.ShowError(msg.WindowTitle, fmt.Sprintf(msg.ManagedEnvInvalid, parseErr))
+			return
+		}
+		app.Env = overrides
+		w.save()
+	})
+	w.envEdit = envEdit
+
 	optionsRow, err := walk.NewComposite(editor)
 	if err != nil {
 		return err
@@ -432,11 +757,109 @@ func (w *MainWindow) buildManagedEditor() error {
 		} else {
 			w.appAutoHide.SetEnabled(true)
 		}
+		w.appSupervise.SetEnabled(checked)
+		w.appCapture.SetEnabled(checked)
 		w.refreshManagedList()
 		w.save()
 	})
 	w.appLaunchHidden = appLaunchHidden
 
+	appSupervise, err := walk.NewCheckBox(optionsRow)
+	if err != nil {
+		return err
+	}
+	appSupervise.CheckedChanged().Attach(func() {
+		if w.updatingEditor {
+			return
+		}
+		app, _, ok := w.selectedManagedApp()
+		if !ok {
+			return
+		}
+		app.Supervise.Enabled = appSupervise.Checked()
+		w.save()
+	})
+	w.appSupervise = appSupervise
+
+	appProxyIcon, err := walk.NewCheckBox(optionsRow)
+	if err != nil {
+		return err
+	}
+	appProxyIcon.CheckedChanged().Attach(func() {
+		if w.updatingEditor {
+			return
+		}
+		app, _, ok := w.selectedManagedApp()
+		if !ok {
+			return
+		}
+		app.TrayBehavior.ProxyTrayIcon = appProxyIcon.Checked()
+		w.save()
+	})
+	w.appProxyIcon = appProxyIcon
+
+	appCapture, err := walk.NewCheckBox(optionsRow)
+	if err != nil {
+		return err
+	}
+	appCapture.CheckedChanged().Attach(func() {
+		if w.updatingEditor {
+			return
+		}
+		app, _, ok := w.selectedManagedApp()
+		if !ok {
+			return
+		}
+		app.CaptureOutput = appCapture.Checked()
+		w.save()
+	})
+	w.appCapture = appCapture
+
+	appMuteNotify, err := walk.NewCheckBox(optionsRow)
+	if err != nil {
+		return err
+	}
+	appMuteNotify.CheckedChanged().Attach(func() {
+		if w.updatingEditor {
+			return
+		}
+		app, _, ok := w.selectedManagedApp()
+		if !ok {
+			return
+		}
+		app.MuteNotifications = appMuteNotify.Checked()
+		w.save()
+	})
+	w.appMuteNotify = appMuteNotify
+
+	stopPolicyLabel, err := walk.NewLabel(optionsRow)
+	if err != nil {
+		return err
+	}
+	w.stopPolicyLabel = stopPolicyLabel
+
+	stopPolicyCombo, err := walk.NewComboBox(optionsRow)
+	if err != nil {
+		return err
+	}
+	stopPolicyCombo.SetMinMaxSize(walk.Size{Width: 140, Height: 0}, walk.Size{Width: 140, Height: 0})
+	stopPolicyCombo.CurrentIndexChanged().Attach(func() {
+		if w.updatingEditor || w.applyingLocale {
+			return
+		}
+		app, _, ok := w.selectedManagedApp()
+		if !ok {
+			return
+		}
+		idx := stopPolicyCombo.CurrentIndex()
+		if idx < 0 || idx >= len(stopPolicies) {
+			return
+		}
+		app.StopPolicy = stopPolicies[idx]
+		w.save()
+	})
+	w.stopPolicyCombo = stopPolicyCombo
+
 	return nil
 }
 
@@ -458,6 +881,35 @@ func (w *MainWindow) buildActions() error {
 	removeBtn.Clicked().Attach(w.onRemoveSelected)
 	w.removeBtn = removeBtn
 
+	importBtn, err := walk.NewPushButton(row)
+	if err != nil {
+		return err
+	}
+	importBtn.Clicked().Attach(w.onImportStartup)
+	w.importBtn = importBtn
+
+	undoImportBtn, err := walk.NewPushButton(row)
+	if err != nil {
+		return err
+	}
+	undoImportBtn.Clicked().Attach(w.onUndoStartupImport)
+	w.undoImportBtn = undoImportBtn
+
+	historyBtn, err := walk.NewPushButton(row)
+	if err != nil {
+		return err
+	}
+	historyBtn.Clicked().Attach(w.onShowHistory)
+	w.historyBtn = historyBtn
+
+	issuesBtn, err := walk.NewPushButton(row)
+	if err != nil {
+		return err
+	}
+	issuesBtn.Clicked().Attach(w.onShowValidationIssues)
+	issuesBtn.SetVisible(false)
+	w.issuesBtn = issuesBtn
+
 	openLogsBtn, err := walk.NewPushButton(row)
 	if err != nil {
 		return err
@@ -504,17 +956,55 @@ func (w *MainWindow) applyLanguage(language string) {
 	w.runAtLogon.SetText(msg.RunAtLogon)
 	w.startHidden.SetText(msg.StartHidden)
 	w.exitOnDone.SetText(msg.ExitOnDone)
+	w.stopOnExit.SetText(msg.StopOnExit)
 	w.retryLabel.SetText(msg.RetrySeconds)
+	w.notifyLabel.SetText(msg.AutorunNotifyLabel)
+	_ = w.notifyCombo.SetModel([]string{msg.AutorunNotifyOff, msg.AutorunNotifyFailures, msg.AutorunNotifySummary})
+	w.notifyCombo.SetCurrentIndex(0)
+	for i, mode := range autorunNotifyModes {
+		if mode == w.settings.AutorunNotify {
+			w.notifyCombo.SetCurrentIndex(i)
+		}
+	}
+	w.logonBackendLabel.SetText(msg.StartupBackendLabel)
+	_ = w.logonBackendCombo.SetModel([]string{msg.StartupBackendRunKey, msg.StartupBackendFolder, msg.StartupBackendTask})
+	w.logonBackendCombo.SetCurrentIndex(0)
+	for i, backend := range startupBackends {
+		if backend == w.settings.StartupBackend {
+			w.logonBackendCombo.SetCurrentIndex(i)
+		}
+	}
+	w.logonDelayLabel.SetText(msg.LogonDelaySeconds)
+	w.logonHighest.SetText(msg.LogonHighestPrivileges)
+	w.logonBattery.SetText(msg.LogonDisallowOnBattery)
+	w.syncLogonOptions()
 	w.managedTitle.SetText(msg.ManagedListTitle)
 	w.editorTitle.SetText(msg.ManagedEditorTitle)
 	w.pathLabel.SetText(msg.ManagedAppPath)
 	w.argsLabel.SetText(msg.ManagedAppArgs)
+	w.workDirLabel.SetText(msg.ManagedWorkingDir)
+	w.expectedLabel.SetText(msg.ManagedExpectedProcess)
+	_ = w.expectedEdit.SetToolTipText(msg.ManagedExpectedProcessHint)
+	_ = w.pathEdit.SetToolTipText(msg.ManagedAppPathHint)
+	_ = w.workDirEdit.SetToolTipText(msg.ManagedWorkingDirHint)
+	w.envLabel.SetText(msg.ManagedEnv)
+	_ = w.envEdit.SetToolTipText(msg.ManagedEnvHint)
 	w.browseBtn.SetText(msg.SelectProgram)
 	w.appAutoHide.SetText(msg.ManagedAutoHide)
 	w.appLaunchHidden.SetText(msg.ManagedLaunchHidden)
+	w.appSupervise.SetText(msg.ManagedSupervise)
+	w.appProxyIcon.SetText(msg.ManagedProxyTrayIcon)
+	w.appCapture.SetText(msg.ManagedCaptureOutput)
+	w.appMuteNotify.SetText(msg.ManagedMuteNotifications)
+	w.stopPolicyLabel.SetText(msg.ManagedStopPolicy)
+	_ = w.stopPolicyCombo.SetModel([]string{msg.StopPolicyNever, msg.StopPolicyClose, msg.StopPolicyCloseThenKill})
 	w.noSelectLabel.SetText(msg.ManagedNoSelectionHint)
 	w.languageLabel.SetText(msg.LanguageLabel)
 	w.removeBtn.SetText(msg.RemoveSelected)
+	w.importBtn.SetText(msg.ImportStartup)
+	w.undoImportBtn.SetText(msg.UndoStartupImport)
+	w.historyBtn.SetText(msg.RunHistory)
+	w.issuesBtn.SetText(fmt.Sprintf(msg.ValidationButton, len(w.issues)))
 	w.openLogsBtn.SetText(msg.OpenLogs)
 	w.cleanupBtn.SetText(msg.CleanupRestore)
 	w.exitBtn.SetText(msg.ExitApp)
@@ -539,6 +1029,8 @@ func (w *MainWindow) onAddProgram() {
 	dlg := new(walk.FileDialog)
 	dlg.Title = msg.SelectManagedExe
 	dlg.Filter = fmt.Sprintf("%s|%s", msg.ExeFilter, msg.AllFilesFilter)
+	// Keep .lnk files as shortcuts instead of the dialog resolving them.
+	dlg.Flags = win.OFN_NODEREFERENCELINKS
 	ok, err := dlg.ShowOpen(w.mw)
 	if err != nil || !ok {
 		return
@@ -548,18 +1040,7 @@ func (w *MainWindow) onAddProgram() {
 		name = msg.NewAppName
 	}
 	id := strconv.FormatInt(time.Now().UnixNano(), 10)
-	w.settings.ManagedApps = append(w.settings.ManagedApps, config.ManagedAppEntry{
-		ID:           id,
-		Name:         name,
-		ExePath:      dlg.FilePath,
-		Args:         "",
-		RunOnStartup: true,
-		WindowMatch: config.WindowMatchRule{
-			Strategy: config.MatchProcessNameThenTitle,
-		},
-		LaunchHiddenInBackground: false,
-		TrayBehavior:             config.TrayBehavior{AutoMinimizeAndHideOnLaunch: true},
-	})
+	w.settings.ManagedApps = append(w.settings.ManagedApps, config.NewManagedAppEntry(id, name, dlg.FilePath))
 	w.refreshManagedList()
 	w.managedList.SetCurrentIndex(len(w.settings.ManagedApps) - 1)
 	w.syncManagedEditor()
@@ -577,11 +1058,13 @@ func (w *MainWindow) onSelectProgramForSelected() {
 	dlg := new(walk.FileDialog)
 	dlg.Title = msg.SelectManagedExe
 	dlg.Filter = fmt.Sprintf("%s|%s", msg.ExeFilter, msg.AllFilesFilter)
+	// Keep .lnk files as shortcuts instead of the dialog resolving them.
+	dlg.Flags = win.OFN_NODEREFERENCELINKS
 	result, err := dlg.ShowOpen(w.mw)
 	if err != nil || !result {
 		return
 	}
-	app.ExePath = dlg.FilePath
+	setLaunchTarget(app, dlg.FilePath)
 	name := stringutil.TrimExt(filepath.Base(dlg.FilePath))
 	if name != "" {
 		app.Name = name
@@ -591,6 +1074,125 @@ func (w *MainWindow) onSelectProgramForSelected() {
 	w.save()
 }
 
+func (w *MainWindow) onImportStartup() {
+	if w.callbacks.OnScanStartup == nil {
+		return
+	}
+	msg := i18n.For(w.settings.Language)
+	items, err := w.callbacks.OnScanStartup()
+	if err != nil {
+		w.ShowError(msg.ImportStartupTitle, fmt.Sprintf("%s: %v", msg.ImportStartupScanFailed, err))
+	}
+	items = w.unmanagedStartupItems(items)
+	if len(items) == 0 {
+		if err == nil {
+			w.ShowInfo(msg.ImportStartupTitle, msg.ImportStartupNone)
+		}
+		return
+	}
+
+	selected, disable, ok, err := showImportDialog(w.mw, msg, items)
+	if err != nil {
+		w.ShowError(msg.ImportStartupTitle, err.Error())
+		return
+	}
+	if !ok || len(selected) == 0 {
+		return
+	}
+	base := time.Now().UnixNano()
+	for i, item := range selected {
+		id := strconv.FormatInt(base+int64(i), 10)
+		w.settings.ManagedApps = append(w.settings.ManagedApps, startup.ToManagedEntry(item, id))
+	}
+	w.refreshManagedList()
+	w.managedList.SetCurrentIndex(len(w.settings.ManagedApps) - 1)
+	w.syncManagedEditor()
+	w.save()
+
+	if disable && w.callbacks.OnDisableStartup != nil {
+		if err = w.callbacks.OnDisableStartup(selected); err != nil {
+			w.ShowError(msg.ImportStartupTitle, fmt.Sprintf("%s: %v", msg.ImportStartupDisableFailed, err))
+			return
+		}
+	}
+	w.ShowInfo(msg.ImportStartupTitle, fmt.Sprintf(msg.ImportStartupDone, len(selected)))
+}
+
+// unmanagedStartupItems drops items whose target is already a managed entry.
+func (w *MainWindow) unmanagedStartupItems(items []startup.Item) []startup.Item {
+	out := items[:0]
+	for _, item := range items {
+		managed := false
+		for _, app := range w.settings.ManagedApps {
+			if strings.EqualFold(strings.TrimSpace(app.ExePath), item.Target) {
+				managed = true
+				break
+			}
+		}
+		if !managed {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func (w *MainWindow) onUndoStartupImport() {
+	if w.callbacks.OnUndoStartupImport == nil {
+		return
+	}
+	msg := i18n.For(w.settings.Language)
+	restored, err := w.callbacks.OnUndoStartupImport()
+	switch {
+	case errors.Is(err, startup.ErrNoJournal):
+		w.ShowInfo(msg.ImportStartupTitle, msg.UndoStartupImportNone)
+	case err != nil:
+		w.ShowError(msg.ImportStartupTitle, fmt.Sprintf("%s: %v", msg.UndoStartupImportFailed, err))
+	default:
+		w.ShowInfo(msg.ImportStartupTitle, fmt.Sprintf(msg.UndoStartupImportDone, restored))
+	}
+}
+
+// ShowHistory opens the run history dialog.
+func (w *MainWindow) ShowHistory() {
+	w.mw.Synchronize(w.onShowHistory)
+}
+
+func (w *MainWindow) onShowHistory() {
+	if w.callbacks.OnLoadHistory == nil {
+		return
+	}
+	msg := i18n.For(w.settings.Language)
+	runs, err := w.callbacks.OnLoadHistory()
+	if err != nil && len(runs) == 0 {
+		w.ShowError(msg.RunHistory, fmt.Sprintf("%s: %v", msg.RunHistoryLoadFailed, err))
+		return
+	}
+	if err = showHistoryDialog(w.mw, w.settings.Language, runs); err != nil {
+		w.ShowError(msg.RunHistory, err.Error())
+	}
+}
+
+// SetValidationIssues shows a button opening issues, or hides it when there
+// are none.
+func (w *MainWindow) SetValidationIssues(issues config.Issues) {
+	w.mw.Synchronize(func() {
+		w.issues = issues
+		w.issuesBtn.SetText(fmt.Sprintf(i18n.For(w.settings.Language).ValidationButton, len(issues)))
+		w.issuesBtn.SetVisible(len(issues) > 0)
+	})
+}
+
+// ShowValidationIssues opens the settings issues dialog.
+func (w *MainWindow) ShowValidationIssues() {
+	w.mw.Synchronize(w.onShowValidationIssues)
+}
+
+func (w *MainWindow) onShowValidationIssues() {
+	if err := showIssuesDialog(w.mw, w.settings.Language, w.issues); err != nil {
+		w.ShowError(i18n.For(w.settings.Language).ValidationTitle, err.Error())
+	}
+}
+
 func (w *MainWindow) onRemoveSelected() {
 	idx := w.managedList.CurrentIndex()
 	if idx < 0 || idx >= len(w.settings.ManagedApps) {
@@ -622,7 +1224,7 @@ func (w *MainWindow) refreshManagedList() {
 }
 
 func (w *MainWindow) syncManagedEditor() {
-	if w.pathEdit == nil || w.argsEdit == nil || w.appAutoHide == nil || w.appLaunchHidden == nil {
+	if w.pathEdit == nil || w.argsEdit == nil || w.workDirEdit == nil || w.expectedEdit == nil || w.envEdit == nil || w.appAutoHide == nil || w.appLaunchHidden == nil || w.appSupervise == nil || w.appProxyIcon == nil || w.appCapture == nil || w.appMuteNotify == nil || w.stopPolicyCombo == nil {
 		return
 	}
 	app, _, ok := w.selectedManagedApp()
@@ -632,9 +1234,17 @@ func (w *MainWindow) syncManagedEditor() {
 	msg := i18n.For(w.settings.Language)
 	w.pathEdit.SetEnabled(ok)
 	w.argsEdit.SetEnabled(ok)
+	w.workDirEdit.SetEnabled(ok)
+	w.expectedEdit.SetEnabled(ok)
+	w.envEdit.SetEnabled(ok)
 	w.browseBtn.SetEnabled(true)
 	w.appAutoHide.SetEnabled(ok)
 	w.appLaunchHidden.SetEnabled(ok)
+	w.appSupervise.SetEnabled(ok)
+	w.appProxyIcon.SetEnabled(ok)
+	w.appCapture.SetEnabled(ok)
+	w.appMuteNotify.SetEnabled(ok)
+	w.stopPolicyCombo.SetEnabled(ok)
 	if ok {
 		w.noSelectLabel.SetVisible(false)
 	} else {
@@ -645,16 +1255,81 @@ func (w *MainWindow) syncManagedEditor() {
 	if !ok {
 		w.pathEdit.SetText("")
 		w.argsEdit.SetText("")
+		w.workDirEdit.SetText("")
+		w.expectedEdit.SetText("")
+		_ = w.envEdit.SetText("")
 		w.appAutoHide.SetChecked(false)
 		w.appLaunchHidden.SetChecked(false)
+		w.appSupervise.SetChecked(false)
+		w.appProxyIcon.SetChecked(false)
+		w.appCapture.SetChecked(false)
+		w.appMuteNotify.SetChecked(false)
+		w.stopPolicyCombo.SetCurrentIndex(-1)
 		return
 	}
 
 	w.pathEdit.SetText(app.ExePath)
-	w.argsEdit.SetText(app.Args)
+	w.argsEdit.SetText(cmdline.Join(app.Args))
+	w.workDirEdit.SetText(app.WorkingDir)
+	w.expectedEdit.SetText(app.ExpectedProcess)
+	_ = w.envEdit.SetText(formatEnvText(app.Env))
 	w.appAutoHide.SetChecked(app.TrayBehavior.AutoMinimizeAndHideOnLaunch)
 	w.appLaunchHidden.SetChecked(app.LaunchHiddenInBackground)
+	w.appSupervise.SetChecked(app.Supervise.Enabled)
+	w.appProxyIcon.SetChecked(app.TrayBehavior.ProxyTrayIcon)
+	w.appCapture.SetChecked(app.CaptureOutput)
+	w.appMuteNotify.SetChecked(app.MuteNotifications)
+	w.stopPolicyCombo.SetCurrentIndex(stopPolicyIndex(app.StopPolicy))
 	w.appAutoHide.SetEnabled(!app.LaunchHiddenInBackground)
+	w.appSupervise.SetEnabled(app.LaunchHiddenInBackground)
+	w.appCapture.SetEnabled(app.LaunchHiddenInBackground)
+	// Only processes WinTray starts itself can run hidden.
+	w.appLaunchHidden.SetEnabled(app.LaunchKind == config.LaunchExe || app.LaunchKind == config.LaunchShortcut)
+}
+
+// setLaunchTarget changes the target of app and re-derives its launch kind.
+// URI and packaged app targets cannot run hidden.
+func setLaunchTarget(app *config.ManagedAppEntry, target string) {
+	app.ExePath = target
+	app.LaunchKind = config.InferLaunchKind(target)
+	if app.LaunchKind == config.LaunchURI || app.LaunchKind == config.LaunchAppID {
+		app.LaunchHiddenInBackground = false
+	}
+}
+
+// parseEnvText parses the environment editor, one override per line.
+func parseEnvText(text string) ([]config.EnvOverride, error) {
+	overrides := make([]config.EnvOverride, 0)
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		o, err := config.ParseEnvOverride(line)
+		if err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, o)
+	}
+	return overrides, nil
+}
+
+func formatEnvText(overrides []config.EnvOverride) string {
+	lines := make([]string, 0, len(overrides))
+	for _, o := range overrides {
+		lines = append(lines, config.FormatEnvOverride(o))
+	}
+	return strings.Join(lines, "\r\n")
+}
+
+var stopPolicies = []config.StopPolicy{config.StopNever, config.StopClose, config.StopCloseThenKill}
+
+func stopPolicyIndex(policy config.StopPolicy) int {
+	for i, p := range stopPolicies {
+		if p == policy {
+			return i
+		}
+	}
+	return 1
 }
 
 func (w *MainWindow) selectedManagedApp() (*config.ManagedAppEntry, int, bool) {
@@ -678,11 +1353,65 @@ func (w *MainWindow) ShowError(title, body string) {
 }
 
 func (w *MainWindow) save() {
+	if w.reloading {
+		return
+	}
+	w.pendingEdit = false
 	if w.callbacks.OnSave != nil {
 		w.callbacks.OnSave(w.settings)
 	}
 }
 
+// trackPendingEdits sets pendingEdit when the user types into edits.
+func (w *MainWindow) trackPendingEdits(edits ...interface{ TextChanged() *walk.Event }) {
+	for _, edit := range edits {
+		edit.TextChanged().Attach(func() {
+			if !w.updatingEditor && !w.reloading {
+				w.pendingEdit = true
+			}
+		})
+	}
+}
+
+// ConfirmExternalChange reports whether settings changed outside the window
+// may replace the ones shown, asking first if there are unsaved edits. It
+// must be called on the UI thread.
+func (w *MainWindow) ConfirmExternalChange() bool {
+	if !w.pendingEdit {
+		return true
+	}
+	msg := i18n.For(w.settings.Language)
+	return walk.MsgBox(w.mw, msg.SettingsExternalTitle, msg.SettingsExternalDiscardEdits, walk.MsgBoxYesNo|walk.MsgBoxIconQuestion) == walk.DlgCmdYes
+}
+
+// ConfirmOverwrite asks whether a change made in the window should replace
+// a settings file changed outside it. It must be called on the UI thread.
+func (w *MainWindow) ConfirmOverwrite() bool {
+	msg := i18n.For(w.settings.Language)
+	return walk.MsgBox(w.mw, msg.SettingsExternalTitle, msg.SettingsExternalOverwrite, walk.MsgBoxYesNo|walk.MsgBoxIconWarning) == walk.DlgCmdYes
+}
+
+// ReloadSettings shows settings loaded from outside the window without
+// saving them back.
+func (w *MainWindow) ReloadSettings(settings config.Settings) {
+	w.mw.Synchronize(func() {
+		w.reloading = true
+		defer func() { w.reloading = false }()
+		w.settings = settings
+		w.runAtLogon.SetChecked(settings.RunAtLogon)
+		w.startHidden.SetChecked(settings.StartMinimizedToTray)
+		w.exitOnDone.SetChecked(settings.ExitAfterManagedAppsCompleted)
+		w.stopOnExit.SetChecked(settings.StopManagedAppsOnExit)
+		w.retryEdit.SetText(strconv.Itoa(settings.CloseWindowRetrySeconds))
+		w.logonDelayEdit.SetText(strconv.Itoa(settings.LogonTask.DelaySeconds))
+		w.logonHighest.SetChecked(settings.LogonTask.HighestPrivileges)
+		w.logonBattery.SetChecked(settings.LogonTask.DisallowOnBattery)
+		w.applyLanguage(settings.Language)
+		w.refreshManagedList()
+		w.pendingEdit = false
+	})
+}
+
 func (w *MainWindow) ShowMainWindow() {
 	w.mw.Synchronize(func() {
 		hwnd := w.mw.Handle()